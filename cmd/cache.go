@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/logger"
+	"encoding/csv"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	cacheDateFormat = "2006-01-02 15:04"
+)
+
+var (
+	cacheCmd = &cobra.Command{
+		Use:   "cache",
+		Short: "Candles cache tools",
+	}
+
+	cacheListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List stored markets, symbols and resolutions with covered ranges",
+		RunE:  listCache,
+	}
+
+	cachePruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Remove candles by retention and size limit of config, or all candles of market, symbol and resolution",
+		RunE:  pruneCache,
+	}
+
+	cacheVerifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Check consistency and gaps of stored candles",
+		RunE:  verifyCache,
+	}
+
+	cacheExportCmd = &cobra.Command{
+		Use:   "export [market] [symbol] [resolution]",
+		Short: "Export stored candles to CSV",
+		Args:  cobra.ExactArgs(3),
+		RunE:  exportCache,
+	}
+
+	cacheFlags = struct {
+		market     string
+		symbol     string
+		resolution string
+		from       string
+		to         string
+		out        string
+	}{}
+)
+
+func init() {
+	f := cachePruneCmd.Flags()
+	f.StringVar(&cacheFlags.market, "market", "", "remove candles of market")
+	f.StringVar(&cacheFlags.symbol, "symbol", "", "remove candles of symbol")
+	f.StringVar(&cacheFlags.resolution, "resolution", "", "remove candles of resolution (e.g. 1h)")
+
+	f = cacheExportCmd.Flags()
+	f.StringVar(&cacheFlags.from, "from", "", "start date (2006-01-02), all stored if empty")
+	f.StringVar(&cacheFlags.to, "to", "", "end date (2006-01-02), all stored if empty")
+	f.StringVarP(&cacheFlags.out, "out", "o", "", "output file, stdout if empty")
+
+	cacheCmd.AddCommand(cacheListCmd, cachePruneCmd, cacheVerifyCmd, cacheExportCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+// openCache open candles cache without limits, so inspection does not remove anything
+func openCache() (*candles.Cache, error) {
+	cfg := initConfig()
+	lg := logger.New(os.Stdout, logger.WarnLevel)
+
+	return candles.NewCandleCache(cfg.Storage.Candles.Path, lg)
+}
+
+func listCache(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	stored, err := cache.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "MARKET\tSYMBOL\tRESOLUTION\tCANDLES\tSIZE\tFROM\tTO\tGAPS\tUSED\n")
+	for _, st := range stored {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t\t\t\t\n", st.Market, st.Symbol, st.Resolution, st.Candles, formatSize(st.Size))
+		for _, p := range st.Periods {
+			fmt.Fprintf(w, "\t\t\t\t\t%s\t%s\t%d\t%s\n",
+				p.From.UTC().Format(cacheDateFormat), p.To.UTC().Format(cacheDateFormat), len(p.Gaps), formatUsed(p.Used))
+		}
+	}
+
+	return nil
+}
+
+func pruneCache(cmd *cobra.Command, args []string) error {
+	filter := cacheFlags.market != "" || cacheFlags.symbol != "" || cacheFlags.resolution != ""
+	if !filter {
+		cfg := initConfig()
+		cache, err := newCandlesCache(cfg, logger.New(os.Stdout, logger.WarnLevel))
+		if err != nil {
+			return err
+		}
+		defer cache.Close()
+
+		n, err := cache.Prune()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d candles\n", n)
+		return nil
+	}
+
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	stored, err := cache.List()
+	if err != nil {
+		return err
+	}
+
+	for _, st := range stored {
+		if cacheFlags.market != "" && st.Market != cacheFlags.market ||
+			cacheFlags.symbol != "" && st.Symbol != cacheFlags.symbol ||
+			cacheFlags.resolution != "" && string(st.Resolution) != cacheFlags.resolution {
+			continue
+		}
+		if err := cache.Remove(st.Market, st.Symbol, st.Resolution); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s %s %s, %d candles\n", st.Market, st.Symbol, st.Resolution, st.Candles)
+	}
+
+	return nil
+}
+
+func verifyCache(cmd *cobra.Command, args []string) error {
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	results, err := cache.Verify()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "MARKET\tSYMBOL\tRESOLUTION\tFROM\tTO\tCANDLES\tGAPS\tUNRECORDED\tERROR\n")
+	for _, r := range results {
+		errText := "ok"
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n",
+			r.Stored.Market, r.Stored.Symbol, r.Stored.Resolution,
+			r.Period.From.UTC().Format(cacheDateFormat), r.Period.To.UTC().Format(cacheDateFormat),
+			r.Candles, len(r.Gaps), len(r.Unrecorded), errText)
+		for _, g := range r.Unrecorded {
+			fmt.Fprintf(w, "\t\t\tunrecorded gap\t%s - %s\t\t\t\t\n",
+				g.From.UTC().Format(cacheDateFormat), g.To.UTC().Format(cacheDateFormat))
+		}
+	}
+
+	return nil
+}
+
+func exportCache(cmd *cobra.Command, args []string) error {
+	resolution, err := models.CandleResolutionFromString(args[2])
+	if err != nil {
+		return err
+	}
+
+	from := time.Unix(0, 0)
+	if cacheFlags.from != "" {
+		if from, err = time.Parse(dateFormat, cacheFlags.from); err != nil {
+			return err
+		}
+	}
+	to := time.Now()
+	if cacheFlags.to != "" {
+		if to, err = time.Parse(dateFormat, cacheFlags.to); err != nil {
+			return err
+		}
+		to = to.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	cache, err := openCache()
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	rs, err := cache.Read(args[0], args[1], resolution, from, to)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if cacheFlags.out != "" {
+		f, err := os.Create(cacheFlags.out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := writeCandlesCSV(out, rs); err != nil {
+		return err
+	}
+
+	if cacheFlags.out != "" {
+		fmt.Printf("Exported %d candles to %s\n", len(rs.Candles), cacheFlags.out)
+	}
+
+	return nil
+}
+
+// writeCandlesCSV write candles in format of mock exchange local data
+func writeCandlesCSV(out io.Writer, candles *models.Candles) error {
+	w := csv.NewWriter(out)
+
+	if err := w.Write([]string{"datetime", "open", "high", "low", "close", "volume"}); err != nil {
+		return err
+	}
+
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	for _, c := range candles.Candles {
+		row := []string{c.Date.UTC().Format(cacheDateFormat), format(c.Open), format(c.High), format(c.Low), format(c.Close), format(c.Volume)}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+func formatUsed(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(cacheDateFormat)
+}
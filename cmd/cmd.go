@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"DaruBot/internal/config"
+	"DaruBot/internal/core"
 	"DaruBot/pkg/logger"
+	_ "DaruBot/strategies"
 	"context"
 	"fmt"
 	"github.com/spf13/cobra"
@@ -28,63 +30,67 @@ $$$$$$$  |\$$$$$$$ |$$ |      \$$$$$$  |      $$$$$$$  |\$$$$$$  | \$$$$  |
 	GitCommit  = "unknown"
 	ConfigFile = ""
 
-	rootCmd = &cobra.Command{}
-)
-
-func init() {
 	rootCmd = &cobra.Command{
 		Use:     "",
 		Short:   "",                                   // TODO
 		Long:    `https://github.com/leporel/DaruBot`, // TODO
 		Version: Ver,
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("%s \n\n", logo)
-			fmt.Printf("\t Version: %s, Date: %s, Git: %s\n\n", Ver, BuildDate, GitCommit)
-			fmt.Printf("\t To close program correctly, use Ctrl+C\n\n\n")
+		Run:     runRoot,
+	}
+)
 
-			cfg := initConfig()
+func init() {
+	rootCmd.PersistentFlags().StringVarP(&ConfigFile, "config", "c", "", "config file (e.g. ./config.yaml)")
+	rootCmd.PersistentFlags().BoolVarP(&DebugMode, "debug", "d", false, "debug mode")
+}
 
-			logLevel := logger.InfoLevel
-			if cfg.IsDebug() {
-				logLevel = logger.DebugLevel
-			}
+func runRoot(cmd *cobra.Command, args []string) {
+	fmt.Printf("%s \n\n", logo)
+	fmt.Printf("\t Version: %s, Date: %s, Git: %s\n\n", Ver, BuildDate, GitCommit)
+	fmt.Printf("\t To close program correctly, use Ctrl+C\n\n\n")
 
-			log := logger.New(os.Stdout, logLevel)
-			log.Debug("DebugMode enabled")
+	cfg := initConfig()
 
-			sigs := make(chan os.Signal, 1)
-			done := make(chan bool, 1)
+	logLevel := logger.InfoLevel
+	if cfg.IsDebug() {
+		logLevel = logger.DebugLevel
+	}
 
-			signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	log := logger.New(os.Stdout, logLevel)
+	log.Debug("DebugMode enabled")
 
-			go func() {
-				sig := <-sigs
-				log.Infof("SIG: %v, shutdown...", sig)
-				done <- true
-			}()
+	sigs := make(chan os.Signal, 1)
+	done := make(chan bool, 1)
 
-			rootCtx := context.Background()
-			_, cancelFn := context.WithCancel(rootCtx)
-			//ctx, cancelFn := context.WithCancel(rootCtx)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 
-			//core.Run(ctx)
+	go func() {
+		sig := <-sigs
+		log.Infof("SIG: %v, shutdown...", sig)
+		done <- true
+	}()
 
-			<-done
-			cancelFn()
+	rootCtx := context.Background()
+	ctx, cancelFn := context.WithCancel(rootCtx)
 
-			//core.Shutdown()
-		},
+	if err := core.Run(ctx, cfg); err != nil {
+		cancelFn()
+		log.Error(err)
+		os.Exit(1)
 	}
 
-	rootCmd.PersistentFlags().StringVarP(&ConfigFile, "config", "c", "", "config file (e.g. ./config.yaml)")
-	rootCmd.PersistentFlags().BoolVarP(&DebugMode, "debug", "d", false, "debug mode")
+	<-done
+
+	// core cancels its own context while tearing down parts in reverse order
+	err := core.Shutdown()
+	cancelFn()
+	if err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
 }
 
 func Run() {
-	// TODO CMD list strategies
-
-	// TODO CMD test strategies
-
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
 	}
@@ -111,8 +117,5 @@ func initConfig() config.Configurations {
 	}
 	cfg.SetDebug(DebugMode)
 
-	// TODO viper.UnmarshalKey() dynamic strategy
-	// custom_name - strategy and params
-
 	return cfg
 }
@@ -28,3 +28,16 @@ func TestReadConfig(t *testing.T) {
 
 	t.Logf("%s", pretty.Sdump(cfg))
 }
+
+func TestRootCommands(t *testing.T) {
+	names := make(map[string]bool)
+	for _, c := range rootCmd.Commands() {
+		names[c.Name()] = true
+	}
+
+	for _, name := range []string{"cache", "strategies"} {
+		if !names[name] {
+			t.Errorf("command %q not registered", name)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"DaruBot/internal/backtest"
+	"DaruBot/internal/config"
+	"DaruBot/pkg/logger"
+	"DaruBot/storage"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	leaderboardBucket = "optimize"
+)
+
+var (
+	strategiesOptimizeCmd = &cobra.Command{
+		Use:   "optimize [strategy]",
+		Short: "Search best strategy parameters by backtests on mock exchange",
+		Example: "  strategies optimize dumb -s BTCUSDT --from 2021-01-01 --to 2021-02-01 " +
+			"-r streak=2:6:1 -r amount=0.001,0.01 --metric sharpe",
+		Args: cobra.ExactArgs(1),
+		RunE: optimizeStrategy,
+	}
+
+	optimizeFlags = struct {
+		ranges  []string
+		search  string
+		samples int
+		workers int
+		metric  string
+		seed    int64
+		top     int
+	}{}
+)
+
+func init() {
+	addOptimizeFlags(strategiesOptimizeCmd)
+	strategiesCmd.AddCommand(strategiesOptimizeCmd)
+}
+
+// addOptimizeFlags add flags of backtestOptions and optimizeOptions to command
+func addOptimizeFlags(cmd *cobra.Command) {
+	addBacktestFlags(cmd)
+	f := cmd.Flags()
+	f.StringToStringVarP(&testFlags.params, "param", "p", nil, "fixed strategy parameter (e.g. -p amount=0.1)")
+	f.StringArrayVarP(&optimizeFlags.ranges, "range", "r", nil, "parameter range min:max:step or list a,b,c (e.g. -r streak=2:6:1)")
+	f.StringVar(&optimizeFlags.search, "search", backtest.SearchGrid, "search method: grid or random")
+	f.IntVar(&optimizeFlags.samples, "samples", 20, "runs of random search")
+	f.IntVar(&optimizeFlags.workers, "workers", 0, "parallel backtests, number of CPU if 0")
+	f.StringVar(&optimizeFlags.metric, "metric", "return", "rank by metric: "+strings.Join(backtest.Metrics(), ", "))
+	f.Int64Var(&optimizeFlags.seed, "seed", 0, "seed of random search, random if 0")
+	f.IntVar(&optimizeFlags.top, "top", 10, "print top results, all if 0")
+	_ = cmd.MarkFlagRequired("range")
+}
+
+func optimizeStrategy(cmd *cobra.Command, args []string) error {
+	cfg := initConfig()
+
+	logLevel := logger.ErrorLevel
+	if cfg.IsDebug() {
+		logLevel = logger.DebugLevel
+	}
+	lg := logger.New(os.Stdout, logLevel)
+
+	opts, err := optimizeOptions(args[0], cfg)
+	if err != nil {
+		return err
+	}
+
+	cache, err := newCandlesCache(cfg, lg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			lg.Error(err)
+		}
+	}()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	lb, err := backtest.Optimize(ctx, opts, cache, lg)
+	if lb == nil {
+		return err
+	}
+
+	printLeaderboard(lb, optimizeFlags.top)
+
+	// leaderboard of interrupted optimization saved too
+	if saveErr := saveLeaderboard(cfg, lb); saveErr != nil {
+		return saveErr
+	}
+
+	return err
+}
+
+func optimizeOptions(name string, cfg config.Configurations) (backtest.OptimizeOptions, error) {
+	base, err := backtestOptions(name, cfg)
+	if err != nil {
+		return backtest.OptimizeOptions{}, err
+	}
+
+	opts := backtest.OptimizeOptions{
+		Options: base,
+		Search:  optimizeFlags.search,
+		Samples: optimizeFlags.samples,
+		Workers: optimizeFlags.workers,
+		Metric:  optimizeFlags.metric,
+		Seed:    optimizeFlags.seed,
+	}
+
+	for _, spec := range optimizeFlags.ranges {
+		r, err := backtest.ParseParamRange(spec)
+		if err != nil {
+			return opts, err
+		}
+		opts.Ranges = append(opts.Ranges, r)
+	}
+
+	return opts, nil
+}
+
+func saveLeaderboard(cfg config.Configurations, lb *backtest.Leaderboard) error {
+	st, err := storage.New(cfg)
+	if err != nil {
+		return err
+	}
+	defer st.Stop()
+
+	cs, err := st.ProvideCustomStorage(leaderboardBucket)
+	if err != nil {
+		return err
+	}
+
+	if err := cs.Save(lb.Key(), lb); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nLeaderboard saved to %s, bucket %s, key %s\n", cfg.Storage.Local.Path, leaderboardBucket, lb.Key())
+
+	return nil
+}
+
+func printLeaderboard(lb *backtest.Leaderboard, top int) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "\nLeaderboard %s by %s (%d runs):\n", lb.Strategy, lb.Metric, len(lb.Entries))
+	fmt.Fprintf(w, "RANK\tSCORE\tRETURN\tDRAWDOWN\tSHARPE\tTRADES\tWIN RATE\tPARAMETERS\n")
+
+	for i, e := range lb.Entries {
+		if top > 0 && i >= top {
+			break
+		}
+
+		if e.Error != "" {
+			fmt.Fprintf(w, "%d\t-\t-\t-\t-\t-\t-\t%s error: %s\n", e.Rank, formatParams(e.Params), e.Error)
+			continue
+		}
+
+		fmt.Fprintf(w, "%d\t%.4f\t%.2f%%\t%.2f%%\t%.2f\t%d\t%.2f%%\t%s\n",
+			e.Rank, e.Score, e.Return, e.MaxDrawdown, e.Sharpe, e.Trades, e.WinRate, formatParams(e.Params))
+	}
+
+	_ = w.Flush()
+}
+
+func formatParams(params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	rs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		rs = append(rs, fmt.Sprintf("%s=%v", k, params[k]))
+	}
+
+	return strings.Join(rs, " ")
+}
@@ -0,0 +1,317 @@
+package cmd
+
+import (
+	"DaruBot/internal/backtest"
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/config"
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/strategy"
+	"DaruBot/pkg/logger"
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	dateFormat = "2006-01-02"
+)
+
+var (
+	strategiesCmd = &cobra.Command{
+		Use:   "strategies",
+		Short: "Strategies tools",
+	}
+
+	strategiesListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List registered strategies with parameters",
+		Run:   listStrategies,
+	}
+
+	strategiesTestCmd = &cobra.Command{
+		Use:   "test [strategy]",
+		Short: "Backtest strategy on mock exchange",
+		Args:  cobra.ExactArgs(1),
+		RunE:  testStrategy,
+	}
+
+	testFlags = struct {
+		symbol      string
+		from        string
+		to          string
+		market      string
+		data        string
+		aggregate   bool
+		currency    string
+		deposit     float64
+		maxLeverage uint8
+		makerFee    float64
+		takerFee    float64
+		slippage    float64
+		slippagePct float64
+		volumeShare float64
+		tick        time.Duration
+		step        time.Duration
+		snapshot    time.Duration
+		report      string
+		params      map[string]string
+	}{}
+)
+
+func init() {
+	addBacktestFlags(strategiesTestCmd)
+	f := strategiesTestCmd.Flags()
+	f.StringVar(&testFlags.report, "report", "", "save report to file (.json, .csv or .html)")
+	f.StringToStringVarP(&testFlags.params, "param", "p", nil, "strategy parameter (e.g. -p amount=0.1)")
+
+	strategiesCmd.AddCommand(strategiesListCmd, strategiesTestCmd)
+	rootCmd.AddCommand(strategiesCmd)
+}
+
+// addBacktestFlags add flags of backtestOptions to command
+func addBacktestFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVarP(&testFlags.symbol, "symbol", "s", "", "symbol to trade (e.g. BTCUSDT)")
+	f.StringVar(&testFlags.from, "from", "", "start date (2006-01-02)")
+	f.StringVar(&testFlags.to, "to", "", "end date (2006-01-02), now if empty")
+	f.StringVar(&testFlags.market, "market", "", "go-quote market (config exchanges.mock.market if empty)")
+	f.StringVar(&testFlags.data, "data", "", "directory with local candles files, used instead of market (config exchanges.mock.data if empty)")
+	f.BoolVar(&testFlags.aggregate, "aggregate", false, "build candles of bigger resolutions from minute candles (config exchanges.mock.aggregate if not set)")
+	f.StringVar(&testFlags.currency, "currency", "", "wallet currency (config exchanges.mock.currency if empty)")
+	f.Float64Var(&testFlags.deposit, "deposit", 0, "starting wallet (config exchanges.mock.deposit if empty)")
+	f.Uint8Var(&testFlags.maxLeverage, "leverage", 0, "max leverage (config exchanges.mock.maxleverage if empty)")
+	f.Float64Var(&testFlags.makerFee, "maker-fee", -1, "maker fee in percent (config exchanges.mock.makerfee if not set)")
+	f.Float64Var(&testFlags.takerFee, "taker-fee", -1, "taker fee in percent (config exchanges.mock.takerfee if not set)")
+	f.Float64Var(&testFlags.slippage, "slippage", -1, "price slippage of market and stop orders (config exchanges.mock.slippage if not set)")
+	f.Float64Var(&testFlags.slippagePct, "slippage-pct", -1, "price slippage in percent (config exchanges.mock.slippagepct if not set)")
+	f.Float64Var(&testFlags.volumeShare, "volume-share", -1, "max part of candle volume filled per tick (config exchanges.mock.volumeshare if not set)")
+	f.DurationVar(&testFlags.tick, "tick", 0, "real time slept between steps, as fast as possible if 0")
+	f.DurationVar(&testFlags.step, "step", time.Minute, "simulated time of one step")
+	f.DurationVar(&testFlags.snapshot, "snapshot", time.Hour, "simulated time between balance snapshots of report")
+	_ = cmd.MarkFlagRequired("from")
+}
+
+func listStrategies(cmd *cobra.Command, args []string) {
+	lg := logger.New(os.Stdout, logger.ErrorLevel)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, name := range strategy.List() {
+		s, err := strategy.New(name, lg)
+		if err != nil {
+			fmt.Fprintf(w, "%s\terror: %v\n", name, err)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\n", name)
+		for _, p := range strategy.Describe(s) {
+			fmt.Fprintf(w, "\t%s\t%s\t%v\n", p.Name, p.Type, p.Default)
+		}
+	}
+}
+
+func testStrategy(cmd *cobra.Command, args []string) error {
+	cfg := initConfig()
+
+	logLevel := logger.WarnLevel
+	if cfg.IsDebug() {
+		logLevel = logger.DebugLevel
+	}
+	lg := logger.New(os.Stdout, logLevel)
+
+	opts, err := backtestOptions(args[0], cfg)
+	if err != nil {
+		return err
+	}
+
+	cache, err := newCandlesCache(cfg, lg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			lg.Error(err)
+		}
+	}()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	rs, err := backtest.Run(ctx, opts, cache, lg)
+	if rs != nil {
+		printResult(rs)
+
+		// report of interrupted run saved too
+		if testFlags.report != "" && rs.Report != nil {
+			if err := rs.Report.Save(testFlags.report); err != nil {
+				return err
+			}
+			fmt.Printf("\nReport saved to %s\n", testFlags.report)
+		}
+	}
+
+	return err
+}
+
+// newCandlesCache open candles cache with gaps repair and limits of config
+func newCandlesCache(cfg config.Configurations, lg logger.Logger) (*candles.Cache, error) {
+	cache, err := candles.NewCandleCache(cfg.Storage.Candles.Path, lg)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.SetGapOptions(candles.GapOptions{
+		Refetch: cfg.Storage.Candles.RefetchGaps,
+		Fill:    cfg.Storage.Candles.FillGaps,
+	})
+
+	retention, err := candles.ParseRetention(cfg.Storage.Candles.Retention)
+	if err != nil {
+		cache.Close()
+		return nil, err
+	}
+	cache.SetLimits(retention, cfg.Storage.Candles.MaxSize*1024*1024)
+
+	return cache, nil
+}
+
+func backtestOptions(name string, cfg config.Configurations) (backtest.Options, error) {
+	mCfg := cfg.Exchanges.Mock
+
+	opts := backtest.Options{
+		Strategy:    name,
+		Params:      make(map[string]interface{}, len(testFlags.params)),
+		Market:      mCfg.Market,
+		Data:        mCfg.Data,
+		Aggregate:   mCfg.Aggregate,
+		Symbol:      testFlags.symbol,
+		Currency:    mCfg.Currency,
+		Deposit:     mCfg.Deposit,
+		MaxLeverage: mCfg.MaxLeverage,
+		Fees:        mock.Fees{Maker: mCfg.MakerFee, Taker: mCfg.TakerFee},
+		Tick:        testFlags.tick,
+		Step:        testFlags.step,
+
+		SnapshotInterval: testFlags.snapshot,
+	}
+
+	for k, v := range testFlags.params {
+		opts.Params[k] = v
+	}
+
+	if testFlags.market != "" {
+		opts.Market = testFlags.market
+	}
+	if testFlags.data != "" {
+		opts.Data = testFlags.data
+	}
+	if testFlags.aggregate {
+		opts.Aggregate = true
+	}
+	if testFlags.currency != "" {
+		opts.Currency = testFlags.currency
+	}
+	if testFlags.deposit != 0 {
+		opts.Deposit = testFlags.deposit
+	}
+	if testFlags.maxLeverage != 0 {
+		opts.MaxLeverage = testFlags.maxLeverage
+	}
+	if testFlags.makerFee >= 0 {
+		opts.Fees.Maker = testFlags.makerFee
+	}
+	if testFlags.takerFee >= 0 {
+		opts.Fees.Taker = testFlags.takerFee
+	}
+
+	fill := mock.SlippageFill{Fixed: mCfg.Slippage, Percent: mCfg.SlippagePct, VolumeShare: mCfg.VolumeShare}
+	if testFlags.slippage >= 0 {
+		fill.Fixed = testFlags.slippage
+	}
+	if testFlags.slippagePct >= 0 {
+		fill.Percent = testFlags.slippagePct
+	}
+	if testFlags.volumeShare >= 0 {
+		fill.VolumeShare = testFlags.volumeShare
+	}
+	opts.Fill = fill
+
+	var err error
+
+	opts.From, err = time.ParseInLocation(dateFormat, testFlags.from, time.Local)
+	if err != nil {
+		return opts, err
+	}
+
+	opts.To = time.Now()
+	if testFlags.to != "" {
+		opts.To, err = time.ParseInLocation(dateFormat, testFlags.to, time.Local)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	return opts, nil
+}
+
+func printResult(rs *backtest.Result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "\nTrades:\n")
+	fmt.Fprintf(w, "DATE\tSYMBOL\tTYPE\tAMOUNT\tPRICE\tFEE\n")
+	for _, t := range rs.Trades {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%v\t%.4f %s\n", t.Updated.Format("2006-01-02 15:04"), t.Symbol, t.Type, t.AmountOriginal-t.AmountCurrent, t.PriceAvg, t.Fee, t.FeeCurrency)
+	}
+
+	fmt.Fprintf(w, "\nSummary:\n")
+	fmt.Fprintf(w, "Strategy\t%s\n", rs.Strategy)
+	fmt.Fprintf(w, "Parameters\t%+v\n", rs.Params)
+	fmt.Fprintf(w, "Trades\t%d\n", rs.Stats.TotalTrades)
+	fmt.Fprintf(w, "Total profit\t%.2f\n", rs.Stats.TotalProfit)
+	fmt.Fprintf(w, "Total loss\t%.2f\n", rs.Stats.TotalLoss)
+	fmt.Fprintf(w, "Total fees\t%.2f\n", rs.Stats.TotalFees)
+	if rs.StartBalance != nil && rs.EndBalance != nil {
+		fmt.Fprintf(w, "Start net worth\t%.2f\n", rs.StartBalance.NetWorth)
+		fmt.Fprintf(w, "End net worth\t%.2f\n", rs.EndBalance.NetWorth)
+		if rs.StartBalance.NetWorth != 0 {
+			fmt.Fprintf(w, "Return\t%.2f%%\n", (rs.EndBalance.NetWorth/rs.StartBalance.NetWorth-1)*100)
+		}
+	}
+	if r := rs.Report; r != nil {
+		fmt.Fprintf(w, "Max drawdown\t%.2f%%\n", r.MaxDrawdown)
+		fmt.Fprintf(w, "Sharpe\t%.2f\n", r.Sharpe)
+		fmt.Fprintf(w, "Sortino\t%.2f\n", r.Sortino)
+		fmt.Fprintf(w, "Round trips\t%d\n", r.Trades)
+		fmt.Fprintf(w, "Win rate\t%.2f%%\n", r.WinRate)
+		fmt.Fprintf(w, "Profit factor\t%.2f\n", r.ProfitFactor)
+		fmt.Fprintf(w, "Avg trade duration\t%s\n", r.AvgTradeDuration)
+		fmt.Fprintf(w, "Exposure\t%.2f%%\n", r.Exposure)
+	}
+
+	_ = w.Flush()
+}
+
+// signalContext returns context canceled on SIGINT/SIGTERM
+func signalContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sigs)
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"DaruBot/internal/backtest"
+	"DaruBot/pkg/logger"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	strategiesWalkForwardCmd = &cobra.Command{
+		Use:   "walkforward [strategy]",
+		Short: "Optimize strategy on rolling in-sample windows and validate on following out-of-sample windows",
+		Example: "  strategies walkforward dumb -s BTCUSDT --from 2021-01-01 --to 2021-04-01 " +
+			"--in-sample 720h --out-sample 168h -r streak=2:6:1",
+		Args: cobra.ExactArgs(1),
+		RunE: walkForwardStrategy,
+	}
+
+	walkForwardFlags = struct {
+		inSample  time.Duration
+		outSample time.Duration
+		anchored  bool
+		report    string
+	}{}
+)
+
+func init() {
+	addOptimizeFlags(strategiesWalkForwardCmd)
+	f := strategiesWalkForwardCmd.Flags()
+	f.DurationVar(&walkForwardFlags.inSample, "in-sample", 30*24*time.Hour, "in-sample window for optimization")
+	f.DurationVar(&walkForwardFlags.outSample, "out-sample", 7*24*time.Hour, "out-of-sample window for validation, windows shifted by it")
+	f.BoolVar(&walkForwardFlags.anchored, "anchored", false, "in-sample windows start at --from and grow")
+	f.StringVar(&walkForwardFlags.report, "report", "", "save out-of-sample report to file (.json, .csv or .html)")
+
+	strategiesCmd.AddCommand(strategiesWalkForwardCmd)
+}
+
+func walkForwardStrategy(cmd *cobra.Command, args []string) error {
+	cfg := initConfig()
+
+	logLevel := logger.ErrorLevel
+	if cfg.IsDebug() {
+		logLevel = logger.DebugLevel
+	}
+	lg := logger.New(os.Stdout, logLevel)
+
+	optOpts, err := optimizeOptions(args[0], cfg)
+	if err != nil {
+		return err
+	}
+
+	opts := backtest.WalkForwardOptions{
+		OptimizeOptions: optOpts,
+		InSample:        walkForwardFlags.inSample,
+		OutSample:       walkForwardFlags.outSample,
+		Anchored:        walkForwardFlags.anchored,
+	}
+
+	cache, err := newCandlesCache(cfg, lg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := cache.Close(); err != nil {
+			lg.Error(err)
+		}
+	}()
+
+	ctx, cancel := signalContext()
+	defer cancel()
+
+	rs, err := backtest.WalkForward(ctx, opts, cache, lg)
+	if rs == nil {
+		return err
+	}
+
+	printWalkForward(rs)
+
+	// report of interrupted validation saved too
+	if walkForwardFlags.report != "" && rs.Report != nil {
+		if err := rs.Report.Save(walkForwardFlags.report); err != nil {
+			return err
+		}
+		fmt.Printf("\nReport saved to %s\n", walkForwardFlags.report)
+	}
+
+	return err
+}
+
+func printWalkForward(rs *backtest.WalkForwardResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "\nWindows %s by %s:\n", rs.Strategy, rs.Metric)
+	fmt.Fprintf(w, "IN-SAMPLE\tOUT-OF-SAMPLE\tIN SCORE\tIN RETURN\tOUT RETURN\tOUT DRAWDOWN\tOUT TRADES\tPARAMETERS\n")
+	for _, win := range rs.Windows {
+		period := func(from, to time.Time) string {
+			return from.Format("2006-01-02 15:04") + " - " + to.Format("2006-01-02 15:04")
+		}
+
+		if win.Error != "" {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t-\t-\t-\t%s error: %s\n",
+				period(win.InFrom, win.InTo), period(win.OutFrom, win.OutTo), formatParams(win.Params), win.Error)
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%.4f\t%.2f%%\t%.2f%%\t%.2f%%\t%d\t%s\n",
+			period(win.InFrom, win.InTo), period(win.OutFrom, win.OutTo),
+			win.InScore, win.InReturn, win.OutReturn, win.OutMaxDrawdown, win.OutTrades, formatParams(win.Params))
+	}
+
+	if r := rs.Report; r != nil {
+		fmt.Fprintf(w, "\nOut-of-sample summary:\n")
+		fmt.Fprintf(w, "Start net worth\t%.2f\n", r.StartBalance)
+		fmt.Fprintf(w, "End net worth\t%.2f\n", r.EndBalance)
+		fmt.Fprintf(w, "Return\t%.2f%%\n", r.Return)
+		fmt.Fprintf(w, "Max drawdown\t%.2f%%\n", r.MaxDrawdown)
+		fmt.Fprintf(w, "Sharpe\t%.2f\n", r.Sharpe)
+		fmt.Fprintf(w, "Sortino\t%.2f\n", r.Sortino)
+		fmt.Fprintf(w, "Round trips\t%d\n", r.Trades)
+		fmt.Fprintf(w, "Win rate\t%.2f%%\n", r.WinRate)
+		fmt.Fprintf(w, "Profit factor\t%.2f\n", r.ProfitFactor)
+		fmt.Fprintf(w, "Fees\t%.2f\n", r.TotalFees)
+	}
+	fmt.Fprintf(w, "Walk-forward efficiency\t%.2f\n", rs.Efficiency)
+
+	_ = w.Flush()
+}
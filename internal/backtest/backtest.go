@@ -0,0 +1,231 @@
+/*
+Backtest run strategy against mock exchange on historical candles
+*/
+package backtest
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/config"
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/models"
+	"DaruBot/internal/models/exchanges"
+	"DaruBot/internal/strategy"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"context"
+	"time"
+)
+
+const (
+	defaultSnapshotInterval = time.Hour
+)
+
+var (
+	ErrWrongPeriod = errors.New("WRONG BACKTEST PERIOD")
+)
+
+type Options struct {
+	Strategy string
+	Params   map[string]interface{}
+
+	Market      string
+	Data        string // directory with local candles files, used instead of Market if set
+	Aggregate   bool   // build candles of bigger resolutions from minute candles
+	Symbol      string
+	From        time.Time
+	To          time.Time
+	Currency    string
+	Deposit     float64
+	MaxLeverage uint8
+	Fees        mock.Fees
+	Fill        mock.FillModel // perfect fill if nil
+	Tick        time.Duration  // real time slept between steps, as fast as possible if 0
+	Step        time.Duration  // simulated time of one step, one minute if 0
+
+	// Interval of balance snapshots for report, one hour if 0
+	SnapshotInterval time.Duration
+}
+
+type Result struct {
+	Strategy     string
+	Params       interface{}
+	Trades       []*models.Order
+	Stats        models.Stats
+	StartBalance *models.BalanceUSD
+	EndBalance   *models.BalanceUSD
+	Balances     []mock.BalanceSnapshot
+	Report       *Report
+}
+
+// Run backtest, blocks until end of period or context done
+func Run(ctx context.Context, opts Options, cache *candles.Cache, lg logger.Logger) (*Result, error) {
+	if !opts.To.After(opts.From) {
+		return nil, ErrWrongPeriod
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lg = lg.WithPrefix("backtest", opts.Strategy)
+
+	s, err := newStrategy(opts, lg)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := mock.NewSource(opts.Market, opts.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &models.Wallets{WalletType: models.WalletTypeExchange}
+	w.Update(&models.WalletCurrency{
+		Name:       opts.Currency,
+		WalletType: models.WalletTypeExchange,
+		Balance:    opts.Deposit,
+		Available:  opts.Deposit,
+	})
+
+	from := opts.From.Truncate(time.Minute)
+
+	wManager := watcher.NewWatcherManager()
+	stand := mock.NewTheWorld(from, opts.To, opts.Tick)
+	stand.SetStep(opts.Step)
+	plutos := mock.NewPlutos(opts.MaxLeverage, opts.Fees, opts.Currency, w, []models.Order{}, []models.Position{})
+	if opts.Fill != nil {
+		plutos.SetFillModel(opts.Fill)
+	}
+
+	snapshotInterval := opts.SnapshotInterval
+	if snapshotInterval == 0 {
+		snapshotInterval = defaultSnapshotInterval
+	}
+	plutos.SetBalanceInterval(snapshotInterval)
+
+	// result is wrong if orders were not processed, first error stops backtest
+	processErr := make(chan error, 1)
+	plutos.SetErrorHandler(func(err error) {
+		select {
+		case processErr <- err:
+			cancel()
+		default:
+		}
+	})
+
+	cfg := config.GetDefaultConfig()
+	cfg.Exchanges.Mock.Aggregate = opts.Aggregate
+
+	ex, err := mock.NewExchangeMock(ctx, wManager, lg, cfg, source, cache, stand, plutos)
+	if err != nil {
+		return nil, err
+	}
+	// strategy acks every tick, results do not depend on speed of strategy
+	mock.SyncTicks(ex)
+
+	rs := &Result{
+		Strategy: opts.Strategy,
+		Params:   s.Params(),
+	}
+
+	rs.StartBalance, err = ex.GetBalance()
+	if err != nil {
+		return nil, err
+	}
+
+	if hu, ok := s.(strategy.HistoryUser); ok {
+		hu.SetHistory(strategy.NewHistory(ex, nil, "", stand.CurrentTime))
+	}
+
+	if err := s.Init(ex, wManager, newMemoryStorage()); err != nil {
+		return nil, err
+	}
+
+	strategyCtx, strategyCancel := context.WithCancel(ctx)
+	defer strategyCancel()
+
+	done, err := strategy.Start(strategyCtx, s, wManager, exchanges.ExchangeTypeMock.String(), lg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ex.Connect(); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-stand.Done():
+	case <-ctx.Done():
+	}
+
+	strategyCancel()
+	<-done
+
+	if err := s.Shutdown(); err != nil {
+		lg.Error("strategy shutdown", err)
+	}
+
+	rs.Trades = plutos.GetHistory()
+	rs.Stats = calcStats(rs.Trades, opts.Currency)
+
+	rs.EndBalance, err = ex.GetBalance()
+	if err != nil {
+		return nil, err
+	}
+
+	rs.Balances = plutos.GetBalanceHistory()
+	rs.Report = NewReport(rs, opts.Currency, rs.Balances, snapshotInterval)
+
+	select {
+	case err := <-processErr:
+		return rs, errors.WrapMessage(err, "process orders")
+	default:
+	}
+
+	return rs, ctx.Err()
+}
+
+func newStrategy(opts Options, lg logger.Logger) (strategy.Strategy, error) {
+	s, err := strategy.New(opts.Strategy, lg)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]interface{}, len(opts.Params)+1)
+	if opts.Symbol != "" && strategy.HasParam(s, "symbol") {
+		params["symbol"] = opts.Symbol
+	}
+	for k, v := range opts.Params {
+		params[k] = v
+	}
+
+	if err := strategy.DecodeParams(params, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// calcStats count profit and loss of closed round trips, fees of all trades converted to currency by trade price
+func calcStats(trades []*models.Order, currency string) models.Stats {
+	rs := models.Stats{}
+
+	for _, t := range trades {
+		if t.FeeCurrency == currency {
+			rs.TotalFees = rs.TotalFees + t.Fee
+		} else {
+			rs.TotalFees = rs.TotalFees + t.Fee*t.PriceAvg
+		}
+	}
+
+	for _, t := range roundTrips(trades, currency) {
+		rs.TotalTrades++
+		if t.Profit > 0 {
+			rs.TotalProfit = rs.TotalProfit + t.Profit
+		} else {
+			rs.TotalLoss = rs.TotalLoss - t.Profit
+		}
+	}
+
+	return rs
+}
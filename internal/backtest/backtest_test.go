@@ -0,0 +1,179 @@
+package backtest
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/exchanges"
+	"DaruBot/internal/models"
+	"DaruBot/internal/strategy"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"DaruBot/storage"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalcStats(t *testing.T) {
+	trades := []*models.Order{
+		{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 100, Fee: 0.25, FeeCurrency: "USDT"},
+		{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 200},
+		{Symbol: "BTCUSDT", AmountOriginal: -1, PriceAvg: 250},
+		{Symbol: "BTCUSDT", AmountOriginal: -1, PriceAvg: 100},
+		{Symbol: "ETHUSDT", AmountOriginal: 2, PriceAvg: 10},
+		{Symbol: "ETHUSDT", AmountOriginal: -2, PriceAvg: 12, Fee: 0.125, FeeCurrency: "ETH"},
+		{Symbol: "ETHUSDT", AmountOriginal: 4, AmountCurrent: 3, PriceAvg: 10},
+		{Symbol: "ETHUSDT", AmountOriginal: -4, AmountCurrent: -3, PriceAvg: 13},
+		{Symbol: "XRPUSDT", AmountOriginal: -10, PriceAvg: 1},
+		{Symbol: "XRPUSDT", AmountOriginal: 10, PriceAvg: 1.5},
+	}
+
+	stats := calcStats(trades, "USDT")
+
+	want := models.Stats{
+		TotalLoss:   5,
+		TotalProfit: 55.25,
+		TotalTrades: 4,
+		TotalFees:   1.75,
+	}
+
+	if stats != want {
+		t.Fatalf("expected %+v, got %+v", want, stats)
+	}
+}
+
+const (
+	testSymbol = "BTCUSDT"
+	testEvery  = 5 // strategy trades on every N-th candle
+)
+
+// testTrader buy on every testEvery candle and sell on the next one
+type testTrader struct {
+	ex      exchanges.CryptoExchange
+	candles int
+	holding bool
+}
+
+func newTestTrader(lg logger.Logger) strategy.Strategy {
+	return &testTrader{}
+}
+
+func (t *testTrader) Name() string        { return "test_trader" }
+func (t *testTrader) Params() interface{} { return &struct{}{} }
+func (t *testTrader) Init(ex exchanges.CryptoExchange, wManager *watcher.Manager, st storage.CustomStorage) error {
+	t.ex = ex
+	_, err := ex.SubscribeCandles(testSymbol, models.OneMinute)
+	return err
+}
+func (t *testTrader) OnTicker(ticker models.Ticker) {}
+func (t *testTrader) OnCandle(candle models.Candle) {
+	t.candles++
+	if t.candles%testEvery != 0 && !t.holding {
+		return
+	}
+
+	amount := 1.0
+	if t.holding {
+		amount = -1
+	}
+	if _, err := t.ex.PutOrder(&models.PutOrder{Symbol: testSymbol, Type: models.OrderTypeMarket, Amount: amount}); err != nil {
+		panic(err)
+	}
+	t.holding = !t.holding
+}
+func (t *testTrader) OnOrder(event watcher.EventHead, order models.Order)          {}
+func (t *testTrader) OnPosition(event watcher.EventHead, position models.Position) {}
+func (t *testTrader) Shutdown() error                                              { return nil }
+
+func init() {
+	strategy.Register("test_trader", newTestTrader)
+}
+
+// writeTestCandles write two hours of minute candles, open price of minute N is 100+N
+func writeTestCandles(t *testing.T, dir string, from time.Time) {
+	b := &strings.Builder{}
+	b.WriteString("datetime,open,high,low,close,volume\n")
+	for i := 0; i < 120; i++ {
+		open := 100 + float64(i)
+		fmt.Fprintf(b, "%s,%.2f,%.2f,%.2f,%.2f,10\n", from.Add(time.Duration(i)*time.Minute).UTC().Format("2006-01-02 15:04"),
+			open, open+1.5, open-0.5, open+1)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, testSymbol+"_1m.csv"), []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// daily candle of emulated ticker
+	daily := fmt.Sprintf("datetime,open,high,low,close,volume\n%s,100,221.5,99.5,220,1200\n", from.UTC().Format("2006-01-02"))
+	if err := ioutil.WriteFile(filepath.Join(dir, testSymbol+"_D.csv"), []byte(daily), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	from := time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC).Local()
+	writeTestCandles(t, dir, from)
+
+	lg := logger.New(os.Stdout, logger.ErrorLevel)
+
+	run := func() *Result {
+		cache, err := candles.NewCandleCache(filepath.Join(dir, "candles.cache"), lg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cache.Close()
+
+		rs, err := Run(context.Background(), Options{
+			Strategy:    "test_trader",
+			Data:        dir,
+			Symbol:      testSymbol,
+			From:        from,
+			To:          from.Add(time.Hour),
+			Currency:    "USDT",
+			Deposit:     1000,
+			MaxLeverage: 1,
+		}, cache, lg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs
+	}
+
+	rs := run()
+	// last buy on the last candle is not closed
+	if len(rs.Trades) != 2*(60/testEvery)-1 {
+		t.Fatalf("expected %d trades, got %d", 2*(60/testEvery)-1, len(rs.Trades))
+	}
+
+	for i, o := range rs.Trades {
+		// candle N closed at N+1 minute, order filled at that moment by price of next candle
+		n := (i/2)*testEvery + testEvery + i%2
+		fill := from.Add(time.Duration(n) * time.Minute)
+		if !o.Updated.Equal(fill) {
+			t.Errorf("trade %d filled at %s, expected %s", i, o.Updated, fill)
+		}
+		if low, high := 99.5+float64(n), 101.5+float64(n); o.PriceAvg < low || o.PriceAvg > high {
+			t.Errorf("trade %d filled by %v, expected %v - %v", i, o.PriceAvg, low, high)
+		}
+	}
+
+	again := run()
+	if len(again.Trades) != len(rs.Trades) || again.Stats != rs.Stats {
+		t.Fatalf("results are different: %+v, %+v", rs.Stats, again.Stats)
+	}
+	for i := range rs.Trades {
+		if !again.Trades[i].Updated.Equal(rs.Trades[i].Updated) || again.Trades[i].PriceAvg != rs.Trades[i].PriceAvg {
+			t.Errorf("trade %d is different", i)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package backtest
+
+import (
+	"DaruBot/pkg/errors"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth  = 1000
+	chartHeight = 300
+)
+
+var (
+	ErrUnknownReportFormat = errors.New("UNKNOWN REPORT FORMAT")
+)
+
+// Save write report to file, format chosen by extension: .json, .csv or .html.
+// For csv equity curve written to path and round trips to <name>_trades.csv
+func (r *Report) Save(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	switch ext {
+	case ".json":
+		return writeFile(path, r.WriteJSON)
+	case ".csv":
+		if err := writeFile(path, r.WriteEquityCSV); err != nil {
+			return err
+		}
+		return writeFile(strings.TrimSuffix(path, filepath.Ext(path))+"_trades.csv", r.WriteTradesCSV)
+	case ".html", ".htm":
+		return writeFile(path, r.WriteHTML)
+	default:
+		return errors.WrapMessage(ErrUnknownReportFormat, ext)
+	}
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Report) WriteEquityCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"time", "net_worth", "drawdown"}); err != nil {
+		return err
+	}
+
+	for _, p := range r.Equity {
+		err := cw.Write([]string{
+			p.Time.Format(time.RFC3339),
+			formatFloat(p.NetWorth),
+			formatFloat(p.Drawdown),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *Report) WriteTradesCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"symbol", "side", "open", "close", "amount", "entry_price", "exit_price", "fees", "profit"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, t := range r.RoundTrips {
+		err := cw.Write([]string{
+			t.Symbol,
+			tripSide(t),
+			t.Open.Format(time.RFC3339),
+			t.Close.Format(time.RFC3339),
+			formatFloat(t.Amount),
+			formatFloat(t.EntryPrice),
+			formatFloat(t.ExitPrice),
+			formatFloat(t.Fees),
+			formatFloat(t.Profit),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, struct {
+		*Report
+		Width  int
+		Height int
+		Line   string
+	}{
+		Report: r,
+		Width:  chartWidth,
+		Height: chartHeight,
+		Line:   r.equityLine(chartWidth, chartHeight),
+	})
+}
+
+// equityLine returns points of svg polyline for equity curve
+func (r *Report) equityLine(width, height float64) string {
+	if len(r.Equity) == 0 {
+		return ""
+	}
+
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, p := range r.Equity {
+		min = math.Min(min, p.NetWorth)
+		max = math.Max(max, p.NetWorth)
+	}
+	if max == min {
+		max = min + 1
+	}
+
+	step := 0.0
+	if len(r.Equity) > 1 {
+		step = width / float64(len(r.Equity)-1)
+	}
+
+	points := make([]string, 0, len(r.Equity))
+	for i, p := range r.Equity {
+		x := float64(i) * step
+		y := height - (p.NetWorth-min)/(max-min)*height
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+
+	return strings.Join(points, " ")
+}
+
+func tripSide(t RoundTrip) string {
+	if t.Short {
+		return "short"
+	}
+	return "long"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"side": tripSide,
+	"date": func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Backtest {{.Strategy}}</title>
+<style>
+body { font-family: sans-serif; margin: 20px; }
+table { border-collapse: collapse; margin-bottom: 20px; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th { background: #eee; }
+svg { border: 1px solid #ccc; }
+</style>
+</head>
+<body>
+<h1>{{.Strategy}}</h1>
+<p>{{date .From}} - {{date .To}}, parameters: {{printf "%+v" .Params}}</p>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" preserveAspectRatio="none">
+<polyline fill="none" stroke="#2a7ae2" stroke-width="1.5" points="{{.Line}}"/>
+</svg>
+<h2>Summary</h2>
+<table>
+<tr><th>Start balance</th><td>{{printf "%.2f" .StartBalance}}</td></tr>
+<tr><th>End balance</th><td>{{printf "%.2f" .EndBalance}}</td></tr>
+<tr><th>Return</th><td>{{printf "%.2f" .Return}}%</td></tr>
+<tr><th>Max drawdown</th><td>{{printf "%.2f" .MaxDrawdown}}% ({{printf "%.2f" .MaxDrawdownValue}})</td></tr>
+<tr><th>Sharpe</th><td>{{printf "%.2f" .Sharpe}}</td></tr>
+<tr><th>Sortino</th><td>{{printf "%.2f" .Sortino}}</td></tr>
+<tr><th>Trades</th><td>{{.Trades}}</td></tr>
+<tr><th>Win rate</th><td>{{printf "%.2f" .WinRate}}%</td></tr>
+<tr><th>Profit factor</th><td>{{printf "%.2f" .ProfitFactor}}</td></tr>
+<tr><th>Avg trade duration</th><td>{{.AvgTradeDuration}}</td></tr>
+<tr><th>Exposure</th><td>{{printf "%.2f" .Exposure}}%</td></tr>
+<tr><th>Fees</th><td>{{printf "%.2f" .TotalFees}}</td></tr>
+</table>
+<h2>Trades</h2>
+<table>
+<tr><th>Symbol</th><th>Side</th><th>Open</th><th>Close</th><th>Amount</th><th>Entry</th><th>Exit</th><th>Fees</th><th>Profit</th></tr>
+{{range .RoundTrips}}<tr><td>{{.Symbol}}</td><td>{{side .}}</td><td>{{date .Open}}</td><td>{{date .Close}}</td><td>{{.Amount}}</td><td>{{printf "%.2f" .EntryPrice}}</td><td>{{printf "%.2f" .ExitPrice}}</td><td>{{printf "%.4f" .Fees}}</td><td>{{printf "%.2f" .Profit}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,329 @@
+package backtest
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SearchGrid   = "grid"
+	SearchRandom = "random"
+
+	defaultMetric = "return"
+)
+
+var (
+	ErrWrongParamRange = errors.New("WRONG PARAMETER RANGE")
+	ErrUnknownMetric   = errors.New("UNKNOWN OPTIMIZATION METRIC")
+	ErrUnknownSearch   = errors.New("UNKNOWN SEARCH METHOD")
+)
+
+// metrics of report used for ranking, bigger is better
+var metrics = map[string]func(r *Report) float64{
+	"return":        func(r *Report) float64 { return r.Return },
+	"sharpe":        func(r *Report) float64 { return r.Sharpe },
+	"sortino":       func(r *Report) float64 { return r.Sortino },
+	"profit_factor": func(r *Report) float64 { return r.ProfitFactor },
+	"win_rate":      func(r *Report) float64 { return r.WinRate },
+	"drawdown":      func(r *Report) float64 { return -r.MaxDrawdown },
+}
+
+// Metrics returns sorted names of metrics available for ranking
+func Metrics() []string {
+	rs := make([]string, 0, len(metrics))
+	for name := range metrics {
+		rs = append(rs, name)
+	}
+	sort.Strings(rs)
+	return rs
+}
+
+// ParamRange values of strategy parameter to try
+type ParamRange struct {
+	Name   string
+	Values []interface{}
+}
+
+// ParseParamRange parse range of parameter from name=min:max:step or name=a,b,c
+func ParseParamRange(spec string) (ParamRange, error) {
+	i := strings.Index(spec, "=")
+	if i <= 0 || i == len(spec)-1 {
+		return ParamRange{}, errors.WrapMessage(ErrWrongParamRange, spec)
+	}
+
+	rs := ParamRange{Name: spec[:i]}
+	values := spec[i+1:]
+
+	if !strings.Contains(values, ":") {
+		for _, v := range strings.Split(values, ",") {
+			rs.Values = append(rs.Values, strings.TrimSpace(v))
+		}
+		return rs, nil
+	}
+
+	parts := strings.Split(values, ":")
+	if len(parts) != 3 {
+		return ParamRange{}, errors.WrapMessage(ErrWrongParamRange, spec)
+	}
+
+	nums := make([]float64, 3)
+	decimals := 0
+	for k, p := range parts {
+		n, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return ParamRange{}, errors.WrapMessage(ErrWrongParamRange, spec)
+		}
+		nums[k] = n
+
+		if d := strings.Index(p, "."); d >= 0 && len(p)-d-1 > decimals {
+			decimals = len(p) - d - 1
+		}
+	}
+
+	min, max, step := nums[0], nums[1], nums[2]
+	if step <= 0 || max < min {
+		return ParamRange{}, errors.WrapMessage(ErrWrongParamRange, spec)
+	}
+
+	// values rounded to precision of range, so 0.1 steps give 0.3 but not 0.30000000000000004
+	pow := math.Pow(10, float64(decimals))
+	for n := 0; ; n++ {
+		v := math.Round((min+float64(n)*step)*pow) / pow
+		if v > max {
+			break
+		}
+		rs.Values = append(rs.Values, v)
+	}
+
+	return rs, nil
+}
+
+type OptimizeOptions struct {
+	Options // base options of every run, Params not in Ranges stay fixed
+
+	Ranges  []ParamRange
+	Search  string // grid or random, grid if empty
+	Samples int    // runs of random search
+	Workers int    // parallel runs, number of CPU if 0
+	Metric  string // name from Metrics(), return if empty
+	Seed    int64  // seed of random search, current time if 0
+}
+
+// Leaderboard results of optimization sorted by score, failed runs at the end
+type Leaderboard struct {
+	Strategy string
+	Symbol   string
+	From     time.Time
+	To       time.Time
+	Metric   string
+	Search   string
+	Created  time.Time
+	Entries  []LeaderboardEntry
+}
+
+type LeaderboardEntry struct {
+	Rank        int
+	Params      map[string]interface{}
+	Score       float64
+	Return      float64
+	MaxDrawdown float64
+	Sharpe      float64
+	Sortino     float64
+	Trades      int
+	WinRate     float64
+	TotalFees   float64
+	Error       string `json:",omitempty"`
+}
+
+// Key used to save leaderboard to storage
+func (l *Leaderboard) Key() string {
+	return fmt.Sprintf("%s_%s", l.Strategy, l.Created.Format("20060102_150405"))
+}
+
+// Optimize run backtest for every parameters set in parallel and rank them by metric.
+// Candles cache shared between runs. Interrupted optimization returns leaderboard of finished runs
+func Optimize(ctx context.Context, opts OptimizeOptions, cache *candles.Cache, lg logger.Logger) (*Leaderboard, error) {
+	if opts.Metric == "" {
+		opts.Metric = defaultMetric
+	}
+	score, ok := metrics[opts.Metric]
+	if !ok {
+		return nil, errors.WrapMessage(ErrUnknownMetric, opts.Metric)
+	}
+
+	if opts.Search == "" {
+		opts.Search = SearchGrid
+	}
+
+	var sets []map[string]interface{}
+	switch opts.Search {
+	case SearchGrid:
+		sets = gridParams(opts.Ranges)
+	case SearchRandom:
+		seed := opts.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		sets = randomParams(opts.Ranges, opts.Samples, rand.New(rand.NewSource(seed)))
+	default:
+		return nil, errors.WrapMessage(ErrUnknownSearch, opts.Search)
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	lb := &Leaderboard{
+		Strategy: opts.Strategy,
+		Symbol:   opts.Symbol,
+		From:     opts.From,
+		To:       opts.To,
+		Metric:   opts.Metric,
+		Search:   opts.Search,
+		Created:  time.Now(),
+		Entries:  make([]LeaderboardEntry, 0, len(sets)),
+	}
+
+	jobs := make(chan map[string]interface{})
+	mu := &sync.Mutex{}
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for params := range jobs {
+				runOpts := opts.Options
+				runOpts.Params = mergeParams(opts.Params, params)
+
+				entry := LeaderboardEntry{Params: runOpts.Params}
+
+				rs, err := Run(ctx, runOpts, cache, lg)
+				if ctx.Err() != nil {
+					// interrupted run is not comparable with finished ones
+					continue
+				}
+				if err != nil {
+					entry.Error = err.Error()
+				} else if r := rs.Report; r != nil {
+					entry.Score = score(r)
+					entry.Return = r.Return
+					entry.MaxDrawdown = r.MaxDrawdown
+					entry.Sharpe = r.Sharpe
+					entry.Sortino = r.Sortino
+					entry.Trades = r.Trades
+					entry.WinRate = r.WinRate
+					entry.TotalFees = r.TotalFees
+				}
+
+				mu.Lock()
+				lb.Entries = append(lb.Entries, entry)
+				mu.Unlock()
+			}
+		}()
+	}
+
+loop:
+	for _, params := range sets {
+		select {
+		case jobs <- params:
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	lb.rank()
+
+	return lb, ctx.Err()
+}
+
+func (l *Leaderboard) rank() {
+	sort.SliceStable(l.Entries, func(i, j int) bool {
+		a, b := l.Entries[i], l.Entries[j]
+		if (a.Error == "") != (b.Error == "") {
+			return a.Error == ""
+		}
+		return a.Score > b.Score
+	})
+
+	for i := range l.Entries {
+		l.Entries[i].Rank = i + 1
+	}
+}
+
+func mergeParams(base, params map[string]interface{}) map[string]interface{} {
+	rs := make(map[string]interface{}, len(base)+len(params))
+	for k, v := range base {
+		rs[k] = v
+	}
+	for k, v := range params {
+		rs[k] = v
+	}
+	return rs
+}
+
+// gridParams returns every combination of ranges values
+func gridParams(ranges []ParamRange) []map[string]interface{} {
+	rs := []map[string]interface{}{{}}
+
+	for _, r := range ranges {
+		next := make([]map[string]interface{}, 0, len(rs)*len(r.Values))
+		for _, set := range rs {
+			for _, v := range r.Values {
+				p := mergeParams(set, nil)
+				p[r.Name] = v
+				next = append(next, p)
+			}
+		}
+		rs = next
+	}
+
+	return rs
+}
+
+// randomParams returns up to samples unique random combinations of ranges values
+func randomParams(ranges []ParamRange, samples int, rnd *rand.Rand) []map[string]interface{} {
+	total := 1
+	for _, r := range ranges {
+		total = total * len(r.Values)
+	}
+	if samples <= 0 || samples >= total {
+		return gridParams(ranges)
+	}
+
+	rs := make([]map[string]interface{}, 0, samples)
+	seen := make(map[string]bool, samples)
+
+	for len(rs) < samples {
+		p := make(map[string]interface{}, len(ranges))
+		key := ""
+		for _, r := range ranges {
+			i := rnd.Intn(len(r.Values))
+			p[r.Name] = r.Values[i]
+			key = key + strconv.Itoa(i) + ","
+		}
+
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		rs = append(rs, p)
+	}
+
+	return rs
+}
@@ -0,0 +1,107 @@
+package backtest
+
+import (
+	"DaruBot/pkg/errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestParseParamRange(t *testing.T) {
+	tests := []struct {
+		spec string
+		want ParamRange
+		err  error
+	}{
+		{spec: "streak=2:5:1", want: ParamRange{Name: "streak", Values: []interface{}{2.0, 3.0, 4.0, 5.0}}},
+		{spec: "amount=0.1:0.3:0.1", want: ParamRange{Name: "amount", Values: []interface{}{0.1, 0.2, 0.3}}},
+		{spec: "resolution=1m, 5m,15m", want: ParamRange{Name: "resolution", Values: []interface{}{"1m", "5m", "15m"}}},
+		{spec: "margin=true", want: ParamRange{Name: "margin", Values: []interface{}{"true"}}},
+		{spec: "streak", err: ErrWrongParamRange},
+		{spec: "streak=", err: ErrWrongParamRange},
+		{spec: "streak=1:5", err: ErrWrongParamRange},
+		{spec: "streak=5:1:1", err: ErrWrongParamRange},
+		{spec: "streak=1:5:0", err: ErrWrongParamRange},
+		{spec: "streak=a:5:1", err: ErrWrongParamRange},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseParamRange(tt.spec)
+		if errors.Cause(err) != tt.err {
+			t.Fatalf("%s: expected error %v, got %v", tt.spec, tt.err, err)
+		}
+		if tt.err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s: expected %+v, got %+v", tt.spec, tt.want, got)
+		}
+	}
+}
+
+func TestGridParams(t *testing.T) {
+	ranges := []ParamRange{
+		{Name: "a", Values: []interface{}{1, 2, 3}},
+		{Name: "b", Values: []interface{}{"x", "y"}},
+	}
+
+	sets := gridParams(ranges)
+	if len(sets) != 6 {
+		t.Fatalf("expected 6 sets, got %d", len(sets))
+	}
+
+	seen := make(map[[2]interface{}]bool)
+	for _, s := range sets {
+		seen[[2]interface{}{s["a"], s["b"]}] = true
+	}
+	if len(seen) != 6 {
+		t.Fatalf("expected unique sets, got %v", sets)
+	}
+}
+
+func TestRandomParams(t *testing.T) {
+	ranges := []ParamRange{
+		{Name: "a", Values: []interface{}{1, 2, 3, 4, 5}},
+		{Name: "b", Values: []interface{}{1, 2, 3, 4, 5}},
+	}
+
+	sets := randomParams(ranges, 10, rand.New(rand.NewSource(1)))
+	if len(sets) != 10 {
+		t.Fatalf("expected 10 sets, got %d", len(sets))
+	}
+
+	seen := make(map[[2]interface{}]bool)
+	for _, s := range sets {
+		seen[[2]interface{}{s["a"], s["b"]}] = true
+	}
+	if len(seen) != 10 {
+		t.Fatalf("expected unique sets, got %v", sets)
+	}
+
+	again := randomParams(ranges, 10, rand.New(rand.NewSource(1)))
+	if !reflect.DeepEqual(sets, again) {
+		t.Fatal("random search with same seed is not reproducible")
+	}
+
+	// more samples than combinations
+	if all := randomParams(ranges, 100, rand.New(rand.NewSource(1))); len(all) != 25 {
+		t.Fatalf("expected 25 sets, got %d", len(all))
+	}
+}
+
+func TestLeaderboardRank(t *testing.T) {
+	lb := &Leaderboard{
+		Entries: []LeaderboardEntry{
+			{Score: 1},
+			{Score: 5, Error: "failed"},
+			{Score: -2},
+			{Score: 3},
+		},
+	}
+
+	lb.rank()
+
+	want := []float64{3, 1, -2, 5}
+	for i, e := range lb.Entries {
+		if e.Score != want[i] || e.Rank != i+1 {
+			t.Fatalf("wrong rank %d: %+v", i, e)
+		}
+	}
+}
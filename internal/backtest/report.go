@@ -0,0 +1,264 @@
+package backtest
+
+import (
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/models"
+	"math"
+	"time"
+)
+
+const (
+	// amount less than dust treated as closed position
+	dust = 1e-9
+)
+
+// Report performance metrics of backtest, percents are in 0..100 scale
+type Report struct {
+	Strategy string
+	Params   interface{}
+	From     time.Time
+	To       time.Time
+
+	StartBalance float64
+	EndBalance   float64
+	Return       float64 // percent
+
+	MaxDrawdown      float64 // percent
+	MaxDrawdownValue float64
+	Sharpe           float64 // annualized, zero risk free rate
+	Sortino          float64 // annualized, zero risk free rate
+
+	Trades           int
+	WinRate          float64 // percent
+	GrossProfit      float64
+	GrossLoss        float64
+	ProfitFactor     float64 // 0 if no losing trades
+	AvgTradeDuration time.Duration
+	Exposure         float64 // percent of time in market
+	TotalFees        float64
+
+	Equity     []EquityPoint
+	RoundTrips []RoundTrip
+}
+
+type EquityPoint struct {
+	Time     time.Time
+	NetWorth float64
+	Drawdown float64 // percent from previous peak
+}
+
+// RoundTrip position from open to close, Profit includes fees
+type RoundTrip struct {
+	Symbol     string
+	Short      bool
+	Open       time.Time
+	Close      time.Time
+	Amount     float64
+	EntryPrice float64
+	ExitPrice  float64
+	Fees       float64
+	Profit     float64
+}
+
+// NewReport build report of backtest result by balance snapshots taken every interval
+func NewReport(rs *Result, currency string, snapshots []mock.BalanceSnapshot, interval time.Duration) *Report {
+	r := &Report{
+		Strategy:   rs.Strategy,
+		Params:     rs.Params,
+		TotalFees:  rs.Stats.TotalFees,
+		Equity:     make([]EquityPoint, 0, len(snapshots)),
+		RoundTrips: roundTrips(rs.Trades, currency),
+	}
+
+	if rs.StartBalance != nil {
+		r.StartBalance = rs.StartBalance.NetWorth
+	}
+	if rs.EndBalance != nil {
+		r.EndBalance = rs.EndBalance.NetWorth
+	}
+	if r.StartBalance != 0 {
+		r.Return = (r.EndBalance/r.StartBalance - 1) * 100
+	}
+
+	if len(snapshots) > 0 {
+		r.From = snapshots[0].Time
+		r.To = snapshots[len(snapshots)-1].Time
+	}
+
+	r.calcEquity(snapshots, interval)
+	r.calcTrades()
+
+	return r
+}
+
+func (r *Report) calcEquity(snapshots []mock.BalanceSnapshot, interval time.Duration) {
+	peak := 0.0
+	inMarket := 0
+	returns := make([]float64, 0, len(snapshots))
+
+	for i, s := range snapshots {
+		peak = math.Max(peak, s.NetWorth)
+
+		p := EquityPoint{Time: s.Time, NetWorth: s.NetWorth}
+		if peak > 0 {
+			p.Drawdown = (peak - s.NetWorth) / peak * 100
+		}
+		if p.Drawdown > r.MaxDrawdown {
+			r.MaxDrawdown = p.Drawdown
+			r.MaxDrawdownValue = peak - s.NetWorth
+		}
+		r.Equity = append(r.Equity, p)
+
+		if s.Invested > 0 {
+			inMarket++
+		}
+
+		if i > 0 && snapshots[i-1].NetWorth != 0 {
+			returns = append(returns, s.NetWorth/snapshots[i-1].NetWorth-1)
+		}
+	}
+
+	if len(snapshots) > 0 {
+		r.Exposure = float64(inMarket) / float64(len(snapshots)) * 100
+	}
+
+	if len(returns) < 2 || interval <= 0 {
+		return
+	}
+
+	mean, downside := 0.0, 0.0
+	for _, v := range returns {
+		mean = mean + v
+		if v < 0 {
+			downside = downside + v*v
+		}
+	}
+	mean = mean / float64(len(returns))
+	downside = math.Sqrt(downside / float64(len(returns)))
+
+	variance := 0.0
+	for _, v := range returns {
+		variance = variance + (v-mean)*(v-mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns)-1))
+
+	annual := math.Sqrt(float64(365*24*time.Hour) / float64(interval))
+
+	if std > 0 {
+		r.Sharpe = mean / std * annual
+	}
+	if downside > 0 {
+		r.Sortino = mean / downside * annual
+	}
+}
+
+func (r *Report) calcTrades() {
+	r.Trades = len(r.RoundTrips)
+	if r.Trades == 0 {
+		return
+	}
+
+	wins := 0
+	var duration time.Duration
+
+	for _, t := range r.RoundTrips {
+		if t.Profit > 0 {
+			wins++
+			r.GrossProfit = r.GrossProfit + t.Profit
+		} else {
+			r.GrossLoss = r.GrossLoss - t.Profit
+		}
+		duration = duration + t.Close.Sub(t.Open)
+	}
+
+	r.WinRate = float64(wins) / float64(r.Trades) * 100
+	r.AvgTradeDuration = duration / time.Duration(r.Trades)
+	if r.GrossLoss > 0 {
+		r.ProfitFactor = r.GrossProfit / r.GrossLoss
+	}
+}
+
+// roundTrips split fills to round trips, position still open at the end is not counted
+func roundTrips(trades []*models.Order, currency string) []RoundTrip {
+	type state struct {
+		trip      RoundTrip
+		amount    float64 // signed
+		exitValue float64
+		exitSize  float64
+	}
+
+	rs := make([]RoundTrip, 0)
+	states := make(map[string]*state)
+
+	open := func(st *state, t *models.Order, amount float64, price float64, fee float64) {
+		st.amount = amount
+		st.exitValue, st.exitSize = 0, 0
+		st.trip = RoundTrip{
+			Symbol:     t.Symbol,
+			Short:      amount < 0,
+			Open:       t.Updated,
+			Amount:     math.Abs(amount),
+			EntryPrice: price,
+			Fees:       fee,
+		}
+	}
+
+	for _, t := range trades {
+		executed := t.AmountOriginal - t.AmountCurrent
+		size := math.Abs(executed)
+		if size < dust {
+			continue
+		}
+
+		fee := t.Fee
+		if t.FeeCurrency != currency {
+			fee = fee * t.PriceAvg
+		}
+
+		st, ok := states[t.Symbol]
+		if !ok {
+			st = &state{}
+			states[t.Symbol] = st
+		}
+
+		if math.Abs(st.amount) < dust {
+			open(st, t, executed, t.PriceAvg, fee)
+			continue
+		}
+
+		if math.Signbit(st.amount) == math.Signbit(executed) {
+			total := st.trip.Amount + size
+			st.trip.EntryPrice = (st.trip.EntryPrice*st.trip.Amount + t.PriceAvg*size) / total
+			st.trip.Amount = total
+			st.trip.Fees = st.trip.Fees + fee
+			st.amount = st.amount + executed
+			continue
+		}
+
+		closed := math.Min(size, math.Abs(st.amount))
+		rest := size - closed
+
+		st.trip.Profit = st.trip.Profit + (t.PriceAvg-st.trip.EntryPrice)*math.Copysign(closed, st.amount)
+		st.trip.Fees = st.trip.Fees + fee*closed/size
+		st.exitValue = st.exitValue + t.PriceAvg*closed
+		st.exitSize = st.exitSize + closed
+		st.amount = st.amount - math.Copysign(closed, st.amount)
+
+		if math.Abs(st.amount) >= dust {
+			continue
+		}
+
+		st.trip.Close = t.Updated
+		st.trip.ExitPrice = st.exitValue / st.exitSize
+		st.trip.Profit = st.trip.Profit - st.trip.Fees
+		rs = append(rs, st.trip)
+		st.amount = 0
+
+		if rest >= dust {
+			// flip
+			open(st, t, math.Copysign(rest, executed), t.PriceAvg, fee*rest/size)
+		}
+	}
+
+	return rs
+}
@@ -0,0 +1,124 @@
+package backtest
+
+import (
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/models"
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrips(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time { return start.Add(time.Duration(h) * time.Hour) }
+
+	trades := []*models.Order{
+		{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 100, Updated: at(0), Fee: 1, FeeCurrency: "USDT"},
+		{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 200, Updated: at(1)},
+		// close long and open short of 1
+		{Symbol: "BTCUSDT", AmountOriginal: -3, PriceAvg: 250, Updated: at(3)},
+		{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 300, Updated: at(4)},
+		// open position not counted
+		{Symbol: "ETHUSDT", AmountOriginal: 1, PriceAvg: 10, Updated: at(4)},
+	}
+
+	trips := roundTrips(trades, "USDT")
+	if len(trips) != 2 {
+		t.Fatalf("expected 2 trips, got %+v", trips)
+	}
+
+	long := trips[0]
+	if long.Short || long.Amount != 2 || long.EntryPrice != 150 || long.ExitPrice != 250 || long.Profit != 199 {
+		t.Fatalf("wrong long trip %+v", long)
+	}
+	if long.Close.Sub(long.Open) != 3*time.Hour {
+		t.Fatalf("wrong long trip duration %v", long.Close.Sub(long.Open))
+	}
+
+	short := trips[1]
+	if !short.Short || short.Amount != 1 || short.EntryPrice != 250 || short.Profit != -50 {
+		t.Fatalf("wrong short trip %+v", short)
+	}
+}
+
+func TestReport(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	worth := []float64{100, 110, 99, 121, 121}
+
+	snapshots := make([]mock.BalanceSnapshot, 0, len(worth))
+	for i, w := range worth {
+		invested := 0.0
+		if i%2 == 0 {
+			invested = w
+		}
+		snapshots = append(snapshots, mock.BalanceSnapshot{
+			Time:     start.Add(time.Duration(i) * time.Hour),
+			NetWorth: w,
+			Invested: invested,
+		})
+	}
+
+	rs := &Result{
+		Strategy: "test",
+		Trades: []*models.Order{
+			{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 100, Updated: start},
+			{Symbol: "BTCUSDT", AmountOriginal: -1, PriceAvg: 130, Updated: start.Add(time.Hour)},
+			{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 130, Updated: start.Add(2 * time.Hour)},
+			{Symbol: "BTCUSDT", AmountOriginal: -1, PriceAvg: 120, Updated: start.Add(5 * time.Hour)},
+		},
+		StartBalance: &models.BalanceUSD{NetWorth: 100},
+		EndBalance:   &models.BalanceUSD{NetWorth: 121},
+	}
+
+	r := NewReport(rs, "USDT", snapshots, time.Hour)
+
+	if math.Abs(r.Return-21) > 1e-9 {
+		t.Errorf("wrong return %v", r.Return)
+	}
+	if r.MaxDrawdown != 10 || r.MaxDrawdownValue != 11 {
+		t.Errorf("wrong drawdown %v (%v)", r.MaxDrawdown, r.MaxDrawdownValue)
+	}
+	if r.Exposure != 60 {
+		t.Errorf("wrong exposure %v", r.Exposure)
+	}
+	if r.Sharpe <= 0 || r.Sortino <= r.Sharpe {
+		t.Errorf("wrong ratios sharpe %v, sortino %v", r.Sharpe, r.Sortino)
+	}
+	if r.Trades != 2 || r.WinRate != 50 || r.ProfitFactor != 3 {
+		t.Errorf("wrong trades stats %+v", r)
+	}
+	if r.AvgTradeDuration != 2*time.Hour {
+		t.Errorf("wrong avg duration %v", r.AvgTradeDuration)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := r.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &Report{}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+	if err := r.WriteEquityCSV(buf); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(worth)+1 {
+		t.Errorf("expected %d csv lines, got %d", len(worth)+1, lines)
+	}
+
+	buf.Reset()
+	if err := r.WriteHTML(buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<polyline") {
+		t.Error("html report without chart")
+	}
+
+	if err := r.Save("report.txt"); err == nil {
+		t.Error("expected unknown format error")
+	}
+}
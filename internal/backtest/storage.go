@@ -0,0 +1,50 @@
+package backtest
+
+import (
+	"DaruBot/pkg/errors"
+	"encoding/json"
+	"sync"
+)
+
+var (
+	ErrKeyNotFound = errors.New("KEY NOT FOUND")
+)
+
+// memoryStorage implements storage.CustomStorage for strategy during backtest,
+// so test runs does not touch bot database
+type memoryStorage struct {
+	mu   *sync.Mutex
+	data map[string][]byte
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{
+		mu:   &sync.Mutex{},
+		data: make(map[string][]byte),
+	}
+}
+
+func (m *memoryStorage) Save(key string, data interface{}) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = raw
+
+	return nil
+}
+
+func (m *memoryStorage) Load(key string, to interface{}) error {
+	m.mu.Lock()
+	raw, ok := m.data[key]
+	m.mu.Unlock()
+
+	if !ok {
+		return ErrKeyNotFound
+	}
+
+	return json.Unmarshal(raw, to)
+}
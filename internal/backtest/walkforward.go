@@ -0,0 +1,249 @@
+package backtest
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"context"
+	"time"
+)
+
+const (
+	year = 365 * 24 * time.Hour
+)
+
+var (
+	ErrNoWindows = errors.New("NO WALK-FORWARD WINDOWS IN PERIOD")
+)
+
+// WalkForwardOptions split period From-To to rolling windows, parameters optimized on in-sample window
+// and evaluated on following out-of-sample window, next window shifted by OutSample
+type WalkForwardOptions struct {
+	OptimizeOptions
+
+	InSample  time.Duration
+	OutSample time.Duration
+	Anchored  bool // in-sample windows start at From and grow
+}
+
+type WalkForwardWindow struct {
+	InFrom  time.Time
+	InTo    time.Time
+	OutFrom time.Time
+	OutTo   time.Time
+
+	Params   map[string]interface{} // best parameters of in-sample window
+	InScore  float64
+	InReturn float64
+
+	OutReturn      float64
+	OutMaxDrawdown float64
+	OutSharpe      float64
+	OutTrades      int
+
+	Error string `json:",omitempty"`
+
+	result *Result
+}
+
+type WalkForwardResult struct {
+	Strategy string
+	Metric   string
+	From     time.Time
+	To       time.Time
+	Windows  []*WalkForwardWindow
+
+	// Report of out-of-sample windows stitched together, every window continues with balance of previous one
+	Report *Report
+	// Efficiency annualized out-of-sample return to annualized in-sample return, near 1 or above for robust strategy
+	Efficiency float64
+}
+
+// WalkForward run walk-forward validation, interrupted validation returns result of finished windows
+func WalkForward(ctx context.Context, opts WalkForwardOptions, cache *candles.Cache, lg logger.Logger) (*WalkForwardResult, error) {
+	windows := walkForwardWindows(opts.From, opts.To, opts.InSample, opts.OutSample, opts.Anchored)
+	if len(windows) == 0 {
+		return nil, ErrNoWindows
+	}
+
+	rs := &WalkForwardResult{
+		Strategy: opts.Strategy,
+		Metric:   opts.Metric,
+		From:     opts.From,
+		To:       opts.To,
+		Windows:  make([]*WalkForwardWindow, 0, len(windows)),
+	}
+	if rs.Metric == "" {
+		rs.Metric = defaultMetric
+	}
+
+	var err error
+
+	for _, w := range windows {
+		err = walkForwardWindow(ctx, opts, w, cache, lg)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rs.Windows = append(rs.Windows, w)
+	}
+
+	rs.stitch(opts.Currency, opts.SnapshotInterval)
+
+	return rs, err
+}
+
+// walkForwardWindow optimize parameters on in-sample and run out-of-sample backtest with best of them,
+// fails only if optimization options are wrong, errors of runs saved to window
+func walkForwardWindow(ctx context.Context, opts WalkForwardOptions, w *WalkForwardWindow, cache *candles.Cache, lg logger.Logger) error {
+	inOpts := opts.OptimizeOptions
+	inOpts.From, inOpts.To = w.InFrom, w.InTo
+
+	lb, err := Optimize(ctx, inOpts, cache, lg)
+	if err != nil {
+		return err
+	}
+
+	if len(lb.Entries) == 0 || lb.Entries[0].Error != "" {
+		w.Error = "no successful in-sample runs"
+		return nil
+	}
+
+	best := lb.Entries[0]
+	w.Params = best.Params
+	w.InScore = best.Score
+	w.InReturn = best.Return
+
+	outOpts := opts.Options
+	outOpts.From, outOpts.To = w.OutFrom, w.OutTo
+	outOpts.Params = best.Params
+
+	res, err := Run(ctx, outOpts, cache, lg)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		w.Error = err.Error()
+		return nil
+	}
+
+	w.result = res
+	if r := res.Report; r != nil {
+		w.OutReturn = r.Return
+		w.OutMaxDrawdown = r.MaxDrawdown
+		w.OutSharpe = r.Sharpe
+		w.OutTrades = r.Trades
+	}
+
+	return nil
+}
+
+func walkForwardWindows(from, to time.Time, in, out time.Duration, anchored bool) []*WalkForwardWindow {
+	rs := make([]*WalkForwardWindow, 0)
+	if in <= 0 || out <= 0 {
+		return rs
+	}
+
+	for start := from; ; start = start.Add(out) {
+		w := &WalkForwardWindow{
+			InFrom: start,
+			InTo:   start.Add(in),
+		}
+		if anchored {
+			w.InFrom = from
+		}
+		w.OutFrom = w.InTo
+		w.OutTo = w.OutFrom.Add(out)
+
+		if !w.OutFrom.Before(to) {
+			break
+		}
+		if w.OutTo.After(to) {
+			w.OutTo = to
+		}
+
+		rs = append(rs, w)
+	}
+
+	return rs
+}
+
+// stitch build report of out-of-sample windows and efficiency.
+// Balances of window scaled to continue previous window, trades are not scaled
+func (wf *WalkForwardResult) stitch(currency string, interval time.Duration) {
+	if interval == 0 {
+		interval = defaultSnapshotInterval
+	}
+
+	params := make([]map[string]interface{}, 0, len(wf.Windows))
+	results := make([]*Result, 0, len(wf.Windows))
+
+	var inAnnual, outAnnual float64
+
+	for _, w := range wf.Windows {
+		if w.result == nil {
+			continue
+		}
+		params = append(params, w.Params)
+		results = append(results, w.result)
+
+		inAnnual = inAnnual + w.InReturn*float64(year)/float64(w.InTo.Sub(w.InFrom))
+		outAnnual = outAnnual + w.OutReturn*float64(year)/float64(w.OutTo.Sub(w.OutFrom))
+	}
+
+	rs := stitchResults(results, currency)
+	rs.Strategy = wf.Strategy
+	rs.Params = params
+
+	wf.Report = NewReport(rs, currency, rs.Balances, interval)
+
+	if inAnnual != 0 {
+		wf.Efficiency = outAnnual / inAnnual
+	}
+}
+
+func stitchResults(results []*Result, currency string) *Result {
+	rs := &Result{
+		Trades:   make([]*models.Order, 0),
+		Balances: make([]mock.BalanceSnapshot, 0),
+	}
+
+	for _, r := range results {
+		if r.StartBalance == nil || r.EndBalance == nil || r.StartBalance.NetWorth == 0 {
+			continue
+		}
+
+		scale := 1.0
+		if rs.EndBalance == nil {
+			start := *r.StartBalance
+			rs.StartBalance = &start
+		} else {
+			scale = rs.EndBalance.NetWorth / r.StartBalance.NetWorth
+		}
+
+		for _, s := range r.Balances {
+			if n := len(rs.Balances); n > 0 && !s.Time.After(rs.Balances[n-1].Time) {
+				continue
+			}
+			s.Total = s.Total * scale
+			s.NetWorth = s.NetWorth * scale
+			s.Invested = s.Invested * scale
+			rs.Balances = append(rs.Balances, s)
+		}
+
+		rs.EndBalance = &models.BalanceUSD{
+			Total:    r.EndBalance.Total * scale,
+			NetWorth: r.EndBalance.NetWorth * scale,
+		}
+		rs.Trades = append(rs.Trades, r.Trades...)
+	}
+
+	rs.Stats = calcStats(rs.Trades, currency)
+
+	return rs
+}
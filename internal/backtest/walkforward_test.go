@@ -0,0 +1,127 @@
+package backtest
+
+import (
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWalkForwardWindows(t *testing.T) {
+	day := 24 * time.Hour
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(20 * day)
+
+	windows := walkForwardWindows(from, to, 10*day, 4*day, false)
+	if len(windows) != 3 {
+		t.Fatalf("expected 3 windows, got %d", len(windows))
+	}
+
+	for i, w := range windows {
+		inFrom := from.Add(time.Duration(i) * 4 * day)
+		if !w.InFrom.Equal(inFrom) || !w.InTo.Equal(inFrom.Add(10*day)) || !w.OutFrom.Equal(w.InTo) {
+			t.Fatalf("wrong window %d: %+v", i, w)
+		}
+	}
+
+	// last out-of-sample window cut by end of period
+	if !windows[2].OutTo.Equal(to) {
+		t.Fatalf("expected last window end %s, got %s", to, windows[2].OutTo)
+	}
+
+	anchored := walkForwardWindows(from, to, 10*day, 4*day, true)
+	for i, w := range anchored {
+		if !w.InFrom.Equal(from) || !w.InTo.Equal(windows[i].InTo) {
+			t.Fatalf("wrong anchored window %d: %+v", i, w)
+		}
+	}
+
+	if len(walkForwardWindows(from, to, 20*day, 4*day, false)) != 0 {
+		t.Fatal("expected no windows if in-sample covers period")
+	}
+}
+
+func TestStitchResults(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h int) time.Time {
+		return start.Add(time.Duration(h) * time.Hour)
+	}
+
+	results := []*Result{
+		{
+			StartBalance: &models.BalanceUSD{Total: 1000, NetWorth: 1000},
+			EndBalance:   &models.BalanceUSD{Total: 1100, NetWorth: 1100},
+			Balances: []mock.BalanceSnapshot{
+				{Time: at(0), Total: 1000, NetWorth: 1000},
+				{Time: at(1), Total: 1100, NetWorth: 1100},
+			},
+			Trades: []*models.Order{
+				{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 100},
+				{Symbol: "BTCUSDT", AmountOriginal: -1, PriceAvg: 200},
+			},
+		},
+		{
+			StartBalance: &models.BalanceUSD{Total: 1000, NetWorth: 1000},
+			EndBalance:   &models.BalanceUSD{Total: 900, NetWorth: 900},
+			Balances: []mock.BalanceSnapshot{
+				{Time: at(1), Total: 1000, NetWorth: 1000},
+				{Time: at(2), Total: 900, NetWorth: 900, Invested: 100},
+			},
+			Trades: []*models.Order{
+				{Symbol: "BTCUSDT", AmountOriginal: 1, PriceAvg: 200},
+				{Symbol: "BTCUSDT", AmountOriginal: -1, PriceAvg: 100},
+			},
+		},
+	}
+
+	rs := stitchResults(results, "USDT")
+
+	if rs.StartBalance.NetWorth != 1000 || math.Abs(rs.EndBalance.NetWorth-990) > 1e-9 {
+		t.Fatalf("wrong balances %+v %+v", rs.StartBalance, rs.EndBalance)
+	}
+
+	want := []float64{1000, 1100, 990}
+	if len(rs.Balances) != len(want) {
+		t.Fatalf("expected %d snapshots, got %d", len(want), len(rs.Balances))
+	}
+	for i, s := range rs.Balances {
+		if math.Abs(s.NetWorth-want[i]) > 1e-9 {
+			t.Fatalf("snapshot %d: expected %v, got %v", i, want[i], s.NetWorth)
+		}
+	}
+	if math.Abs(rs.Balances[2].Invested-110) > 1e-9 {
+		t.Fatalf("invested not scaled %v", rs.Balances[2].Invested)
+	}
+
+	if len(rs.Trades) != 4 || rs.Stats.TotalProfit != 100 || rs.Stats.TotalLoss != 100 {
+		t.Fatalf("wrong trades %d %+v", len(rs.Trades), rs.Stats)
+	}
+}
+
+func TestWalkForwardEfficiency(t *testing.T) {
+	day := 24 * time.Hour
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	res := &Result{
+		StartBalance: &models.BalanceUSD{NetWorth: 1000},
+		EndBalance:   &models.BalanceUSD{NetWorth: 1010},
+	}
+
+	wf := &WalkForwardResult{
+		Windows: []*WalkForwardWindow{
+			{InFrom: from, InTo: from.Add(10 * day), OutFrom: from.Add(10 * day), OutTo: from.Add(15 * day), InReturn: 4, OutReturn: 1, result: res},
+			{InFrom: from.Add(5 * day), InTo: from.Add(15 * day), OutFrom: from.Add(15 * day), OutTo: from.Add(20 * day), Error: "failed"},
+		},
+	}
+
+	wf.stitch("USDT", time.Hour)
+
+	// in-sample 4% per 10 days, out-of-sample 1% per 5 days
+	if math.Abs(wf.Efficiency-0.5) > 1e-9 {
+		t.Fatalf("expected efficiency 0.5, got %v", wf.Efficiency)
+	}
+	if wf.Report == nil || math.Abs(wf.Report.Return-1) > 1e-9 {
+		t.Fatalf("wrong report %+v", wf.Report)
+	}
+}
@@ -0,0 +1,117 @@
+package candles
+
+import (
+	"DaruBot/internal/models"
+	"math"
+)
+
+// Aggregate merge candles to bigger resolution, candles grouped by models.CandleResolution.PeriodStart
+func Aggregate(src *models.Candles, resolution models.CandleResolution) *models.Candles {
+	rs := &models.Candles{
+		Symbol:     src.Symbol,
+		Resolution: resolution,
+		Candles:    make([]*models.Candle, 0),
+	}
+
+	var cur *models.Candle
+
+	for _, c := range src.Candles {
+		date := resolution.PeriodStart(c.Date)
+
+		if cur == nil || !cur.Date.Equal(date) {
+			cur = mergeCandle(nil, c)
+			cur.Symbol = src.Symbol
+			cur.Resolution = resolution
+			cur.Date = date
+			rs.Candles = append(rs.Candles, cur)
+			continue
+		}
+
+		mergeTo(cur, c)
+	}
+
+	return rs
+}
+
+// mergeCandle returns copy of candle agg merged with c, copy of c if agg is nil
+func mergeCandle(agg *models.Candle, c *models.Candle) *models.Candle {
+	if agg == nil {
+		cc := *c
+		return &cc
+	}
+
+	rs := *agg
+	mergeTo(&rs, c)
+
+	return &rs
+}
+
+func mergeTo(agg *models.Candle, c *models.Candle) {
+	agg.Close = c.Close
+	agg.High = math.Max(agg.High, c.High)
+	agg.Low = math.Min(agg.Low, c.Low)
+	agg.Volume = agg.Volume + c.Volume
+}
+
+type aggregatorState struct {
+	last   *models.Candle
+	closed map[models.CandleResolution]*models.Candle // merged closed candles of current period
+}
+
+// Aggregator build candles of bigger resolutions from stream of candles (e.g. minute candles of Bitfinex.Aggregate),
+// so one subscription feeds all resolutions of strategy
+type Aggregator struct {
+	resolutions []models.CandleResolution
+	symbols     map[string]*aggregatorState
+}
+
+func NewAggregator(resolutions ...models.CandleResolution) *Aggregator {
+	return &Aggregator{
+		resolutions: resolutions,
+		symbols:     make(map[string]*aggregatorState),
+	}
+}
+
+// Update add candle or update of the last candle of symbol, returns forming candles of resolutions
+// which can be aggregated from resolution of c. Candle older than the last one is ignored
+func (a *Aggregator) Update(c *models.Candle) []*models.Candle {
+	st, ok := a.symbols[c.Symbol]
+	if !ok {
+		st = &aggregatorState{closed: make(map[models.CandleResolution]*models.Candle)}
+		a.symbols[c.Symbol] = st
+	}
+
+	if st.last != nil {
+		if c.Date.Before(st.last.Date) {
+			return nil
+		}
+
+		// the last candle closed
+		if c.Date.After(st.last.Date) {
+			for _, res := range a.resolutions {
+				if res.PeriodStart(st.last.Date).Equal(res.PeriodStart(c.Date)) {
+					st.closed[res] = mergeCandle(st.closed[res], st.last)
+				} else {
+					delete(st.closed, res)
+				}
+			}
+		}
+	}
+
+	cc := *c
+	st.last = &cc
+
+	rs := make([]*models.Candle, 0, len(a.resolutions))
+	for _, res := range a.resolutions {
+		if !c.Resolution.CanAggregate(res) {
+			continue
+		}
+
+		cndl := mergeCandle(st.closed[res], c)
+		cndl.Resolution = res
+		cndl.Date = res.PeriodStart(c.Date)
+		rs = append(rs, cndl)
+	}
+
+	return rs
+}
@@ -0,0 +1,115 @@
+package candles
+
+import (
+	"DaruBot/internal/models"
+	"testing"
+	"time"
+)
+
+func minuteCandles(start time.Time, n int) *models.Candles {
+	src := &models.Candles{Symbol: "BTCUSD", Resolution: models.OneMinute}
+	for i := 0; i < n; i++ {
+		src.Candles = append(src.Candles, &models.Candle{
+			Symbol:     "BTCUSD",
+			Resolution: models.OneMinute,
+			Date:       start.Add(time.Duration(i) * time.Minute),
+			Open:       float64(i),
+			Close:      float64(i + 1),
+			High:       float64(i + 2),
+			Low:        float64(i),
+			Volume:     1,
+		})
+	}
+	return src
+}
+
+func TestAggregateDay(t *testing.T) {
+	start := time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC)
+
+	src := &models.Candles{Symbol: "BTCUSD", Resolution: models.OneHour}
+	for i := 0; i < 48; i++ {
+		src.Candles = append(src.Candles, &models.Candle{
+			Date:   start.Add(time.Duration(i) * time.Hour),
+			Open:   float64(i),
+			Close:  float64(i + 1),
+			High:   float64(i + 2),
+			Low:    float64(i),
+			Volume: 1,
+		})
+	}
+
+	rs := Aggregate(src, models.OneDay)
+	if len(rs.Candles) != 2 {
+		t.Fatalf("expected 2 candles, got %d", len(rs.Candles))
+	}
+
+	second := rs.Candles[1]
+	if !second.Date.Equal(start.AddDate(0, 0, 1)) || second.Open != 24 || second.Close != 48 || second.High != 49 || second.Low != 24 || second.Volume != 24 {
+		t.Fatalf("wrong candle %+v", second)
+	}
+}
+
+func TestAggregateCalendar(t *testing.T) {
+	// Friday 2021-01-29 - Tuesday 2021-02-02
+	start := time.Date(2021, 1, 29, 0, 0, 0, 0, time.UTC)
+	src := minuteCandles(start, 5*24*60)
+
+	weeks := Aggregate(src, models.OneWeek)
+	if len(weeks.Candles) != 2 {
+		t.Fatalf("expected 2 weeks, got %d", len(weeks.Candles))
+	}
+	if !weeks.Candles[0].Date.Equal(time.Date(2021, 1, 25, 0, 0, 0, 0, time.UTC)) ||
+		!weeks.Candles[1].Date.Equal(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("wrong week dates %v, %v", weeks.Candles[0].Date, weeks.Candles[1].Date)
+	}
+	if weeks.Candles[0].Volume != 3*24*60 || weeks.Candles[1].Volume != 2*24*60 {
+		t.Fatalf("wrong week volumes %v, %v", weeks.Candles[0].Volume, weeks.Candles[1].Volume)
+	}
+
+	months := Aggregate(src, models.OneMonth)
+	if len(months.Candles) != 2 {
+		t.Fatalf("expected 2 months, got %d", len(months.Candles))
+	}
+	feb := months.Candles[1]
+	if !feb.Date.Equal(time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)) || feb.Volume != 2*24*60 || feb.Open != 3*24*60 {
+		t.Fatalf("wrong month candle %+v", feb)
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	start := time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC)
+	src := minuteCandles(start, 20)
+
+	a := NewAggregator(models.FiveMinutes, models.OneHour)
+
+	var last []*models.Candle
+	for i, c := range src.Candles {
+		// unfinished candle updated before final one
+		upd := *c
+		upd.Close, upd.High, upd.Volume = c.Open, c.High+100, 0.5
+		a.Update(&upd)
+
+		last = a.Update(c)
+		if len(last) != 2 {
+			t.Fatalf("expected 2 candles, got %d", len(last))
+		}
+
+		five := last[0]
+		if five.Resolution != models.FiveMinutes || !five.Date.Equal(start.Add(time.Duration(i/5*5)*time.Minute)) {
+			t.Fatalf("wrong 5m candle %+v", five)
+		}
+		if five.Open != float64(i/5*5) || five.Close != float64(i+1) || five.Volume != float64(i%5+1) {
+			t.Fatalf("wrong 5m candle %+v", five)
+		}
+	}
+
+	hour := last[1]
+	if !hour.Date.Equal(start) || hour.Open != 0 || hour.Close != 20 || hour.High != 21 || hour.Low != 0 || hour.Volume != 20 {
+		t.Fatalf("wrong 1h candle %+v", hour)
+	}
+
+	// older candle ignored
+	if rs := a.Update(src.Candles[3]); rs != nil {
+		t.Fatalf("older candle not ignored")
+	}
+}
@@ -77,17 +77,13 @@ func TestPeriods(t *testing.T) {
 	}
 	collection := &marketCandles{
 		Periods: []*period{{
-			To:      candles.Candles[len(candles.Candles)-1].Date,
-			From:    candles.Candles[0].Date,
-			Candles: candles,
+			To:   candles.Candles[len(candles.Candles)-1].Date,
+			From: candles.Candles[0].Date,
 		}},
 	}
 
 	for _, p := range collection.Periods {
 		t.Logf("%s -> %s\n", p.From.Format(time.Stamp), p.To.Format(time.Stamp))
-		for _, cd := range p.Candles.Candles {
-			t.Log("\t", cd.Date.Format(time.Stamp), cd.Open, cd.Close)
-		}
 	}
 
 	collection.add(collection.Periods[0])
@@ -104,16 +100,12 @@ func TestPeriods(t *testing.T) {
 		t.Fatal(err)
 	}
 	collection.add(&period{
-		To:      candles2.Candles[len(candles2.Candles)-1].Date,
-		From:    candles2.Candles[0].Date,
-		Candles: candles2,
+		To:   candles2.Candles[len(candles2.Candles)-1].Date,
+		From: candles2.Candles[0].Date,
 	})
 
 	for _, p := range collection.Periods {
 		t.Logf("%s -> %s\n", p.From.Format(time.Stamp), p.To.Format(time.Stamp))
-		for _, cd := range p.Candles.Candles {
-			t.Log("\t", cd.Date.Format(time.Stamp), cd.Open, cd.Close)
-		}
 	}
 
 	if len(collection.Periods) != 2 {
@@ -128,16 +120,12 @@ func TestPeriods(t *testing.T) {
 		t.Fatal(err)
 	}
 	collection.add(&period{
-		To:      candles3.Candles[len(candles3.Candles)-1].Date,
-		From:    candles3.Candles[0].Date,
-		Candles: candles3,
+		To:   candles3.Candles[len(candles3.Candles)-1].Date,
+		From: candles3.Candles[0].Date,
 	})
 
 	for _, p := range collection.Periods {
 		t.Logf("%s -> %s\n", p.From.Format(time.Stamp), p.To.Format(time.Stamp))
-		for _, cd := range p.Candles.Candles {
-			t.Log("\t", cd.Date.Format(time.Stamp), cd.Open, cd.Close)
-		}
 	}
 
 	if len(collection.Periods) != 1 {
@@ -149,16 +137,12 @@ func TestPeriods(t *testing.T) {
 		t.Fatal(err)
 	}
 	collection.add(&period{
-		To:      candles4.Candles[len(candles4.Candles)-1].Date,
-		From:    candles4.Candles[0].Date,
-		Candles: candles4,
+		To:   candles4.Candles[len(candles4.Candles)-1].Date,
+		From: candles4.Candles[0].Date,
 	})
 
 	for _, p := range collection.Periods {
 		t.Logf("%s -> %s\n", p.From.Format(time.Stamp), p.To.Format(time.Stamp))
-		for _, cd := range p.Candles.Candles {
-			t.Log("\t", cd.Date.Format(time.Stamp), cd.Open, cd.Close)
-		}
 	}
 
 	if len(collection.Periods) != 1 {
@@ -215,7 +199,7 @@ func TestSaveLoad(t *testing.T) {
 		t.Fatalf("wrong candles len, wanted: %v, got: %v", res, candles.Candles[0].Resolution)
 	}
 
-	err = c.SaveCache()
+	err = c.Close()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -225,26 +209,29 @@ func TestSaveLoad(t *testing.T) {
 
 	key := mk2.makeKey(testPair, res)
 
-	loaded, found := mk2.load(key)
-	if !found {
+	collection, err := c2.load(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.Periods) == 0 {
 		t.Fatal("cache not found")
 	}
-	collection := loaded.(*marketCandles)
 
 	for _, p := range collection.Periods {
 		t.Logf("%s -> %s\n", p.From.Format(time.Stamp), p.To.Format(time.Stamp))
-		for _, cd := range p.Candles.Candles {
-			t.Log("\t", cd.Date.Format(time.Stamp), cd.Open, cd.Close)
-		}
 	}
 
 	t.Logf("\nGet part from: %s to: %s\n", start, end)
 
-	candles, exist := collection.get(start, end)
-	if !exist {
+	if !collection.covers(start, res.PeriodStart(end)) {
 		t.Fatal("periods not exist")
 	}
 
+	candles, err = c2.store.read(key, testPair, res, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	for _, cd := range candles.Candles {
 		t.Log(cd.Date.Format(time.Stamp), cd.Open, cd.Close)
 	}
@@ -303,18 +290,14 @@ func TestGetWithLastCandle(t *testing.T) {
 
 	key := mk.makeKey(testPair, res)
 
-	loaded, found := mk.load(key)
-	if !found {
-		t.Fatal("cache not found")
+	collection, err := c.load(key)
+	if err != nil {
+		t.Fatal(err)
 	}
-	collection := loaded.(*marketCandles)
 
 	t.Log("\nCache:")
 	for _, p := range collection.Periods {
 		t.Logf("%s -> %s\n", p.From.Format(time.Stamp), p.To.Format(time.Stamp))
-		for _, cd := range p.Candles.Candles {
-			t.Log("\t", cd.Date.Format(time.Stamp), cd.Open, cd.Close)
-		}
 	}
 
 }
@@ -10,33 +10,43 @@ import (
 	"DaruBot/pkg/errors"
 	"DaruBot/pkg/logger"
 	"DaruBot/pkg/tools/numbers"
-	"encoding/json"
 	"fmt"
 	"github.com/alibaba/pouch/pkg/kmutex"
-	"github.com/patrickmn/go-cache"
-	"io/ioutil"
-	"os"
+	"sort"
+	"sync"
 	"time"
 )
 
 var (
-	version = "v1"
-
 	ErrWrongSort          = errors.New("Candles not sorted old > new")
 	ErrWrongCandle        = errors.New("Candles are not consistent")
 	ErrDownloadLastCandle = errors.New("Last candle not downloaded")
 )
 
+// period range of stored candles, dates of the first and the last candle. Gaps are missing
+// candles inside period which were not repaired
 type period struct {
-	From    time.Time
-	To      time.Time
-	Candles *models.Candles
+	From time.Time
+	To   time.Time
+	Gaps []Gap     `json:",omitempty"`
+	Used time.Time // last access, least recently used periods are evicted first
 }
 
 type Cache struct {
-	cache    *cache.Cache
+	store    *store
 	filePath string
 	lg       logger.Logger
+
+	mu          *sync.Mutex
+	locks       map[string]*kmutex.KMutex // by market, so caches of one market can be used concurrently
+	collections map[string]*marketCandles // stored periods by key, loaded on first use
+	gapOptions  GapOptions
+
+	retention map[models.CandleResolution]time.Duration
+	maxSize   int64
+	pruning   bool
+	lastPrune time.Time
+	wg        *sync.WaitGroup
 }
 
 type marketCandles struct {
@@ -45,133 +55,129 @@ type marketCandles struct {
 
 type MarketCandlesCache struct {
 	lock       *kmutex.KMutex
-	cache      *cache.Cache
+	cache      *Cache
 	market     string
 	loaderFunc loadFunc
 	lg         logger.Logger
-}
-
-type cacheCollection struct {
-	Version string
-	Items   map[string]item
-}
-
-type item struct {
-	Object     *marketCandles
-	Expiration int64
+	aggregate  bool
 }
 
 type loadFunc func(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error)
 
+// NewCandleCache open database of candles, JSON cache of previous versions is imported
 func NewCandleCache(filePath string, lg logger.Logger) (*Cache, error) {
 	if filePath == "" {
 		return nil, fmt.Errorf("filePath empty")
 	}
-	f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0666)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
 
-	raw, err := ioutil.ReadAll(f)
+	st, err := openStore(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	data := cacheCollection{
-		Version: version,
-		Items:   nil,
-	}
-
-	data.Items = make(map[string]item)
+	log := lg.WithPrefix("module", "candles cache")
 
-	if len(raw) != 0 {
-		err = json.Unmarshal(raw, &data)
-		if err != nil {
-			return nil, err
-		}
+	rs := &Cache{
+		store:       st,
+		filePath:    filePath,
+		lg:          log,
+		mu:          &sync.Mutex{},
+		locks:       make(map[string]*kmutex.KMutex),
+		collections: make(map[string]*marketCandles),
+		retention:   make(map[models.CandleResolution]time.Duration),
+		wg:          &sync.WaitGroup{},
 	}
 
-	cItems := make(map[string]cache.Item, 0)
+	log.Debug("cache opened", filePath)
+
+	return rs, nil
+}
 
-	if data.Version == version {
-		cItems = make(map[string]cache.Item, len(data.Items))
+// Close database, candles are saved when downloaded. Cache is pruned before if limits are set
+func (c *Cache) Close() error {
+	c.wg.Wait()
 
-		for s, item := range data.Items {
-			cItems[s] = cache.Item{
-				Object:     item.Object,
-				Expiration: item.Expiration,
-			}
+	if c.limited() {
+		if _, err := c.Prune(); err != nil {
+			c.lg.Error("prune", err)
 		}
 	}
 
-	log := lg.WithPrefix("module", "candles cache")
-	c := cache.NewFrom(cache.NoExpiration, 10*time.Minute, cItems)
-
-	rs := &Cache{
-		cache:    c,
-		filePath: filePath,
-		lg:       log,
+	// save last access of periods
+	c.mu.Lock()
+	for key, m := range c.collections {
+		if err := c.store.savePeriods(key, m.Periods); err != nil {
+			c.lg.Error("save periods", err)
+		}
 	}
+	c.mu.Unlock()
 
-	log.Debug("cache loaded", filePath)
-
-	return rs, nil
-}
-
-func (c *Cache) SaveCache() error {
-	f, err := os.OpenFile(c.filePath, os.O_RDWR, 0666)
+	err := c.store.close()
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	items := c.cache.Items()
+	c.lg.Debug("cache closed")
 
-	data := cacheCollection{
-		Version: version,
-		Items:   nil,
-	}
+	return nil
+}
 
-	data.Items = make(map[string]item, len(items))
+func (c *Cache) load(key string) (*marketCandles, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	for s, it := range items {
-		data.Items[s] = item{
-			Object:     it.Object.(*marketCandles),
-			Expiration: it.Expiration,
-		}
+	if m, ok := c.collections[key]; ok {
+		return m, nil
 	}
 
-	raw, err := json.Marshal(data)
+	periods, err := c.store.periods(key)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = f.Write(raw)
-	if err != nil {
-		return err
-	}
+	m := &marketCandles{Periods: periods}
+	c.collections[key] = m
+
+	return m, nil
+}
 
-	c.lg.Debug("cache saved")
+func (c *Cache) marketLock(market string) *kmutex.KMutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return nil
+	lock, ok := c.locks[market]
+	if !ok {
+		lock = kmutex.New()
+		c.locks[market] = lock
+	}
+	return lock
 }
 
 func (c *Cache) GetMarket(name string, loadFunc loadFunc) *MarketCandlesCache {
 	c.lg.Debug("cache get market", name)
+
 	return &MarketCandlesCache{
-		cache:      c.cache,
+		cache:      c,
 		market:     name,
-		lock:       kmutex.New(),
+		lock:       c.marketLock(name),
 		loaderFunc: loadFunc,
 		lg:         c.lg.WithPrefix("market", name),
 	}
 }
 
+// SetAggregate build candles of bigger resolutions from minute candles instead of loading them,
+// only minute candles are loaded and cached
+func (c *MarketCandlesCache) SetAggregate(enable bool) {
+	c.aggregate = enable
+}
+
 func (c *MarketCandlesCache) Get(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	if c.aggregate && resolution != models.OneMinute {
+		return c.getAggregated(from, to, symbol, resolution)
+	}
+
 	c.lg.Tracef("get candles [%s-%s] %s - %s", c.market, symbol, from.Format(time.RFC822Z), to.Format(time.RFC822Z))
 	var rs *models.Candles
-	var hasUpdate bool
 	key := c.makeKey(symbol, resolution)
 	c.lock.Lock(key)
 	defer c.lock.Unlock(key)
@@ -200,51 +206,52 @@ func (c *MarketCandlesCache) Get(from, to time.Time, symbol string, resolution m
 
 		lastCandle = candles.Candles[len(candles.Candles)-1]
 
-		end = end.Add(-resolution.ToDuration())
+		end = resolution.PeriodStart(end).Add(-time.Second)
 	}
 
-	// Get resolutions from stored for symbol
-	loaded, found := c.load(key)
-	if !found {
-		c.lg.Trace("cached candles for this market not found, make new...")
+	// Get stored periods for symbol
+	collection, err := c.cache.load(key)
+	if err != nil {
+		return nil, err
+	}
 
-		loaded = &marketCandles{
-			Periods: []*period{},
+	var fetched *models.Candles
+	var stored *period
+
+	// Get range of candles, stored periods end with date of the last candle
+	if p := collection.covering(start, resolution.PeriodStart(end)); p != nil {
+		c.lg.Trace("cached candles for this periods found")
+		p.Used = time.Now()
+		rs, err = c.cache.store.read(key, symbol, resolution, start, end)
+		if err != nil {
+			return nil, err
 		}
 	} else {
-		c.lg.Trace("cached candles for this market found")
-	}
-	collection := loaded.(*marketCandles)
-
-	// Get range of candles
-	candles, exist := collection.get(start, end)
-	if !exist {
 		// Get extra old candle to cache
-		startEx := start.Add(-resolution.ToDuration())
+		startEx := resolution.PeriodStart(start.Add(-time.Second))
 
 		c.lg.Tracef("cached candles for %s - %s periods not found, download...", startEx.Format(time.RFC822Z), end.Format(time.RFC822Z))
-		fetched, err := c.loaderFunc(startEx, end, symbol, resolution)
+		fetched, err = c.loaderFunc(startEx, end, symbol, resolution)
 		if err != nil {
 			return nil, err
 		}
 
-		candles = fetched
-		pd := &period{
-			To:      candles.Candles[len(candles.Candles)-1].Date,
-			From:    candles.Candles[0].Date,
-			Candles: candles,
-		}
-
-		collection.add(pd)
-
-		rs = pd.part(start, end)
+		if len(fetched.Candles) > 0 {
+			stored = &period{From: startEx, To: resolution.PeriodStart(end), Used: time.Now()}
+			fetched, stored.Gaps = c.repair(fetched, stored.From, stored.To, symbol, resolution)
 
-		if len(pd.Candles.Candles) > 0 {
-			hasUpdate = true
+			// missing last candles could be not available yet, they are not stored as gap.
+			// Older ones are out of source range and stored, so they are not downloaded again
+			if n := len(stored.Gaps); n > 0 && stored.Gaps[n-1].To.Equal(stored.To) && isPending(stored.Gaps[n-1], resolution) {
+				stored.To = resolution.PeriodStart(stored.Gaps[n-1].From.Add(-time.Second))
+				stored.Gaps = stored.Gaps[:n-1]
+			}
+			if stored.To.Before(stored.From) {
+				stored = nil
+			}
 		}
-	} else {
-		rs = candles
-		c.lg.Trace("cached candles for this periods found")
+
+		rs = part(fetched, start, end)
 	}
 
 	if lastCandle != nil {
@@ -256,34 +263,51 @@ func (c *MarketCandlesCache) Get(from, to time.Time, symbol string, resolution m
 		return nil, err
 	}
 
-	if hasUpdate {
+	if stored != nil {
 		c.lg.Trace("update cache")
-		c.set(key, collection)
+
+		updated := &marketCandles{Periods: append([]*period{}, collection.Periods...)}
+		updated.add(stored)
+
+		info := keyInfo{Market: c.market, Symbol: symbol, Resolution: resolution}
+		if err := c.cache.store.write(key, info, fetched, updated.Periods); err != nil {
+			return nil, err
+		}
+		collection.Periods = updated.Periods
+		c.cache.schedulePrune()
 	}
 
 	return rs, nil
 }
 
-func (c *MarketCandlesCache) makeKey(symbol string, resolution models.CandleResolution) string {
-	return fmt.Sprintf("%s_%s_%s", c.market, resolution, symbol)
+func (c *MarketCandlesCache) getAggregated(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	start := resolution.PeriodStart(from)
+	end := resolution.NextPeriod(to).Add(-time.Minute)
+
+	minutes, err := c.Get(start, end, symbol, models.OneMinute)
+	if err != nil {
+		return nil, err
+	}
+
+	return Aggregate(minutes, resolution), nil
 }
 
-func (c *MarketCandlesCache) load(key string) (interface{}, bool) {
-	return c.cache.Get(key)
+func (c *MarketCandlesCache) makeKey(symbol string, resolution models.CandleResolution) string {
+	return makeKey(c.market, symbol, resolution)
 }
 
-func (c *MarketCandlesCache) set(key string, m *marketCandles) {
-	c.cache.Set(key, m, cache.NoExpiration)
+func makeKey(market, symbol string, resolution models.CandleResolution) string {
+	return fmt.Sprintf("%s_%s_%s", market, resolution, symbol)
 }
 
-func (p *period) part(from, to time.Time) *models.Candles {
+func part(candles *models.Candles, from, to time.Time) *models.Candles {
 	rs := &models.Candles{
-		Symbol:     p.Candles.Symbol,
-		Resolution: p.Candles.Resolution,
+		Symbol:     candles.Symbol,
+		Resolution: candles.Resolution,
 		Candles:    make([]*models.Candle, 0),
 	}
 
-	for _, c := range p.Candles.Candles {
+	for _, c := range candles.Candles {
 		if (c.Date.After(from) || c.Date.Equal(from)) &&
 			(c.Date.Before(to) || c.Date.Equal(to)) {
 			rs.Candles = append(rs.Candles, c)
@@ -293,99 +317,67 @@ func (p *period) part(from, to time.Time) *models.Candles {
 	return rs
 }
 
-func (m *marketCandles) get(from, to time.Time) (*models.Candles, bool) {
+// covers returns true if from - to is inside one of stored periods
+func (m *marketCandles) covers(from, to time.Time) bool {
+	return m.covering(from, to) != nil
+}
+
+// covering returns stored period containing from - to, nil if not found
+func (m *marketCandles) covering(from, to time.Time) *period {
 	for _, c := range m.Periods {
 		if (c.From.Before(from) || c.From.Equal(from)) &&
 			(c.To.After(to) || c.To.Equal(to)) {
-			return c.part(from, to), true
+			return c
 		}
 	}
-	return nil, false
+	return nil
 }
 
+// add period, combined with periods it overlaps. Candles of added period are newer,
+// so gaps of other periods in its range are dropped
 func (m *marketCandles) add(pd *period) {
+	merged := &period{From: pd.From, To: pd.To, Gaps: pd.Gaps, Used: pd.Used}
+	periods := make([]*period, 0, len(m.Periods)+1)
 
-	var needRefresh bool
-
-	periods := m.Periods
-
-	for i := 0; i < len(periods); i++ {
-		needRefresh = canCombine(pd, periods[i])
-		if needRefresh {
-			break
+	for _, p := range m.Periods {
+		if canCombine(merged, p) {
+			merged = combine(merged, p)
+			continue
 		}
+		periods = append(periods, p)
 	}
 
-	periods = append([]*period{pd}, periods...)
-
-	for needRefresh {
-		needRefresh = false
-		old := periods
-		refreshed := make([]*period, 0, len(old))
-
-		for fi := 0; fi < len(old); fi++ {
-			tempPeriod := old[fi]
-			for i := 1; i < len(old); i++ {
-				if canCombine(tempPeriod, old[i]) {
-					tempPeriod = combine(tempPeriod, old[i])
-					old = append(old[:i], old[i+1:]...)
-					needRefresh = true
-				}
-			}
-			refreshed = append(refreshed, tempPeriod)
-		}
-
-		periods = refreshed
-	}
+	periods = append(periods, merged)
+	sort.Slice(periods, func(i, j int) bool {
+		return periods[i].From.Before(periods[j].From)
+	})
 
 	m.Periods = periods
 }
 
 func canCombine(period1, period2 *period) bool {
-	if (period1.To.After(period2.From) || period1.To.Equal(period2.From)) &&
-		(period1.To.Before(period2.To) || period1.To.Equal(period2.To)) {
-		return true
-	}
-	if (period1.To.After(period2.From) || period1.To.Equal(period2.From)) &&
-		(period1.From.Before(period2.To) || period1.From.Equal(period2.To)) {
-		return true
-	}
-	return false
+	return (period1.To.After(period2.From) || period1.To.Equal(period2.From)) &&
+		(period1.From.Before(period2.To) || period1.From.Equal(period2.To))
 }
 
+// combine periods, gaps of period2 in range of period1 are dropped
 func combine(period1, period2 *period) *period {
-	rs := &period{
-		To:   time.Time{},
-		From: time.Time{},
-		Candles: &models.Candles{
-			Symbol:     period1.Candles.Symbol,
-			Resolution: period1.Candles.Resolution,
-			Candles:    make([]*models.Candle, 0),
-		},
+	rs := &period{From: period1.From, To: period1.To}
+	if period2.From.Before(rs.From) {
+		rs.From = period2.From
 	}
-
-	first, second := period1, period2
-	if period1.From.After(period2.From) {
-		first, second = period2, period1
+	if period2.To.After(rs.To) {
+		rs.To = period2.To
 	}
-
-	var last time.Time
-	for _, c := range first.Candles.Candles {
-		rs.Candles.Candles = append(rs.Candles.Candles, c)
-		last = c.Date
+	rs.Used = period1.Used
+	if period2.Used.After(rs.Used) {
+		rs.Used = period2.Used
 	}
 
-	for _, c := range second.Candles.Candles {
-		if c.Date.After(last) {
-			rs.Candles.Candles = append(rs.Candles.Candles, c)
-		}
-	}
-
-	rs.From = first.From
-	rs.To = second.To
-	if second.To.Before(first.To) || second.To.Equal(first.To) {
-		rs.To = first.To
-	}
+	rs.Gaps = append(append([]Gap{}, period1.Gaps...), subtractRange(period2.Gaps, period1.From, period1.To)...)
+	sort.Slice(rs.Gaps, func(i, j int) bool {
+		return rs.Gaps[i].From.Before(rs.Gaps[j].From)
+	})
 
 	return rs
 }
@@ -405,11 +397,16 @@ func (c *MarketCandlesCache) VerifyCandles(candles *models.Candles) error {
 					candles.Candles[i].Symbol)
 				return ErrWrongCandle
 			}
-			if candles.Candles[i].Date.Sub(candles.Candles[i-1].Date) != candles.Resolution.ToDuration() {
+			next := candles.Resolution.NextPeriod(candles.Candles[i-1].Date)
+			if candles.Candles[i].Date.After(next) && candles.Resolution.IsPeriodStart(candles.Candles[i].Date) {
+				// gap, no trades or not repaired
+				c.lg.Tracef("candles gap %s - %s", next, candles.Candles[i].Date)
+				continue
+			}
+			if !candles.Candles[i].Date.Equal(next) {
 				c.lg.Tracef("candle check consistent failed \ncandle1: %s \ncandle2: %s \ngot: %v \nwant: %v\n",
 					candles.Candles[i-1].Date, candles.Candles[i].Date,
-					candles.Candles[i].Date.Sub(candles.Candles[i-1].Date),
-					candles.Resolution.ToDuration())
+					candles.Candles[i].Date, next)
 				return ErrWrongCandle
 			}
 		}
@@ -468,24 +465,11 @@ func normalizeDate(from, to time.Time, resolution models.CandleResolution) (time
 
 		hour = numbers.NumberRoundTo(from.Hour(), -12)
 		start = time.Date(from.Year(), from.Month(), from.Day(), hour, 0, 0, 0, time.UTC)
-	case models.OneDay:
-		end = time.Date(to.Year(), to.Month(), to.Day(), 23, 59, 59, 0, time.UTC)
-
-		start = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
-	case models.OneWeek:
-		day := numbers.NumberRoundTo(to.Day(), 7)
-		end = time.Date(to.Year(), to.Month(), day, 23, 59, 59, 0, time.UTC)
-
-		day = numbers.NumberRoundTo(from.Day(), -7)
-		start = time.Date(from.Year(), from.Month(), day, 0, 0, 0, 0, time.UTC)
-	case models.OneMonth:
-		month := to.Month()
-		if to.Day() != 1 {
-			month = month + 1
-		}
-		end = time.Date(to.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+	case models.OneDay, models.OneWeek, models.OneMonth:
+		// calendar periods
+		end = resolution.PeriodEnd(to)
 
-		start = time.Date(from.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+		start = resolution.PeriodStart(from)
 	default:
 		return from, to, fmt.Errorf("unknown resolution")
 	}
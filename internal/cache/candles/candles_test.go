@@ -0,0 +1,483 @@
+package candles
+
+import (
+	"DaruBot/internal/models"
+	"DaruBot/pkg/logger"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalizeDateCalendar(t *testing.T) {
+	from := time.Date(2020, time.November, 15, 10, 0, 0, 0, time.UTC)
+	to := time.Date(2020, time.December, 20, 10, 0, 0, 0, time.UTC)
+
+	start, end, err := normalizeDate(from, to, models.OneMonth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(time.Date(2020, time.November, 1, 0, 0, 0, 0, time.UTC)) ||
+		!end.Equal(time.Date(2020, time.December, 31, 23, 59, 59, 0, time.UTC)) {
+		t.Fatalf("wrong month bounds %s - %s", start, end)
+	}
+
+	start, end, err = normalizeDate(from, to, models.OneWeek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(time.Date(2020, time.November, 9, 0, 0, 0, 0, time.UTC)) ||
+		!end.Equal(time.Date(2020, time.December, 20, 23, 59, 59, 0, time.UTC)) {
+		t.Fatalf("wrong week bounds %s - %s", start, end)
+	}
+}
+
+func TestVerifyMonthCandles(t *testing.T) {
+	c := &MarketCandlesCache{lg: logger.New(ioutil.Discard, logger.ErrorLevel)}
+
+	q := &models.Candles{Symbol: "BTCUSD", Resolution: models.OneMonth}
+	for m := time.October; m <= time.December+3; m++ {
+		q.Candles = append(q.Candles, &models.Candle{Symbol: "BTCUSD", Date: time.Date(2020, m, 1, 0, 0, 0, 0, time.UTC)})
+	}
+	if err := c.VerifyCandles(q); err != nil {
+		t.Fatal(err)
+	}
+
+	// 30 days after December 1
+	q.Candles[3].Date = q.Candles[2].Date.Add(models.OneMonth.ToDuration())
+	if err := c.VerifyCandles(q); err == nil {
+		t.Fatal("wrong month candle not detected")
+	}
+}
+
+// testLoader generate hourly candles, price is hours from 2020-01-01.
+// Candles of gap are missing in first gapCalls downloads or always if gapCalls is 0,
+// candles out of since - until are missing if set
+type testLoader struct {
+	calls    int
+	gap      Gap
+	gapCalls int
+	since    time.Time
+	until    time.Time
+}
+
+func (l *testLoader) load(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	l.calls++
+	withGap := l.gapCalls == 0 || l.calls <= l.gapCalls
+
+	base := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	rs := &models.Candles{Symbol: symbol, Resolution: resolution}
+	for d := resolution.PeriodStart(from); !d.After(to); d = resolution.NextPeriod(d) {
+		if withGap && !d.Before(l.gap.From) && !d.After(l.gap.To) {
+			continue
+		}
+		if (!l.since.IsZero() && d.Before(l.since)) || (!l.until.IsZero() && d.After(l.until)) {
+			continue
+		}
+		price := d.Sub(base).Hours()
+		rs.Candles = append(rs.Candles, &models.Candle{
+			Symbol:     symbol,
+			Resolution: resolution,
+			Date:       d,
+			Open:       price,
+			Close:      price + 1,
+			High:       price + 2,
+			Low:        price - 1,
+			Volume:     10,
+		})
+	}
+
+	return rs, nil
+}
+
+func TestStorePersist(t *testing.T) {
+	lg := logger.New(ioutil.Discard, logger.ErrorLevel)
+	path := filepath.Join(t.TempDir(), "candles.cache")
+
+	c, err := NewCandleCache(path, lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := &testLoader{}
+	mk := c.GetMarket("test", loader.load)
+
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 3, 23, 0, 0, 0, time.UTC)
+
+	first, err := mk.Get(from, to, "BTCUSD", models.OneHour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Candles) != 72 || loader.calls != 1 {
+		t.Fatalf("expected 72 candles by 1 download, got %d by %d", len(first.Candles), loader.calls)
+	}
+
+	// overlapping period downloaded and combined
+	if _, err := mk.Get(to, to.AddDate(0, 0, 1), "BTCUSD", models.OneHour); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err = NewCandleCache(path, lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	loader = &testLoader{}
+	mk = c.GetMarket("test", loader.load)
+
+	rs, err := mk.Get(from.Add(5*time.Hour), to.AddDate(0, 0, 1), "BTCUSD", models.OneHour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loader.calls != 0 {
+		t.Fatal("stored candles downloaded again")
+	}
+	if len(rs.Candles) != 67+24 {
+		t.Fatalf("expected %d candles, got %d", 67+24, len(rs.Candles))
+	}
+
+	c1, c2 := first.Candles[5], rs.Candles[0]
+	if !c1.Date.Equal(c2.Date) || c1.Open != c2.Open || c1.Close != c2.Close || c1.High != c2.High ||
+		c1.Low != c2.Low || c1.Volume != c2.Volume || c2.Symbol != "BTCUSD" || c2.Resolution != models.OneHour {
+		t.Fatalf("stored candle %+v differs from %+v", c2, c1)
+	}
+
+	collection, err := c.load(mk.makeKey("BTCUSD", models.OneHour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.Periods) != 1 {
+		t.Fatalf("expected 1 combined period, got %d", len(collection.Periods))
+	}
+}
+
+func TestImportLegacy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "candles.cache")
+
+	loader := &testLoader{}
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC)
+	candles, _ := loader.load(from, to, "BTCUSD", models.OneHour)
+
+	legacy := map[string]interface{}{
+		"Version": "v1",
+		"Items": map[string]interface{}{
+			"test_1h_BTCUSD": map[string]interface{}{
+				"Object": map[string]interface{}{
+					"Periods": []interface{}{map[string]interface{}{"From": from, "To": to, "Candles": candles}},
+				},
+			},
+		},
+	}
+	raw, err := json.Marshal(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, raw, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewCandleCache(path, logger.New(ioutil.Discard, logger.ErrorLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	loader = &testLoader{}
+	rs, err := c.GetMarket("test", loader.load).Get(from, to, "BTCUSD", models.OneHour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loader.calls != 0 || len(rs.Candles) != 24 {
+		t.Fatalf("legacy cache not imported, downloads: %d, candles: %d", loader.calls, len(rs.Candles))
+	}
+}
+
+func TestFindGaps(t *testing.T) {
+	loader := &testLoader{gap: Gap{
+		From: time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 3, 1, 11, 0, 0, 0, time.UTC),
+	}}
+	candles, _ := loader.load(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC), "BTCUSD", models.OneHour)
+
+	gaps := FindGaps(candles)
+	if len(gaps) != 1 || !gaps[0].From.Equal(loader.gap.From) || !gaps[0].To.Equal(loader.gap.To) {
+		t.Fatalf("wrong gaps %+v", gaps)
+	}
+}
+
+func TestGaps(t *testing.T) {
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC)
+	gap := Gap{From: time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), To: time.Date(2020, 3, 1, 11, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name     string
+		opts     GapOptions
+		gapCalls int
+		candles  int
+		calls    int
+		gaps     int
+	}{
+		{"recorded", GapOptions{}, 0, 22, 1, 1},
+		{"refetch", GapOptions{Refetch: true}, 1, 24, 2, 0},
+		{"refetch failed", GapOptions{Refetch: true}, 0, 22, 2, 1},
+		{"fill", GapOptions{Fill: true}, 0, 24, 1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewCandleCache(filepath.Join(t.TempDir(), "candles.cache"), logger.New(ioutil.Discard, logger.ErrorLevel))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			c.SetGapOptions(tt.opts)
+
+			loader := &testLoader{gap: gap, gapCalls: tt.gapCalls}
+			mk := c.GetMarket("test", loader.load)
+
+			rs, err := mk.Get(from, to, "BTCUSD", models.OneHour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rs.Candles) != tt.candles || loader.calls != tt.calls {
+				t.Fatalf("expected %d candles by %d downloads, got %d by %d", tt.candles, tt.calls, len(rs.Candles), loader.calls)
+			}
+
+			gaps, err := mk.Gaps("BTCUSD", models.OneHour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(gaps) != tt.gaps {
+				t.Fatalf("expected %d gaps, got %+v", tt.gaps, gaps)
+			}
+			if tt.gaps > 0 && (!gaps[0].From.Equal(gap.From) || !gaps[0].To.Equal(gap.To)) {
+				t.Fatalf("wrong gap %+v", gaps[0])
+			}
+
+			if tt.opts.Fill {
+				flat := rs.Candles[10]
+				if flat.Volume != 0 || flat.Open != rs.Candles[9].Close || flat.High != flat.Low {
+					t.Fatalf("wrong flat candle %+v", flat)
+				}
+			}
+
+			// stored with gaps, not downloaded again
+			calls := loader.calls
+			if _, err := mk.Get(from, to, "BTCUSD", models.OneHour); err != nil {
+				t.Fatal(err)
+			}
+			if loader.calls != calls {
+				t.Fatal("candles with gaps downloaded again")
+			}
+		})
+	}
+}
+
+func TestGapsOutOfSource(t *testing.T) {
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC)
+
+	c, err := NewCandleCache(filepath.Join(t.TempDir(), "candles.cache"), logger.New(ioutil.Discard, logger.ErrorLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetGapOptions(GapOptions{Refetch: true, Fill: true})
+
+	loader := &testLoader{
+		since: time.Date(2020, 3, 1, 2, 0, 0, 0, time.UTC),
+		until: time.Date(2020, 3, 1, 20, 0, 0, 0, time.UTC),
+	}
+	mk := c.GetMarket("test", loader.load)
+
+	rs, err := mk.Get(from, to, "BTCUSD", models.OneHour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// edges are not refetched
+	if len(rs.Candles) != 19 || loader.calls != 1 {
+		t.Fatalf("expected 19 candles by 1 download, got %d by %d", len(rs.Candles), loader.calls)
+	}
+
+	gaps, err := mk.Gaps("BTCUSD", models.OneHour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gaps) != 2 || !gaps[0].To.Equal(time.Date(2020, 3, 1, 1, 0, 0, 0, time.UTC)) ||
+		!gaps[1].From.Equal(time.Date(2020, 3, 1, 21, 0, 0, 0, time.UTC)) || !gaps[1].To.Equal(to) {
+		t.Fatalf("wrong gaps %+v", gaps)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := mk.Get(from, to, "BTCUSD", models.OneHour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loader.calls != 1 {
+		t.Fatalf("ranges out of source downloaded again %d times", loader.calls-1)
+	}
+}
+
+func TestGapsCombine(t *testing.T) {
+	gap := Gap{From: time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), To: time.Date(2020, 3, 1, 11, 0, 0, 0, time.UTC)}
+	m := &marketCandles{Periods: []*period{{
+		From: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC),
+		Gaps: []Gap{gap},
+	}}}
+
+	// newer period without first hour of gap
+	m.add(&period{From: time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC), To: time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)})
+
+	if len(m.Periods) != 1 || len(m.Periods[0].Gaps) != 1 {
+		t.Fatalf("wrong periods %+v", m.Periods)
+	}
+	if g := m.Periods[0].Gaps[0]; !g.From.After(gap.From) || !g.To.Equal(gap.To) {
+		t.Fatalf("wrong gap %+v", g)
+	}
+}
+
+func TestPruneRetention(t *testing.T) {
+	lg := logger.New(ioutil.Discard, logger.ErrorLevel)
+	c, err := NewCandleCache(filepath.Join(t.TempDir(), "candles.cache"), lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	loader := &testLoader{}
+	mk := c.GetMarket("test", loader.load)
+
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 3, 23, 0, 0, 0, time.UTC)
+	if _, err := mk.Get(from, to, "BTCUSD", models.OneHour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mk.Get(from, to, "BTCUSD", models.OneDay); err != nil {
+		t.Fatal(err)
+	}
+
+	// hourly candles kept from 2020-03-02 01:00
+	cutoff := time.Date(2020, 3, 2, 0, 30, 0, 0, time.UTC)
+	c.SetLimits(map[models.CandleResolution]time.Duration{models.OneHour: time.Since(cutoff)}, 0)
+
+	if _, err := c.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("expected 2 stored, got %d", len(stored))
+	}
+	for _, st := range stored {
+		if st.Market != "test" || st.Symbol != "BTCUSD" || len(st.Periods) != 1 {
+			t.Fatalf("wrong stored %+v", st)
+		}
+		if st.Resolution == models.OneDay {
+			continue
+		}
+		first := time.Date(2020, 3, 2, 1, 0, 0, 0, time.UTC)
+		if !st.Periods[0].From.Equal(first) || st.Candles != 47 {
+			t.Fatalf("expected 47 candles from %v, got %d from %v", first, st.Candles, st.Periods[0].From)
+		}
+	}
+
+	// pruned range downloaded again
+	calls := loader.calls
+	if _, err := mk.Get(from, to, "BTCUSD", models.OneHour); err != nil {
+		t.Fatal(err)
+	}
+	if loader.calls == calls {
+		t.Fatal("pruned candles not downloaded")
+	}
+}
+
+func TestPruneSize(t *testing.T) {
+	lg := logger.New(ioutil.Discard, logger.ErrorLevel)
+	c, err := NewCandleCache(filepath.Join(t.TempDir(), "candles.cache"), lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	loader := &testLoader{}
+	mk := c.GetMarket("test", loader.load)
+
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC)
+	for _, symbol := range []string{"BTCUSD", "ETHUSD", "BTCUSD"} {
+		if _, err := mk.Get(from, to, symbol, models.OneHour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loader.calls != 2 {
+		t.Fatalf("expected 2 downloads, got %d", loader.calls)
+	}
+
+	// only one symbol fits, least recently used ETHUSD removed
+	c.SetLimits(nil, 30*candleSize)
+
+	n, err := c.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 25 {
+		t.Fatalf("expected 25 removed candles, got %d", n)
+	}
+
+	stored, err := c.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].Symbol != "BTCUSD" {
+		t.Fatalf("wrong stored %+v", stored)
+	}
+
+	if err := c.Remove("test", "BTCUSD", models.OneHour); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := c.List(); len(stored) != 0 {
+		t.Fatalf("expected nothing stored, got %+v", stored)
+	}
+}
+
+func TestVerifyStored(t *testing.T) {
+	lg := logger.New(ioutil.Discard, logger.ErrorLevel)
+	c, err := NewCandleCache(filepath.Join(t.TempDir(), "candles.cache"), lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	gap := Gap{From: time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC), To: time.Date(2020, 3, 1, 11, 0, 0, 0, time.UTC)}
+	loader := &testLoader{gap: gap}
+	mk := c.GetMarket("test", loader.load)
+
+	from := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 3, 1, 23, 0, 0, 0, time.UTC)
+	if _, err := mk.Get(from, to, "BTCUSD", models.OneHour); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := c.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if r := results[0]; r.Err != nil || len(r.Gaps) != 1 || len(r.Unrecorded) != 0 {
+		t.Fatalf("wrong result %+v", r)
+	}
+}
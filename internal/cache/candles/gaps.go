@@ -0,0 +1,209 @@
+package candles
+
+import (
+	"DaruBot/internal/models"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// missing candles closed less than pendingDelay ago could be not available yet, they are not recorded as gap
+	pendingDelay = time.Hour
+)
+
+// Gap range of missing candles, dates of the first and the last missing candle
+type Gap struct {
+	From time.Time
+	To   time.Time
+}
+
+// GapOptions repair of gaps in downloaded candles, gaps are only recorded if nothing set
+type GapOptions struct {
+	Refetch bool // download missing ranges again
+	Fill    bool // fill missing ranges by flat candles with zero volume, as intervals without trades
+}
+
+// RepairReport gaps found in downloaded candles and what was repaired
+type RepairReport struct {
+	Symbol     string
+	Resolution models.CandleResolution
+	Gaps       []Gap
+	Refetched  int // candles downloaded again
+	Filled     int // flat candles added
+	Remaining  []Gap
+}
+
+func (r RepairReport) String() string {
+	return fmt.Sprintf("%s %s: gaps %d, refetched candles %d, filled candles %d, remaining gaps %d",
+		r.Symbol, r.Resolution, len(r.Gaps), r.Refetched, r.Filled, len(r.Remaining))
+}
+
+// FindGaps returns gaps between the first and the last candle
+func FindGaps(candles *models.Candles) []Gap {
+	if len(candles.Candles) == 0 {
+		return []Gap{}
+	}
+	return findGaps(candles.Candles, candles.Resolution, candles.Candles[0].Date, candles.Candles[len(candles.Candles)-1].Date)
+}
+
+// findGaps returns gaps of sorted candles in range from - to, bounds are dates of the first and the last candle
+func findGaps(candles []*models.Candle, resolution models.CandleResolution, from, to time.Time) []Gap {
+	rs := make([]Gap, 0)
+
+	expected := from
+	for _, c := range candles {
+		if c.Date.Before(expected) {
+			continue
+		}
+		if c.Date.After(to) {
+			break
+		}
+		if c.Date.After(expected) {
+			rs = append(rs, Gap{From: expected, To: resolution.PeriodStart(c.Date.Add(-time.Second))})
+		}
+		expected = resolution.NextPeriod(c.Date)
+	}
+
+	if !expected.After(to) {
+		rs = append(rs, Gap{From: expected, To: to})
+	}
+
+	return rs
+}
+
+// subtractRange returns parts of gaps outside from - to
+func subtractRange(gaps []Gap, from, to time.Time) []Gap {
+	rs := make([]Gap, 0, len(gaps))
+	for _, g := range gaps {
+		if g.To.Before(from) || g.From.After(to) {
+			rs = append(rs, g)
+			continue
+		}
+		if g.From.Before(from) {
+			rs = append(rs, Gap{From: g.From, To: from.Add(-time.Second)})
+		}
+		if g.To.After(to) {
+			rs = append(rs, Gap{From: to.Add(time.Second), To: g.To})
+		}
+	}
+	return rs
+}
+
+// SetGapOptions set repair of gaps in downloaded candles
+func (c *Cache) SetGapOptions(opts GapOptions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gapOptions = opts
+}
+
+func (c *Cache) getGapOptions() GapOptions {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gapOptions
+}
+
+// Gaps returns recorded gaps of stored candles
+func (c *MarketCandlesCache) Gaps(symbol string, resolution models.CandleResolution) ([]Gap, error) {
+	key := c.makeKey(symbol, resolution)
+	c.lock.Lock(key)
+	defer c.lock.Unlock(key)
+
+	collection, err := c.cache.load(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]Gap, 0)
+	for _, p := range collection.Periods {
+		rs = append(rs, p.Gaps...)
+	}
+
+	return rs, nil
+}
+
+// repair find gaps of downloaded candles in from - to, refetch and fill them by options.
+// Candles must not be empty. Returns candles and remaining gaps
+func (c *MarketCandlesCache) repair(candles *models.Candles, from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, []Gap) {
+	gaps := findGaps(candles.Candles, resolution, from, to)
+	if len(gaps) == 0 {
+		return candles, gaps
+	}
+
+	opts := c.cache.getGapOptions()
+	report := RepairReport{
+		Symbol:     symbol,
+		Resolution: resolution,
+		Gaps:       gaps,
+	}
+
+	rs := &models.Candles{
+		Symbol:     candles.Symbol,
+		Resolution: candles.Resolution,
+		Candles:    append(make([]*models.Candle, 0, len(candles.Candles)), candles.Candles...),
+	}
+
+	if opts.Refetch {
+		first, last := candles.Candles[0].Date, candles.Candles[len(candles.Candles)-1].Date
+		for _, g := range gaps {
+			if g.To.Before(first) || g.From.After(last) {
+				// source has no candles before the first or after the last downloaded one
+				continue
+			}
+			fetched, err := c.loaderFunc(g.From, resolution.PeriodEnd(g.To), symbol, resolution)
+			if err != nil {
+				c.lg.Warnf("refetch gap %s - %s: %v", g.From.Format(time.RFC822Z), g.To.Format(time.RFC822Z), err)
+				continue
+			}
+			found := part(fetched, g.From, g.To)
+			report.Refetched = report.Refetched + len(found.Candles)
+			rs.Candles = append(rs.Candles, found.Candles...)
+		}
+		sort.Slice(rs.Candles, func(i, j int) bool {
+			return rs.Candles[i].Date.Before(rs.Candles[j].Date)
+		})
+		gaps = findGaps(rs.Candles, resolution, from, to)
+	}
+
+	if opts.Fill && len(rs.Candles) > 0 {
+		filled := make([]*models.Candle, 0, len(rs.Candles))
+		var prev *models.Candle
+		for _, cndl := range rs.Candles {
+			if prev != nil {
+				for d := resolution.NextPeriod(prev.Date); d.Before(cndl.Date); d = resolution.NextPeriod(d) {
+					filled = append(filled, flatCandle(prev, d))
+					report.Filled++
+				}
+			}
+			filled = append(filled, cndl)
+			prev = cndl
+		}
+		rs.Candles = filled
+		// gaps before the first and after the last candle have no price to fill
+		gaps = findGaps(rs.Candles, resolution, from, to)
+	}
+
+	report.Remaining = gaps
+	c.lg.Infof("candles gaps %s", report)
+
+	return rs, gaps
+}
+
+// flatCandle returns candle without trades at date, price is close of prev
+func flatCandle(prev *models.Candle, date time.Time) *models.Candle {
+	return &models.Candle{
+		Symbol:     prev.Symbol,
+		Resolution: prev.Resolution,
+		Date:       date,
+		Open:       prev.Close,
+		Close:      prev.Close,
+		High:       prev.Close,
+		Low:        prev.Close,
+		Volume:     0,
+	}
+}
+
+// isPending returns true if candles of gap could be not available yet
+func isPending(g Gap, resolution models.CandleResolution) bool {
+	return time.Since(resolution.PeriodEnd(g.To)) < pendingDelay
+}
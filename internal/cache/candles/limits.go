@@ -0,0 +1,392 @@
+package candles
+
+import (
+	"DaruBot/internal/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	pruneInterval = 10 * time.Minute
+)
+
+// StoredPeriod range of stored candles, dates of the first and the last candle
+type StoredPeriod struct {
+	From time.Time
+	To   time.Time
+	Gaps []Gap
+	Used time.Time
+}
+
+// StoredCandles candles stored by market, symbol and resolution
+type StoredCandles struct {
+	Market     string
+	Symbol     string
+	Resolution models.CandleResolution
+	Periods    []StoredPeriod
+	Candles    int
+	Size       int64 // bytes of candles without database overhead
+
+	key string
+}
+
+// VerifyResult consistency of stored period
+type VerifyResult struct {
+	Stored     StoredCandles
+	Period     StoredPeriod
+	Candles    int
+	Err        error // sorting and consistency error
+	Gaps       []Gap // found gaps
+	Unrecorded []Gap // found gaps which are not recorded
+}
+
+// ParseRetention returns retention by resolution from config, resolutions are case insensitive
+// as config keys are lowercased ("m" is month, "1m" is minute)
+func ParseRetention(cfg map[string]time.Duration) (map[models.CandleResolution]time.Duration, error) {
+	rs := make(map[models.CandleResolution]time.Duration, len(cfg))
+	for s, d := range cfg {
+		res, err := models.CandleResolutionFromString(s)
+		if err != nil {
+			res, err = models.CandleResolutionFromString(strings.ToUpper(s))
+			if err != nil {
+				return nil, err
+			}
+		}
+		rs[res] = d
+	}
+	return rs, nil
+}
+
+// SetLimits set how long candles of resolution are kept by date and size limit of all candles in bytes,
+// least recently used periods are removed above size. No limits if empty and 0
+func (c *Cache) SetLimits(retention map[models.CandleResolution]time.Duration, maxSize int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.retention = make(map[models.CandleResolution]time.Duration, len(retention))
+	for res, d := range retention {
+		c.retention[res] = d
+	}
+	c.maxSize = maxSize
+}
+
+func (c *Cache) limited() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.retention) > 0 || c.maxSize > 0
+}
+
+// schedulePrune prune cache in background, not often than pruneInterval
+func (c *Cache) schedulePrune() {
+	if !c.limited() {
+		return
+	}
+
+	c.mu.Lock()
+	if c.pruning || time.Since(c.lastPrune) < pruneInterval {
+		c.mu.Unlock()
+		return
+	}
+	c.pruning = true
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.wg.Done()
+
+		if _, err := c.Prune(); err != nil {
+			c.lg.Error("prune", err)
+		}
+
+		c.mu.Lock()
+		c.pruning = false
+		c.lastPrune = time.Now()
+		c.mu.Unlock()
+	}()
+}
+
+// List returns what is stored
+func (c *Cache) List() ([]StoredCandles, error) {
+	keys, err := c.store.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]StoredCandles, 0, len(keys))
+	for _, key := range keys {
+		st, err := c.stored(key)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, st)
+	}
+
+	sort.Slice(rs, func(i, j int) bool {
+		return rs[i].key < rs[j].key
+	})
+
+	return rs, nil
+}
+
+func (c *Cache) stored(key string) (StoredCandles, error) {
+	info, err := c.store.info(key)
+	if err != nil {
+		return StoredCandles{}, err
+	}
+
+	lock := c.marketLock(info.Market)
+	lock.Lock(key)
+	defer lock.Unlock(key)
+
+	rs := StoredCandles{
+		Market:     info.Market,
+		Symbol:     info.Symbol,
+		Resolution: info.Resolution,
+		Periods:    make([]StoredPeriod, 0),
+		key:        key,
+	}
+
+	collection, err := c.load(key)
+	if err != nil {
+		return rs, err
+	}
+	for _, p := range collection.Periods {
+		rs.Periods = append(rs.Periods, StoredPeriod{From: p.From, To: p.To, Gaps: p.Gaps, Used: p.Used})
+	}
+
+	rs.Candles, err = c.store.count(key)
+	rs.Size = int64(rs.Candles) * candleSize
+
+	return rs, err
+}
+
+// Read returns stored candles without download
+func (c *Cache) Read(market, symbol string, resolution models.CandleResolution, from, to time.Time) (*models.Candles, error) {
+	return c.store.read(makeKey(market, symbol, resolution), symbol, resolution, from, to)
+}
+
+// Remove delete all stored candles of market, symbol and resolution
+func (c *Cache) Remove(market, symbol string, resolution models.CandleResolution) error {
+	key := makeKey(market, symbol, resolution)
+
+	lock := c.marketLock(market)
+	lock.Lock(key)
+	defer lock.Unlock(key)
+
+	if err := c.store.removeKey(key); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	delete(c.collections, key)
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Prune remove candles older than retention of resolution and least recently used periods above size limit,
+// returns number of removed candles
+func (c *Cache) Prune() (int, error) {
+	c.mu.Lock()
+	retention := c.retention
+	maxSize := c.maxSize
+	c.mu.Unlock()
+
+	stored, err := c.List()
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+
+	for _, st := range stored {
+		keep, ok := retention[st.Resolution]
+		if !ok || keep <= 0 {
+			continue
+		}
+		n, err := c.removeBefore(st, time.Now().Add(-keep))
+		if err != nil {
+			return removed, err
+		}
+		removed = removed + n
+	}
+
+	if maxSize > 0 {
+		n, err := c.evict(maxSize)
+		if err != nil {
+			return removed, err
+		}
+		removed = removed + n
+	}
+
+	if removed > 0 {
+		c.lg.Infof("pruned %d candles", removed)
+	}
+
+	return removed, nil
+}
+
+// removeBefore delete candles with date before cutoff
+func (c *Cache) removeBefore(st StoredCandles, cutoff time.Time) (int, error) {
+	lock := c.marketLock(st.Market)
+	lock.Lock(st.key)
+	defer lock.Unlock(st.key)
+
+	collection, err := c.load(st.key)
+	if err != nil {
+		return 0, err
+	}
+
+	first := st.Resolution.PeriodStart(cutoff)
+	if first.Before(cutoff) {
+		first = st.Resolution.NextPeriod(cutoff)
+	}
+
+	periods := make([]*period, 0, len(collection.Periods))
+	for _, p := range collection.Periods {
+		if p.To.Before(first) {
+			continue
+		}
+		if p.From.Before(first) {
+			p = &period{From: first, To: p.To, Gaps: subtractRange(p.Gaps, p.From, first.Add(-time.Second)), Used: p.Used}
+		}
+		periods = append(periods, p)
+	}
+
+	n, err := c.store.remove(st.key, time.Unix(0, 0), first.Add(-time.Second), periods)
+	if err != nil {
+		return 0, err
+	}
+	collection.Periods = periods
+
+	return n, nil
+}
+
+// evict remove least recently used periods until size of candles is not above maxSize
+func (c *Cache) evict(maxSize int64) (int, error) {
+	stored, err := c.List()
+	if err != nil {
+		return 0, err
+	}
+
+	type usedPeriod struct {
+		st StoredCandles
+		p  StoredPeriod
+	}
+
+	var size int64
+	periods := make([]usedPeriod, 0)
+	for _, st := range stored {
+		size = size + st.Size
+		for _, p := range st.Periods {
+			periods = append(periods, usedPeriod{st: st, p: p})
+		}
+	}
+
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].p.Used.Before(periods[j].p.Used)
+	})
+
+	removed := 0
+	for _, up := range periods {
+		if size <= maxSize {
+			break
+		}
+		n, err := c.removePeriod(up.st, up.p)
+		if err != nil {
+			return removed, err
+		}
+		removed = removed + n
+		size = size - int64(n)*candleSize
+	}
+
+	return removed, nil
+}
+
+func (c *Cache) removePeriod(st StoredCandles, sp StoredPeriod) (int, error) {
+	lock := c.marketLock(st.Market)
+	lock.Lock(st.key)
+	defer lock.Unlock(st.key)
+
+	collection, err := c.load(st.key)
+	if err != nil {
+		return 0, err
+	}
+
+	periods := make([]*period, 0, len(collection.Periods))
+	for _, p := range collection.Periods {
+		if p.From.Equal(sp.From) && p.To.Equal(sp.To) {
+			continue
+		}
+		periods = append(periods, p)
+	}
+
+	if len(periods) == 0 {
+		// candles out of periods are removed too
+		count, err := c.store.count(st.key)
+		if err != nil {
+			return 0, err
+		}
+		if err := c.store.removeKey(st.key); err != nil {
+			return 0, err
+		}
+		c.mu.Lock()
+		delete(c.collections, st.key)
+		c.mu.Unlock()
+		return count, nil
+	}
+
+	n, err := c.store.remove(st.key, sp.From, sp.To, periods)
+	if err != nil {
+		return 0, err
+	}
+	collection.Periods = periods
+
+	return n, nil
+}
+
+// Verify check sorting, consistency and gaps of stored periods
+func (c *Cache) Verify() ([]VerifyResult, error) {
+	stored, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	rs := make([]VerifyResult, 0)
+	for _, st := range stored {
+		mk := c.GetMarket(st.Market, nil)
+		for _, p := range st.Periods {
+			candles, err := c.store.read(st.key, st.Symbol, st.Resolution, p.From, p.To)
+			if err != nil {
+				return nil, err
+			}
+
+			vr := VerifyResult{
+				Stored:     st,
+				Period:     p,
+				Candles:    len(candles.Candles),
+				Err:        mk.VerifyCandles(candles),
+				Gaps:       findGaps(candles.Candles, st.Resolution, p.From, p.To),
+				Unrecorded: make([]Gap, 0),
+			}
+
+			for _, g := range vr.Gaps {
+				recorded := false
+				for _, rg := range p.Gaps {
+					if !g.From.Before(rg.From) && !g.To.After(rg.To) {
+						recorded = true
+						break
+					}
+				}
+				if !recorded {
+					vr.Unrecorded = append(vr.Unrecorded, g)
+				}
+			}
+
+			rs = append(rs, vr)
+		}
+	}
+
+	return rs, nil
+}
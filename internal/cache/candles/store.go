@@ -0,0 +1,355 @@
+package candles
+
+import (
+	"DaruBot/internal/models"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	periodsKey     = []byte("periods")
+	infoKey        = []byte("info")
+	candlesBucket  = []byte("candles")
+	candleValueLen = 5 * 8
+	candleSize     = int64(8 + candleValueLen) // stored size of candle without database overhead
+)
+
+// keyInfo what candles are stored by key
+type keyInfo struct {
+	Market     string
+	Symbol     string
+	Resolution models.CandleResolution
+}
+
+// store persist candles in bolt database. Every market/resolution/symbol key has own bucket with
+// ranges of continuous candles and bucket of candles by date, so candles are written incrementally
+// and only requested range is read
+type store struct {
+	db *bolt.DB
+}
+
+func openStore(path string) (*store, error) {
+	legacy, err := readLegacy(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0666, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &store{db: db}
+
+	if legacy != nil {
+		if err := rs.importLegacy(legacy); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return rs, nil
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
+
+// keys returns keys of all stored candles
+func (s *store) keys() ([]string, error) {
+	rs := make([]string, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			rs = append(rs, string(name))
+			return nil
+		})
+	})
+	return rs, err
+}
+
+// info returns what is stored by key
+func (s *store) info(key string) (keyInfo, error) {
+	rs := keyInfo{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+		raw := b.Get(infoKey)
+		if raw == nil {
+			return nil
+		}
+		return json.Unmarshal(raw, &rs)
+	})
+	return rs, err
+}
+
+// count returns number of stored candles of key
+func (s *store) count(key string) (int, error) {
+	rs := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+		if cb := b.Bucket(candlesBucket); cb != nil {
+			rs = cb.Stats().KeyN
+		}
+		return nil
+	})
+	return rs, err
+}
+
+// periods returns stored ranges of key, empty if nothing stored
+func (s *store) periods(key string) ([]*period, error) {
+	rs := make([]*period, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+		raw := b.Get(periodsKey)
+		if raw == nil {
+			return nil
+		}
+		return json.Unmarshal(raw, &rs)
+	})
+	return rs, err
+}
+
+// read returns stored candles of key from - to including bounds
+func (s *store) read(key, symbol string, resolution models.CandleResolution, from, to time.Time) (*models.Candles, error) {
+	rs := &models.Candles{
+		Symbol:     symbol,
+		Resolution: resolution,
+		Candles:    make([]*models.Candle, 0),
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+		cb := b.Bucket(candlesBucket)
+		if cb == nil {
+			return nil
+		}
+
+		end := dateKey(to)
+		cur := cb.Cursor()
+		for k, v := cur.Seek(dateKey(from)); k != nil && bytes.Compare(k, end) <= 0; k, v = cur.Next() {
+			rs.Candles = append(rs.Candles, decodeCandle(k, v, symbol, resolution))
+		}
+		return nil
+	})
+
+	return rs, err
+}
+
+// write save candles and ranges of key in one transaction
+func (s *store) write(key string, info keyInfo, candles *models.Candles, periods []*period) error {
+	rawPeriods, err := json.Marshal(periods)
+	if err != nil {
+		return err
+	}
+	rawInfo, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(key))
+		if err != nil {
+			return err
+		}
+		cb, err := b.CreateBucketIfNotExists(candlesBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range candles.Candles {
+			if err := cb.Put(dateKey(c.Date), encodeCandle(c)); err != nil {
+				return err
+			}
+		}
+
+		if err := b.Put(infoKey, rawInfo); err != nil {
+			return err
+		}
+		return b.Put(periodsKey, rawPeriods)
+	})
+}
+
+// savePeriods update ranges of key
+func (s *store) savePeriods(key string, periods []*period) error {
+	raw, err := json.Marshal(periods)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+		return b.Put(periodsKey, raw)
+	})
+}
+
+// remove delete candles of key from - to including bounds and update ranges, returns number of deleted candles
+func (s *store) remove(key string, from, to time.Time, periods []*period) (int, error) {
+	raw, err := json.Marshal(periods)
+	if err != nil {
+		return 0, err
+	}
+
+	rs := 0
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(key))
+		if b == nil {
+			return nil
+		}
+
+		if cb := b.Bucket(candlesBucket); cb != nil {
+			keys := make([][]byte, 0)
+			end := dateKey(to)
+			cur := cb.Cursor()
+			for k, _ := cur.Seek(dateKey(from)); k != nil && bytes.Compare(k, end) <= 0; k, _ = cur.Next() {
+				keys = append(keys, k)
+			}
+			for _, k := range keys {
+				if err := cb.Delete(k); err != nil {
+					return err
+				}
+			}
+			rs = len(keys)
+		}
+
+		return b.Put(periodsKey, raw)
+	})
+
+	return rs, err
+}
+
+// removeKey delete all candles of key
+func (s *store) removeKey(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(key)) == nil {
+			return nil
+		}
+		return tx.DeleteBucket([]byte(key))
+	})
+}
+
+func dateKey(t time.Time) []byte {
+	rs := make([]byte, 8)
+	binary.BigEndian.PutUint64(rs, uint64(t.Unix()))
+	return rs
+}
+
+func encodeCandle(c *models.Candle) []byte {
+	rs := make([]byte, candleValueLen)
+	for i, v := range []float64{c.Open, c.Close, c.High, c.Low, c.Volume} {
+		binary.BigEndian.PutUint64(rs[i*8:], math.Float64bits(v))
+	}
+	return rs
+}
+
+func decodeCandle(k, v []byte, symbol string, resolution models.CandleResolution) *models.Candle {
+	value := func(i int) float64 {
+		return math.Float64frombits(binary.BigEndian.Uint64(v[i*8:]))
+	}
+
+	return &models.Candle{
+		Symbol:     symbol,
+		Resolution: resolution,
+		Date:       time.Unix(int64(binary.BigEndian.Uint64(k)), 0),
+		Open:       value(0),
+		Close:      value(1),
+		High:       value(2),
+		Low:        value(3),
+		Volume:     value(4),
+	}
+}
+
+// legacyCollection cache saved as one JSON file by previous versions
+type legacyCollection struct {
+	Version string
+	Items   map[string]struct {
+		Object struct {
+			Periods []struct {
+				From    time.Time
+				To      time.Time
+				Candles *models.Candles
+			}
+		}
+	}
+}
+
+// readLegacy returns JSON cache of previous versions and renames its file, nil if file is not JSON cache
+func readLegacy(path string) (*legacyCollection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	// bolt database starts with binary page header
+	head := make([]byte, 1)
+	if _, err := f.Read(head); err != nil || head[0] != '{' {
+		return nil, nil
+	}
+
+	// file could have trailing garbage of previous longer save, only first value decoded
+	rs := &legacyCollection{}
+	if err := json.NewDecoder(io.MultiReader(bytes.NewReader(head), f)).Decode(rs); err != nil {
+		return nil, err
+	}
+
+	f.Close()
+	if err := os.Rename(path, path+".json"); err != nil {
+		return nil, err
+	}
+
+	return rs, nil
+}
+
+func (s *store) importLegacy(legacy *legacyCollection) error {
+	if legacy.Version != "v1" {
+		return nil
+	}
+
+	for key, it := range legacy.Items {
+		collection := &marketCandles{Periods: []*period{}}
+		for _, p := range it.Object.Periods {
+			if p.Candles == nil || len(p.Candles.Candles) == 0 {
+				continue
+			}
+
+			// key is market_resolution_symbol
+			info := keyInfo{Symbol: p.Candles.Symbol, Resolution: p.Candles.Resolution}
+			if parts := strings.SplitN(key, "_", 3); len(parts) == 3 {
+				info.Market = parts[0]
+				info.Resolution = models.CandleResolution(parts[1])
+				info.Symbol = parts[2]
+			}
+
+			collection.add(&period{From: p.From, To: p.To})
+			if err := s.write(key, info, p.Candles, collection.Periods); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"DaruBot/pkg/tools/numbers"
 	"os"
+	"time"
 )
 
 type Configurations struct {
@@ -21,12 +22,17 @@ type Logger struct {
 
 type Exchanges struct {
 	Bitfinex Bitfinex
+	Mock     Mock
+	Paper    Paper
+	Replay   Replay
 }
 
 type Bitfinex struct {
 	ApiKey    string `mapstructure:",omitempty" yaml:",omitempty"`
 	ApiSec    string `mapstructure:",omitempty" yaml:",omitempty"`
 	Strategy  string
+	Record    string // directory to record websocket sessions for Replay, disabled if empty
+	Aggregate bool   // build candles of bigger resolutions from one subscription of minute candles of symbol
 	affiliate string
 }
 
@@ -34,6 +40,48 @@ func (b *Bitfinex) Affiliate() string {
 	return b.affiliate
 }
 
+// Mock exchange emulate trading on historical data, used instead of Bitfinex if enabled
+type Mock struct {
+	Enabled     bool
+	Market      string // go-quote market (e.g. binance-usdt)
+	Data        string // directory with local candles files, used instead of Market if set
+	Aggregate   bool   // build candles of bigger resolutions from minute candles instead of loading them
+	Currency    string // Fiat money name (e.g. USDT)
+	Deposit     float64
+	MaxLeverage uint8
+	MakerFee    float64       // percent of executed cost, applied to limit orders
+	TakerFee    float64       // percent of executed cost, applied to market and stop orders
+	Slippage    float64       // price offset of market and stop orders fills
+	SlippagePct float64       // price offset of market and stop orders fills in percent of price
+	VolumeShare float64       // max part (0..1] of minute candle volume filled per tick, unlimited if 0
+	From        string        // 2006-01-02
+	To          string        // 2006-01-02, now if empty
+	Tick        time.Duration // real time slept between steps, no sleeping if 0
+	Step        time.Duration // simulated time of one step
+}
+
+// Paper exchange trade on simulated account with live Bitfinex market data, used instead of Bitfinex if enabled.
+// Account is saved to local storage, Deposit used only if there is no saved account
+type Paper struct {
+	Enabled     bool
+	Currency    string // Fiat money name (e.g. USD)
+	Deposit     float64
+	MaxLeverage uint8
+	MakerFee    float64       // percent of executed cost, applied to limit orders
+	TakerFee    float64       // percent of executed cost, applied to market and stop orders
+	Slippage    float64       // price offset of market and stop orders fills
+	SlippagePct float64       // price offset of market and stop orders fills in percent of price
+	Interval    time.Duration // how often orders and positions are checked
+}
+
+// Replay exchange re-emit session recorded by Bitfinex.Record, used instead of Bitfinex if enabled
+// as market data of Paper exchange, orders are simulated with new account of Paper settings
+type Replay struct {
+	Enabled bool
+	File    string
+	Speed   float64 // 1 replays with original timing, greater is faster, 0 without delays
+}
+
 type DaruStonks struct {
 	Pair   string
 	Margin bool
@@ -69,13 +117,23 @@ type Proxy struct {
 }
 
 type Storage struct {
-	Local StorageLocal
+	Local   StorageLocal
+	Candles StorageCandles
 }
 
 type StorageLocal struct {
 	Path string
 }
 
+type StorageCandles struct {
+	Path        string // candles database, JSON cache of previous versions is imported
+	RefetchGaps bool   // download missing candles again
+	FillGaps    bool   // fill missing candles by flat candles with zero volume
+
+	Retention map[string]time.Duration // how long candles are kept by resolution, e.g. 1m: 720h
+	MaxSize   int64                    // size limit of candles in megabytes, least recently used are removed, no limit if 0
+}
+
 var (
 	defaultConfig = Configurations{
 		debugMode: true,
@@ -87,8 +145,43 @@ var (
 				ApiKey:    "",
 				ApiSec:    "",
 				Strategy:  "",
+				Record:    "",
+				Aggregate: false,
 				affiliate: "jXAX6tEPA",
 			},
+			Mock: Mock{
+				Enabled:     false,
+				Market:      "binance-usdt",
+				Aggregate:   false,
+				Currency:    "USDT",
+				Deposit:     1000,
+				MaxLeverage: 5,
+				MakerFee:    0.1,
+				TakerFee:    0.2,
+				Slippage:    0,
+				SlippagePct: 0,
+				VolumeShare: 0,
+				From:        "",
+				To:          "",
+				Tick:        100 * time.Millisecond,
+				Step:        time.Minute,
+			},
+			Paper: Paper{
+				Enabled:     false,
+				Currency:    "USD",
+				Deposit:     1000,
+				MaxLeverage: 5,
+				MakerFee:    0.1,
+				TakerFee:    0.2,
+				Slippage:    0,
+				SlippagePct: 0,
+				Interval:    5 * time.Second,
+			},
+			Replay: Replay{
+				Enabled: false,
+				File:    "",
+				Speed:   1,
+			},
 		},
 		Strategies: make(map[string]interface{}),
 		Nexus: Nexus{
@@ -115,6 +208,13 @@ var (
 			Local: StorageLocal{
 				Path: "./storage.db",
 			},
+			Candles: StorageCandles{
+				Path:        "./candles.cache",
+				RefetchGaps: true,
+				FillGaps:    false,
+				Retention:   map[string]time.Duration{},
+				MaxSize:     0,
+			},
 		},
 	}
 )
@@ -0,0 +1,399 @@
+/*
+Core build and bind together all parts of bot: storage, exchange, nexus and strategy.
+Shutdown tear down them in reverse order.
+*/
+package core
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/config"
+	"DaruBot/internal/exchanges"
+	"DaruBot/internal/exchanges/bitfinex"
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/exchanges/paper"
+	"DaruBot/internal/exchanges/replay"
+	logger2 "DaruBot/internal/logger"
+	"DaruBot/internal/models"
+	models2 "DaruBot/internal/models/exchanges"
+	ncore "DaruBot/internal/nexus/core"
+	"DaruBot/internal/nexus/core/modules/telegram"
+	"DaruBot/internal/nexus/core/pb/schema/gen"
+	"DaruBot/internal/strategy"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/nexus"
+	"DaruBot/pkg/watcher"
+	"DaruBot/storage"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	shutdownTimeout = 10 * time.Second
+	dateFormat      = "2006-01-02"
+)
+
+var (
+	ErrAlreadyRunning  = errors.New("CORE ALREADY RUNNING")
+	ErrShutdownTimeout = errors.New("CORE SHUTDOWN TIMEOUT")
+
+	mu      = &sync.Mutex{}
+	running *core
+)
+
+type localStorage interface {
+	ProvideStatsStorage() (storage.StatsStorage, error)
+	ProvideCustomStorage(bucket string) (storage.CustomStorage, error)
+	Stop() error
+}
+
+type core struct {
+	cfg config.Configurations
+	log logger.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	storage      localStorage
+	watchers     *watcher.Manager
+	candlesCache *candles.Cache
+	exchange     exchanges.CryptoExchange
+	exchangeName string
+	mockClock    func() time.Time
+	nexus        nexus.Nexus
+	modules      []nexus.Module
+
+	strategy       strategy.Strategy
+	strategyCancel context.CancelFunc
+	strategyDone   <-chan struct{}
+}
+
+// Run start bot, on error all started parts will be stopped
+func Run(ctx context.Context, cfg config.Configurations) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if running != nil {
+		return ErrAlreadyRunning
+	}
+
+	c := &core{
+		cfg: cfg,
+		log: logger2.NewLogger(cfg).WithPrefix("module", "core"),
+	}
+
+	if err := c.start(ctx); err != nil {
+		c.log.Error("start failed", err)
+		c.stop()
+		return err
+	}
+
+	running = c
+
+	return nil
+}
+
+// Shutdown stop bot, waits not longer than shutdownTimeout
+func Shutdown() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if running == nil {
+		return nil
+	}
+
+	c := running
+	running = nil
+
+	done := make(chan struct{})
+
+	go func() {
+		c.stop()
+		close(done)
+	}()
+
+	timeout := time.NewTimer(shutdownTimeout)
+	defer timeout.Stop()
+
+	select {
+	case <-done:
+		c.log.Info("shutdown complete")
+		return nil
+	case <-timeout.C:
+		c.log.Error(ErrShutdownTimeout)
+		return ErrShutdownTimeout
+	}
+}
+
+func (c *core) start(ctx context.Context) error {
+	var err error
+
+	c.ctx, c.cancel = context.WithCancel(ctx)
+
+	c.storage, err = storage.New(c.cfg)
+	if err != nil {
+		return err
+	}
+
+	c.watchers = watcher.NewWatcherManager()
+
+	if err = c.openCandlesCache(); err != nil {
+		return err
+	}
+
+	if err = c.startExchange(); err != nil {
+		return err
+	}
+
+	if err = c.startNexus(); err != nil {
+		return err
+	}
+
+	if err = c.startStrategy(); err != nil {
+		return err
+	}
+
+	c.log.Info("bot started")
+
+	return nil
+}
+
+func (c *core) openCandlesCache() error {
+	var err error
+
+	c.candlesCache, err = candles.NewCandleCache(c.cfg.Storage.Candles.Path, c.log)
+	if err != nil {
+		return err
+	}
+	c.candlesCache.SetGapOptions(candles.GapOptions{
+		Refetch: c.cfg.Storage.Candles.RefetchGaps,
+		Fill:    c.cfg.Storage.Candles.FillGaps,
+	})
+
+	retention, err := candles.ParseRetention(c.cfg.Storage.Candles.Retention)
+	if err != nil {
+		return err
+	}
+	c.candlesCache.SetLimits(retention, c.cfg.Storage.Candles.MaxSize*1024*1024)
+
+	return nil
+}
+
+func (c *core) startExchange() error {
+	var err error
+
+	if c.cfg.Exchanges.Mock.Enabled {
+		c.exchange, err = c.newMock()
+		c.exchangeName = models2.ExchangeTypeMock.String()
+	} else if c.cfg.Exchanges.Paper.Enabled || c.cfg.Exchanges.Replay.Enabled {
+		// replay is read only, orders are simulated by paper
+		c.exchange, err = c.newPaper()
+		c.exchangeName = models2.ExchangeTypePaper.String()
+	} else {
+		c.exchange, err = bitfinex.NewBitfinex(c.ctx, c.cfg, c.watchers, c.log)
+		c.exchangeName = models2.ExchangeTypeBitfinex.String()
+	}
+	if err != nil {
+		return err
+	}
+
+	c.log.Infof("connecting to %s", c.exchangeName)
+
+	return c.exchange.Connect()
+}
+
+func (c *core) newMock() (exchanges.CryptoExchange, error) {
+	mCfg := c.cfg.Exchanges.Mock
+
+	to := time.Now()
+	if mCfg.To != "" {
+		t, err := time.ParseInLocation(dateFormat, mCfg.To, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, -1)
+	if mCfg.From != "" {
+		t, err := time.ParseInLocation(dateFormat, mCfg.From, time.Local)
+		if err != nil {
+			return nil, err
+		}
+		from = t
+	}
+
+	source, err := mock.NewSource(mCfg.Market, mCfg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &models.Wallets{WalletType: models.WalletTypeExchange}
+	w.Update(&models.WalletCurrency{
+		Name:       mCfg.Currency,
+		WalletType: models.WalletTypeExchange,
+		Balance:    mCfg.Deposit,
+		Available:  mCfg.Deposit,
+	})
+
+	stand := mock.NewTheWorld(from, to, mCfg.Tick)
+	stand.SetStep(mCfg.Step)
+	c.mockClock = stand.CurrentTime
+	plutos := mock.NewPlutos(mCfg.MaxLeverage, mock.Fees{Maker: mCfg.MakerFee, Taker: mCfg.TakerFee}, mCfg.Currency, w, []models.Order{}, []models.Position{})
+	plutos.SetFillModel(mock.SlippageFill{Fixed: mCfg.Slippage, Percent: mCfg.SlippagePct, VolumeShare: mCfg.VolumeShare})
+
+	return mock.NewExchangeMock(c.ctx, c.watchers, c.log, c.cfg, source, c.candlesCache, stand, plutos)
+}
+
+// newPaper returns paper exchange with market data of replayed session if replay enabled, otherwise of bitfinex.
+// Account of replayed session is new on every start and not saved
+func (c *core) newPaper() (exchanges.CryptoExchange, error) {
+	var (
+		market     exchanges.CryptoExchange
+		marketName string
+		err        error
+	)
+
+	if c.cfg.Exchanges.Replay.Enabled {
+		market, err = replay.NewReplay(c.ctx, c.watchers, c.log, c.cfg.Exchanges.Replay.File, c.cfg.Exchanges.Replay.Speed)
+		marketName = models2.ExchangeTypeReplay.String()
+	} else {
+		market, err = bitfinex.NewBitfinex(c.ctx, c.cfg, c.watchers, c.log)
+		marketName = models2.ExchangeTypeBitfinex.String()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st storage.CustomStorage
+	if !c.cfg.Exchanges.Replay.Enabled {
+		st, err = c.storage.ProvideCustomStorage("paper")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return paper.NewPaper(c.ctx, c.watchers, c.log, c.cfg, market, marketName, st)
+}
+
+func (c *core) startNexus() error {
+	c.nexus = nexus.NewNexus(c.handleCommand)
+
+	if c.cfg.Nexus.Modules.Telegram.Enabled {
+		tg, err := telegram.NewTelegram(c.cfg, c.log)
+		if err != nil {
+			return err
+		}
+		if err := c.nexus.Register(tg); err != nil {
+			return err
+		}
+		c.modules = append(c.modules, tg)
+	}
+
+	return nil
+}
+
+func (c *core) startStrategy() error {
+	s, err := strategy.FromConfig(c.cfg, c.log)
+	if err != nil {
+		if err == strategy.ErrStrategyNotSet {
+			c.log.Warn("strategy not set, bot will not trade")
+			return nil
+		}
+		return err
+	}
+
+	cs, err := c.storage.ProvideCustomStorage(fmt.Sprintf("strategy_%s", s.Name()))
+	if err != nil {
+		return err
+	}
+
+	if hu, ok := s.(strategy.HistoryUser); ok {
+		hu.SetHistory(c.newHistory())
+	}
+
+	if err := s.Init(c.exchange, c.watchers, cs); err != nil {
+		return err
+	}
+	c.strategy = s
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.strategyCancel = cancel
+
+	c.strategyDone, err = strategy.Start(ctx, s, c.watchers, c.exchangeName, c.log)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.log.Infof("strategy %s started", s.Name())
+
+	return nil
+}
+
+// newHistory returns candles history of exchange, candles of live markets are cached
+func (c *core) newHistory() *strategy.History {
+	switch {
+	case c.exchangeName == models2.ExchangeTypeMock.String():
+		// mock caches candles itself and lives in simulated time
+		return strategy.NewHistory(c.exchange, nil, "", c.mockClock)
+	case c.cfg.Exchanges.Replay.Enabled:
+		// paper trades on replayed market data
+		return strategy.NewHistory(c.exchange, nil, "", nil)
+	default:
+		// paper trades on bitfinex market data
+		return strategy.NewHistory(c.exchange, c.candlesCache, models2.ExchangeTypeBitfinex.String(), time.Now)
+	}
+}
+
+func (c *core) handleCommand(ctx context.Context, cmd nexus.Command) (nexus.Response, error) {
+	switch cmd.GetPayload().(type) {
+	case *gen.GetStatsRequest:
+		return &ncore.Response{Rsp: &gen.GetStatsReplay{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown command %v", cmd.GetType())
+	}
+}
+
+// stop tear down all started parts in reverse order
+func (c *core) stop() {
+	if c.strategyDone != nil {
+		c.strategyCancel()
+		<-c.strategyDone
+	}
+
+	if c.strategy != nil {
+		if err := c.strategy.Shutdown(); err != nil {
+			c.log.Error("strategy shutdown", err)
+		}
+	}
+
+	for i := len(c.modules) - 1; i >= 0; i-- {
+		if err := c.modules[i].Stop(); err != nil {
+			c.log.Error("nexus module stop", err)
+		}
+	}
+
+	// cancel open requests
+	if c.cancel != nil {
+		c.cancel()
+	}
+
+	if c.exchange != nil {
+		c.exchange.Disconnect()
+	}
+
+	if c.candlesCache != nil {
+		if err := c.candlesCache.Close(); err != nil {
+			c.log.Error("candles cache close", err)
+		}
+	}
+
+	if c.storage != nil {
+		if err := c.storage.Stop(); err != nil {
+			c.log.Error("storage stop", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package bitfinex
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/models"
+	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/common"
+	"github.com/google/uuid"
+	"time"
+)
+
+// aggregatedCandles subscription of candles built from stream of minute candles,
+// aggregator is nil for minute candles
+type aggregatedCandles struct {
+	symbol     string
+	aggregator *candles.Aggregator
+}
+
+// minuteStream subscription of minute candles shared by all resolutions of symbol
+type minuteStream struct {
+	sid  string
+	refs int
+}
+
+// subscribeAggregated subscribe candles built from minute candles, minute candles of symbol are subscribed once
+// for all resolutions. Minute candles of current period are loaded, so the first candle is complete
+func (b *bitfinexWebsocket) subscribeAggregated(symbol string, resolution models.CandleResolution) (string, error) {
+	a := &aggregatedCandles{symbol: symbol}
+
+	if resolution != models.OneMinute {
+		a.aggregator = candles.NewAggregator(resolution)
+		if err := b.loadPeriod(a.aggregator, symbol, resolution); err != nil {
+			b.log.Warn("current period is not loaded, the first candle is not complete", symbol, resolution, err)
+		}
+	}
+
+	b.aggMu.Lock()
+	defer b.aggMu.Unlock()
+
+	m, ok := b.minutes[symbol]
+	if !ok {
+		sid, err := b.ws.SubscribeCandles(b.ctx, symbol, common.OneMinute)
+		if err != nil {
+			return "", err
+		}
+		m = &minuteStream{sid: sid}
+		b.minutes[symbol] = m
+	}
+	m.refs++
+
+	sid := uuid.Must(uuid.NewUUID()).String()
+	b.aggregated[sid] = a
+	b.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeCandle,
+	})
+
+	return sid, nil
+}
+
+// loadPeriod update aggregator by minute candles of current period of resolution
+func (b *bitfinexWebsocket) loadPeriod(agg *candles.Aggregator, symbol string, resolution models.CandleResolution) error {
+	now := time.Now()
+
+	for from := resolution.PeriodStart(now); from.Before(now); {
+		cs, err := b.GetCandles(symbol, models.OneMinute, from, now)
+		if err != nil {
+			return err
+		}
+		if len(cs.Candles) == 0 {
+			return nil
+		}
+
+		for _, c := range cs.Candles {
+			agg.Update(c)
+		}
+
+		// requested by pages
+		from = cs.Candles[len(cs.Candles)-1].Date.Add(time.Minute)
+	}
+
+	return nil
+}
+
+// unsubscribeAggregated returns false if sid is not subscription of aggregated candles,
+// minute candles are unsubscribed with the last resolution of symbol
+func (b *bitfinexWebsocket) unsubscribeAggregated(sid string) (bool, error) {
+	b.aggMu.Lock()
+
+	a, ok := b.aggregated[sid]
+	if !ok {
+		b.aggMu.Unlock()
+		return false, nil
+	}
+
+	delete(b.aggregated, sid)
+	b.subscriptions.Delete(sid)
+
+	m := b.minutes[a.symbol]
+	m.refs--
+	if m.refs > 0 {
+		b.aggMu.Unlock()
+		return true, nil
+	}
+	delete(b.minutes, a.symbol)
+
+	b.aggMu.Unlock()
+
+	return true, b.ws.Unsubscribe(b.ctx, m.sid)
+}
+
+// aggregateCandle emit forming candles of subscribed resolutions updated by minute candle
+func (b *bitfinexWebsocket) aggregateCandle(c *models.Candle) {
+	if c == nil || c.Resolution != models.OneMinute {
+		return
+	}
+
+	b.aggMu.Lock()
+	rs := make([]*models.Candle, 0)
+	for _, a := range b.aggregated {
+		if a.aggregator == nil || a.symbol != c.Symbol {
+			continue
+		}
+		rs = append(rs, a.aggregator.Update(c)...)
+	}
+	b.aggMu.Unlock()
+
+	for _, cndl := range rs {
+		b.emmit(models.EventCandleState, *cndl)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/balanceinfo"
+	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/book"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/candle"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/common"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/notification"
@@ -32,6 +33,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -41,6 +43,8 @@ var (
 
 		models.EventTickerState,
 		models.EventCandleState,
+		models.EventOrderBookUpdate,
+		models.EventTrade,
 
 		models.EventOrderNew,
 		models.EventOrderFilled,
@@ -48,6 +52,8 @@ var (
 		models.EventOrderPartiallyFilled,
 		models.EventOrderUpdate,
 
+		models.EventOwnTradeExecuted,
+
 		models.EventPositionNew,
 		models.EventPositionClosed,
 		models.EventPositionUpdate,
@@ -60,6 +66,12 @@ var (
 	eventRequestFail    = watcher.NewEventType(models.EventsModuleExchange, "EventRequestFail", models.RequestResult{})
 )
 
+// bookSubscription parameters of subscribed order book
+type bookSubscription struct {
+	precision models.BookPrecision
+	depth     int
+}
+
 type bitfinexWebsocket struct {
 	ctx context.Context
 
@@ -77,13 +89,20 @@ type bitfinexWebsocket struct {
 	positions *bitfinex.BitfinexPositions
 
 	subscriptions   models.Subscriptions
+	books           sync.Map // symbol - bookSubscription
 	walletsExchange models.Wallets
 	walletsMargin   models.Wallets
 	balance         models.BalanceUSD
 
 	lastUpdate time.Time
 
+	aggMu      *sync.Mutex
+	aggregated map[string]*aggregatedCandles // by subscription id
+	minutes    map[string]*minuteStream      // by symbol
+
+	name     string // emitter of events
 	watchers *watcher.Manager
+	recorder *recordFactory
 }
 
 func NewBitfinex(ctx context.Context, c config.Configurations, wManager *watcher.Manager, lg logger.Logger) (exchanges2.CryptoExchange, error) {
@@ -91,15 +110,47 @@ func NewBitfinex(ctx context.Context, c config.Configurations, wManager *watcher
 }
 
 func newBitfinex(ctx context.Context, c config.Configurations, wManager *watcher.Manager, lg logger.Logger) (*bitfinexWebsocket, error) {
-	p := websocket.NewDefaultParameters()
-	p.ManageOrderbook = false
-	p.LogTransport = false
-
+	p := newParameters(lg)
 	p.ResubscribeOnReconnect = true
 	p.AutoReconnect = true
 	p.ReconnectAttempts = 1
 	p.ReconnectInterval = time.Second * 3
 
+	name := exchanges.ExchangeTypeBitfinex.String()
+
+	var factory websocket.AsynchronousFactory = websocket.NewWebsocketAsynchronousFactory(p)
+
+	var recorder *recordFactory
+	if dir := c.Exchanges.Bitfinex.Record; dir != "" {
+		recorder = newRecordFactory(factory, dir, lg.WithPrefix("exchange", name))
+		factory = recorder
+	}
+
+	WebSocket := websocket.NewWithParamsAsyncFactory(p, factory).Credentials(c.Exchanges.Bitfinex.ApiKey, c.Exchanges.Bitfinex.ApiSec)
+	REST := rest.NewClient().Credentials(c.Exchanges.Bitfinex.ApiKey, c.Exchanges.Bitfinex.ApiSec)
+
+	status, err := REST.Platform.Status()
+	if err != nil || !status {
+		return nil, exchanges2.ErrNotOperate
+	}
+
+	b, err := newExchange(ctx, c, wManager, lg, name, WebSocket)
+	if err != nil {
+		return nil, err
+	}
+	b.rest = REST
+	b.recorder = recorder
+
+	return b, nil
+}
+
+// newParameters returns parameters of api client with logger
+func newParameters(lg logger.Logger) *websocket.Parameters {
+	p := websocket.NewDefaultParameters()
+	// books are maintained by api client from snapshot and updates, checksums are verified
+	p.ManageOrderbook = true
+	p.LogTransport = false
+
 	log := logging.MustGetLogger("Bitfinex_internal")
 	logging.SetLevel(logging.INFO, log.Module)
 	//if !c.IsDebug() {
@@ -108,32 +159,32 @@ func newBitfinex(ctx context.Context, c config.Configurations, wManager *watcher
 	log.SetBackend(logger2.ConvertToGoLogging(lg.WithPrefix("exchange", log.Module), logging.INFO))
 	p.Logger = log
 
-	WebSocket := websocket.NewWithParams(p).Credentials(c.Exchanges.Bitfinex.ApiKey, c.Exchanges.Bitfinex.ApiSec)
-	REST := rest.NewClient().Credentials(c.Exchanges.Bitfinex.ApiKey, c.Exchanges.Bitfinex.ApiSec)
-
-	status, err := REST.Platform.Status()
-	if err != nil || !status {
-		return nil, exchanges2.ErrNotOperate
-	}
+	return p
+}
 
-	err = wManager.RegisterEvents(exchanges.ExchangeTypeBitfinex.String(), supportEventsBitfinex)
+// newExchange returns exchange of websocket client, events are emitted by name
+func newExchange(ctx context.Context, c config.Configurations, wManager *watcher.Manager, lg logger.Logger, name string, ws *websocket.Client) (*bitfinexWebsocket, error) {
+	err := wManager.RegisterEvents(name, supportEventsBitfinex)
 	if err != nil {
 		return nil, err
 	}
 
 	return &bitfinexWebsocket{
 		ctx:             ctx,
-		ws:              WebSocket,
-		rest:            REST,
-		log:             lg.WithPrefix("exchange", "Bitfinex"),
+		ws:              ws,
+		log:             lg.WithPrefix("exchange", name),
 		walletsExchange: models.Wallets{WalletType: models.WalletTypeExchange},
 		walletsMargin:   models.Wallets{WalletType: models.WalletTypeMargin},
 		balance:         models.BalanceUSD{},
 		subscriptions:   models.Subscriptions{},
 		orders:          &bitfinex.BitfinexOrders{},
 		positions:       &bitfinex.BitfinexPositions{},
+		aggMu:           &sync.Mutex{},
+		aggregated:      make(map[string]*aggregatedCandles),
+		minutes:         make(map[string]*minuteStream),
 		readyChan:       make(chan interface{}, 1),
 		disconnectChan:  make(chan interface{}, 1),
+		name:            name,
 		watchers:        wManager,
 		cfg:             c,
 	}, nil
@@ -180,7 +231,7 @@ func (b *bitfinexWebsocket) Ready() <-chan interface{} {
 }
 
 func (b *bitfinexWebsocket) SupportEvents() watcher.EventsMap {
-	return b.watchers.SupportEvents(string(exchanges.ExchangeTypeBitfinex))
+	return b.watchers.SupportEvents(b.name)
 }
 
 func (b *bitfinexWebsocket) listen() {
@@ -188,6 +239,10 @@ func (b *bitfinexWebsocket) listen() {
 		b.ready = false
 		b.ws.Close()
 		b.log.Info("websocket disconnected")
+
+		if b.recorder != nil {
+			b.recorder.close()
+		}
 	}()
 
 	defer tools.Recover(b.log)
@@ -320,17 +375,34 @@ func (b *bitfinexWebsocket) listen() {
 				b.lastUpdate = time.Now()
 
 			case *tradeexecution.TradeExecution:
+				// fee is known only from following update
 				b.log.Debugf("TRADE EXECUTION:  %#v", data)
 
 			case *tradeexecutionupdate.TradeExecutionUpdate:
 				b.log.Debugf("TRADE EXECUTION UPDATE:  %#v", data)
 
+				if ex := b.convertExecution(data); ex != nil {
+					b.emmit(models.EventOwnTradeExecuted, *ex)
+				}
+				b.lastUpdate = time.Now()
+
 			case *trade.Trade:
 				b.log.Debugf("TRADE NEW:  %#v", data)
 
+				if t := b.convertTrade(data); t != nil {
+					b.emmit(models.EventTrade, *t)
+				}
+
 			case *trade.Snapshot:
 				b.log.Debugf("TRADE SNAPSHOT:  %#v", data)
 
+				// snapshot is sorted from the newest trade
+				for i := len(data.Snapshot) - 1; i >= 0; i-- {
+					if t := b.convertTrade(data.Snapshot[i]); t != nil {
+						b.emmit(models.EventTrade, *t)
+					}
+				}
+
 			case *ticker.Snapshot:
 				b.log.Debugf("TICKER SNAPSHOT:  %#v", data)
 
@@ -354,11 +426,29 @@ func (b *bitfinexWebsocket) listen() {
 				for _, c := range data.Snapshot {
 					b.emmit(models.EventCandleState, *b.convertCandle(c))
 				}
+				// snapshot is ordered from new to old
+				for i := len(data.Snapshot) - 1; i >= 0; i-- {
+					b.aggregateCandle(b.convertCandle(data.Snapshot[i]))
+				}
 
 			case *candle.Candle:
 				b.log.Debugf("CANDLE:  %#v", data)
 
-				b.emmit(models.EventCandleState, *b.convertCandle(data))
+				c := b.convertCandle(data)
+				b.emmit(models.EventCandleState, *c)
+				b.aggregateCandle(c)
+
+			case *book.Snapshot:
+				b.log.Debugf("BOOK SNAPSHOT:  %#v", data)
+
+				if len(data.Snapshot) > 0 {
+					b.emmitBook(data.Snapshot[0].Symbol)
+				}
+
+			case *book.Book:
+				b.log.Tracef("BOOK UPDATE:  %#v", data)
+
+				b.emmitBook(data.Symbol)
 
 			case *notification.Notification:
 				b.log.Debugf("NOTIFICATION NEW:  %#v", data)
@@ -454,14 +544,14 @@ func (b *bitfinexWebsocket) processPosition(p *position.Position) {
 }
 
 func (b *bitfinexWebsocket) emmit(eventHead watcher.EventHead, data interface{}) {
-	err := b.watchers.Emmit(watcher.BuildEvent(eventHead, string(exchanges.ExchangeTypeBitfinex), data))
+	err := b.watchers.Emmit(watcher.BuildEvent(eventHead, b.name, data))
 	if err != nil {
 		b.log.Error(err)
 	}
 }
 
 func (b *bitfinexWebsocket) newWatcher(name string, events ...watcher.EventHead) *watcher.Watcher {
-	return b.watchers.MustNew(name, models.EventsModuleExchange, exchanges.ExchangeTypeBitfinex.String(), events...)
+	return b.watchers.MustNew(name, models.EventsModuleExchange, b.name, events...)
 }
 
 /*
@@ -482,6 +572,10 @@ func (b *bitfinexWebsocket) SubscribeTicker(symbol string) (string, error) {
 }
 
 func (b *bitfinexWebsocket) SubscribeCandles(symbol string, resolution models.CandleResolution) (string, error) {
+	if b.cfg.Exchanges.Bitfinex.Aggregate {
+		return b.subscribeAggregated(symbol, resolution)
+	}
+
 	cres, err := candleResolutionToBitfinex(resolution)
 	if err != nil {
 		return "", err
@@ -501,6 +595,75 @@ func (b *bitfinexWebsocket) SubscribeCandles(symbol string, resolution models.Ca
 	return sid, nil
 }
 
+// SubscribeOrderBook https://docs.bitfinex.com/reference#ws-public-books
+// depth is rounded up to supported number of price levels
+func (b *bitfinexWebsocket) SubscribeOrderBook(symbol string, precision models.BookPrecision, depth int) (string, error) {
+	prec, err := bookPrecisionToBitfinex(precision)
+	if err != nil {
+		return "", err
+	}
+
+	if depth <= 0 {
+		return "", errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "book depth must be positive")
+	}
+
+	sid, err := b.ws.SubscribeBook(b.ctx, symbol, prec, common.FrequencyRealtime, bookLenToBitfinex(depth))
+	if err != nil {
+		return "", err
+	}
+
+	b.books.Store(symbol, bookSubscription{precision: precision, depth: depth})
+	b.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeOrderBook,
+	})
+
+	return sid, nil
+}
+
+func (b *bitfinexWebsocket) emmitBook(symbol string) {
+	ob, err := b.GetOrderBook(symbol)
+	if err != nil {
+		b.log.Debug("book not ready", err)
+		return
+	}
+	b.emmit(models.EventOrderBookUpdate, *ob)
+}
+
+func (b *bitfinexWebsocket) GetOrderBook(symbol string) (*models.OrderBook, error) {
+	bs, ok := b.books.Load(symbol)
+	if !ok {
+		return nil, exchanges2.ErrOrderBookNotSubscribed
+	}
+
+	ob, err := b.ws.GetOrderbook(symbol)
+	if err != nil {
+		return nil, errors.WrapMessage(exchanges2.ErrOrderBookNotSubscribed, err)
+	}
+
+	sub := bs.(bookSubscription)
+
+	return bitfinexBookToModel(ob, sub.precision, sub.depth), nil
+}
+
+// SubscribeTrades https://docs.bitfinex.com/reference#ws-public-trades
+// the last trades are emitted on subscribe
+func (b *bitfinexWebsocket) SubscribeTrades(symbol string) (string, error) {
+	sid, err := b.ws.SubscribeTrades(b.ctx, symbol)
+	if err != nil {
+		return "", err
+	}
+
+	b.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeTrades,
+	})
+
+	return sid, nil
+}
+
 //func (b *Bitfinex) SubscribeStatus(symbol string) (string, error) {
 //	sid, err := b.ws.SubscribeStatus(b.ctx, "global", "liq")
 //	if err != nil {
@@ -517,8 +680,14 @@ func (b *bitfinexWebsocket) SubscribeCandles(symbol string, resolution models.Ca
 //}
 
 func (b *bitfinexWebsocket) Unsubscribe(sid string) error {
+	if ok, err := b.unsubscribeAggregated(sid); ok {
+		return err
+	}
+
 	err := b.ws.Unsubscribe(b.ctx, sid)
-	b.subscriptions.Delete(sid)
+	if sub := b.subscriptions.Delete(sid); sub != nil && sub.Type == models.SubTypeOrderBook {
+		b.books.Delete(sub.Symbol)
+	}
 	return err
 }
 
@@ -682,10 +851,13 @@ func (b *bitfinexWebsocket) PutOrder(o *models.PutOrder) (*models.Order, error)
 
 	var typeOrder string
 	var PriceAuxLimit float64
+	var PriceTrailing float64
+	var PriceOcoStop float64
 	var Price = o.Price
 	var Amount = o.Amount
 	var Pair = o.Symbol
 	var orderClientID int64
+	var flags int
 
 	switch o.Type {
 	case models.OrderTypeLimit:
@@ -709,10 +881,43 @@ func (b *bitfinexWebsocket) PutOrder(o *models.PutOrder) (*models.Order, error)
 			return nil, errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "stop price are not specified")
 		}
 		Price = o.StopPrice
+	case models.OrderTypeTrailingStop:
+		typeOrder = "TRAILING STOP"
+		if o.TrailingDistance <= 0 {
+			return nil, errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "trailing distance are not specified")
+		}
+		PriceTrailing = o.TrailingDistance
+		Price = 0
+	case models.OrderTypeFOK, models.OrderTypeIOC:
+		typeOrder = string(o.Type)
+		if o.Price == 0 {
+			return nil, errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "limit price are not specified")
+		}
+	case models.OrderTypeOCO:
+		// limit order with linked stop order
+		typeOrder = "LIMIT"
+		if o.Price == 0 {
+			return nil, errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "limit price are not specified")
+		}
+		if o.StopPrice == 0 {
+			return nil, errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "stop price are not specified")
+		}
+		PriceOcoStop = o.StopPrice
 	default:
 		return nil, exchanges2.ErrOrderTypeNotSupported
 	}
 
+	if o.PostOnly && o.Type != models.OrderTypeLimit && o.Type != models.OrderTypeOCO {
+		return nil, errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "post only supported by limit orders")
+	}
+
+	if o.ReduceOnly {
+		if !o.Margin {
+			return nil, errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "reduce only supported by margin orders")
+		}
+		flags = flags | orderFlagReduceOnly
+	}
+
 	if !o.Margin {
 		typeOrder = fmt.Sprintf("EXCHANGE %s", typeOrder)
 	}
@@ -738,16 +943,23 @@ func (b *bitfinexWebsocket) PutOrder(o *models.PutOrder) (*models.Order, error)
 		Amount:        Amount,
 		Price:         Price,
 		PriceAuxLimit: PriceAuxLimit,
+		PriceTrailing: PriceTrailing,
+		PriceOcoStop:  PriceOcoStop,
+		OcoOrder:      o.Type == models.OrderTypeOCO,
+		Hidden:        o.Hidden,
+		PostOnly:      o.PostOnly,
 		AffiliateCode: b.cfg.Exchanges.Bitfinex.Affiliate(),
 	}
 
-	b.log.Debugf("Submitting order: %#v", req)
-	err = b.ws.SubmitOrder(b.ctx, req)
+	b.log.Debugf("Submitting order: %#v, flags %d", req, flags)
+	err = b.submitOrder(req, flags)
 	if err != nil {
 		return nil, err
 	}
 
-	orderPipe := b.newWatcher(fmt.Sprint("bf_wait_order", orderClientID), models.EventOrderFilled, models.EventOrderNew, eventRequestFail)
+	// FOK, IOC and post only orders can be canceled at once
+	orderPipe := b.newWatcher(fmt.Sprint("bf_wait_order", orderClientID), models.EventOrderFilled, models.EventOrderNew,
+		models.EventOrderCancel, eventRequestFail)
 	defer b.watchers.Remove(fmt.Sprint("bf_wait_order", orderClientID))
 
 	Timout := time.NewTimer(3 * time.Second)
@@ -762,7 +974,7 @@ func (b *bitfinexWebsocket) PutOrder(o *models.PutOrder) (*models.Order, error)
 				if rr.Meta["order_id"] == o.InternalID {
 					return nil, errors.WrapMessage(rr.Err, rr.Msg)
 				}
-			case evt.Is(models.EventOrderFilled), evt.Is(models.EventOrderNew):
+			case evt.Is(models.EventOrderFilled), evt.Is(models.EventOrderNew), evt.Is(models.EventOrderCancel):
 				or := evt.Payload.(models.Order)
 				if or.InternalID == o.InternalID {
 					return &or, nil
@@ -775,6 +987,20 @@ func (b *bitfinexWebsocket) PutOrder(o *models.PutOrder) (*models.Order, error)
 	}
 }
 
+// submitOrder send new order request, flags not supported by api client are added to request
+func (b *bitfinexWebsocket) submitOrder(req *order.NewRequest, flags int) error {
+	if flags == 0 {
+		return b.ws.SubmitOrder(b.ctx, req)
+	}
+
+	socket, err := b.ws.GetAuthenticatedSocket()
+	if err != nil {
+		return err
+	}
+
+	return socket.Send(b.ctx, &flaggedOrderRequest{NewRequest: req, flags: flags})
+}
+
 func (b *bitfinexWebsocket) CancelOrder(o *models.Order) error {
 	if !b.ready {
 		return exchanges2.ErrNoConnect
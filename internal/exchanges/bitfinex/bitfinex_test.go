@@ -1,14 +1,21 @@
 package bitfinex
 
 import (
+	"DaruBot/internal/cache/candles"
 	"DaruBot/internal/config"
 	"DaruBot/internal/exchanges"
+	"DaruBot/internal/exchanges/record"
 	"DaruBot/internal/models"
 	"DaruBot/pkg/logger"
 	"DaruBot/pkg/watcher"
 	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/common"
+	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/order"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -396,3 +403,140 @@ func Test_BitfinexGetCandles(t *testing.T) {
 	finish()
 	time.Sleep(1 * time.Second)
 }
+
+func Test_aggregateCandle(t *testing.T) {
+	wManager := watcher.NewWatcherManager()
+	bf, err := newExchange(context.Background(), config.GetDefaultConfig(), wManager, logger.New(os.Stdout, logger.ErrorLevel), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipe := bf.newWatcher("candles", models.EventCandleState).Listen()
+
+	// subscriptions of 1m and 5m share stream of minute candles
+	bf.aggregated["1"] = &aggregatedCandles{symbol: "tBTCUSD"}
+	bf.aggregated["2"] = &aggregatedCandles{symbol: "tBTCUSD", aggregator: candles.NewAggregator(models.FiveMinutes)}
+	bf.aggregated["3"] = &aggregatedCandles{symbol: "tETHUSD", aggregator: candles.NewAggregator(models.FiveMinutes)}
+
+	start := time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		bf.aggregateCandle(&models.Candle{
+			Symbol:     "tBTCUSD",
+			Resolution: models.OneMinute,
+			Date:       start.Add(time.Duration(i) * time.Minute),
+			Open:       float64(i),
+			Close:      float64(i + 1),
+			High:       float64(i + 1),
+			Low:        float64(i),
+			Volume:     1,
+		})
+
+		select {
+		case evt := <-pipe:
+			c := evt.Payload.(models.Candle)
+			if c.Resolution != models.FiveMinutes || !c.Date.Equal(start) || c.Open != 0 || c.Close != float64(i+1) || c.Volume != float64(i+1) {
+				t.Fatalf("wrong candle %+v", c)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("candle %d not aggregated", i)
+		}
+	}
+
+	// bigger resolutions are not aggregated
+	bf.aggregateCandle(&models.Candle{Symbol: "tBTCUSD", Resolution: models.FiveMinutes, Date: start})
+	select {
+	case evt := <-pipe:
+		t.Fatalf("unexpected candle %+v", evt.Payload)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func Test_flaggedOrderRequest(t *testing.T) {
+	// ids are not representable by float64
+	req := &order.NewRequest{GID: 1<<53 + 1, CID: 1609459200123456789, Type: "LIMIT", Symbol: "tBTCUSD", Amount: -1, Price: 100, Hidden: true}
+
+	raw, err := json.Marshal(&flaggedOrderRequest{NewRequest: req, flags: orderFlagReduceOnly})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := make([]interface{}, 0)
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg) != 4 || msg[1] != "on" {
+		t.Fatalf("wrong message %s", raw)
+	}
+
+	pld := msg[3].(map[string]interface{})
+	if pld["flags"] != float64(common.OrderFlagHidden|orderFlagReduceOnly) || pld["amount"] != "-1" || pld["type"] != "LIMIT" {
+		t.Fatalf("wrong payload %s", raw)
+	}
+
+	expected := `[0,"on",null,{"gid":9007199254740993,"cid":1609459200123456789,"type":"LIMIT","symbol":"tBTCUSD","amount":"-1","price":"100","flags":1088}]`
+	if string(raw) != expected {
+		t.Fatalf("expected %s, got %s", expected, raw)
+	}
+}
+
+type fakeTransport struct {
+	msgs chan []byte
+	done chan error
+}
+
+func (f *fakeTransport) Connect() error                                  { return nil }
+func (f *fakeTransport) Send(ctx context.Context, msg interface{}) error { return nil }
+func (f *fakeTransport) Listen() <-chan []byte                           { return f.msgs }
+func (f *fakeTransport) Close()                                          { close(f.msgs) }
+func (f *fakeTransport) Done() <-chan error                              { return f.done }
+
+type fakeFactory struct {
+	t *fakeTransport
+}
+
+func (f *fakeFactory) Create() websocket.Asynchronous {
+	return f.t
+}
+
+func Test_recordTransport(t *testing.T) {
+	dir := t.TempDir()
+	inner := &fakeTransport{msgs: make(chan []byte, 2), done: make(chan error)}
+
+	f := newRecordFactory(&fakeFactory{t: inner}, dir, logger.New(os.Stdout, logger.ErrorLevel))
+	tr := f.Create()
+
+	msgs := []string{`{"event":"info","version":2,"platform":{"status":1}}`, `[10,[99,1,101,1,0,0,100,10,105,95]]`}
+	for _, m := range msgs {
+		inner.msgs <- []byte(m)
+	}
+
+	for _, m := range msgs {
+		select {
+		case msg := <-tr.Listen():
+			if string(msg) != m {
+				t.Fatalf("expected %s, got %s", m, msg)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("message not forwarded")
+		}
+	}
+
+	tr.Close()
+	f.close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "bitfinex_*.gz"))
+	if len(files) != 1 {
+		t.Fatalf("expected one record, got %v", files)
+	}
+
+	r, err := record.NewReader(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, m := range msgs {
+		if _, msg, err := r.Next(); err != nil || string(msg) != m {
+			t.Fatalf("expected recorded %s, got %s %v", m, msg, err)
+		}
+	}
+}
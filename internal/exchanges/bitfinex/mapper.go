@@ -5,12 +5,24 @@ import (
 	"DaruBot/internal/models/exchanges"
 	"DaruBot/pkg/errors"
 	"DaruBot/pkg/tools"
+	"encoding/json"
 	"fmt"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/candle"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/common"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/order"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/position"
 	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/ticker"
+	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/trade"
+	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/tradeexecutionupdate"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
+	"math"
+	"reflect"
+	"time"
+)
+
+const (
+	// orderFlagReduceOnly https://docs.bitfinex.com/docs/flag-values
+	orderFlagReduceOnly = 1024
 )
 
 func (b *bitfinexWebsocket) convertOrder(data interface{}) *models.Order {
@@ -49,6 +61,24 @@ func (b *bitfinexWebsocket) convertCandle(data *candle.Candle) *models.Candle {
 	return o
 }
 
+func (b *bitfinexWebsocket) convertTrade(data *trade.Trade) *models.Trade {
+	o, ok := bitfinexTradeToModel(data)
+	if !ok {
+		b.log.Errorf("cant cast trade to model %#v", data)
+		return nil
+	}
+	return o
+}
+
+func (b *bitfinexWebsocket) convertExecution(data *tradeexecutionupdate.TradeExecutionUpdate) *models.Execution {
+	o, ok := bitfinexExecutionToModel(data)
+	if !ok {
+		b.log.Errorf("cant cast trade execution to model %#v", data)
+		return nil
+	}
+	return o
+}
+
 func bitfinexOrderToModel(or interface{}) (*models.Order, bool) {
 	var o order.Order
 
@@ -87,12 +117,21 @@ func bitfinexOrderToModel(or interface{}) (*models.Order, bool) {
 		rs.Type = models.OrderTypeStop
 	case "STOP LIMIT", "EXCHANGE STOP LIMIT":
 		rs.Type = models.OrderTypeStopLimit
-	//case "TRAILING STOP", "EXCHANGE TRAILING STOP":
-	//	rs.Type = models.OrderTypeTrailingStop
+	case "TRAILING STOP", "EXCHANGE TRAILING STOP":
+		rs.Type = models.OrderTypeTrailingStop
+	case "FOK", "EXCHANGE FOK":
+		rs.Type = models.OrderTypeFOK
+	case "IOC", "EXCHANGE IOC":
+		rs.Type = models.OrderTypeIOC
 	default:
 		rs.Type = models.OrderTypeUnknown
 	}
 
+	// limit leg of OCO, stop leg stays stop order
+	if rs.Type == models.OrderTypeLimit && o.Flags&int64(common.OrderFlagOCO) != 0 {
+		rs.Type = models.OrderTypeOCO
+	}
+
 	rs.Meta["Exchange"] = exchanges.ExchangeTypeBitfinex
 	rs.Meta["Status"] = o.Status
 	rs.Meta["Type"] = o.Type
@@ -101,6 +140,33 @@ func bitfinexOrderToModel(or interface{}) (*models.Order, bool) {
 	return rs, true
 }
 
+// flaggedOrderRequest new order request with flags which are not supported by api client (e.g. reduce only)
+type flaggedOrderRequest struct {
+	*order.NewRequest
+	flags int
+}
+
+// MarshalJSON marshal payload of api client with added flags, so ids, numbers and order of fields are kept
+func (r *flaggedOrderRequest) MarshalJSON() ([]byte, error) {
+	pld := r.EnrichedPayload()
+
+	v := reflect.New(reflect.TypeOf(pld)).Elem()
+	v.Set(reflect.ValueOf(pld))
+
+	flags := v.FieldByName("Flags")
+	if !flags.IsValid() || !flags.CanSet() {
+		return nil, errors.New("FLAGS ARE NOT SUPPORTED BY ORDER REQUEST")
+	}
+	flags.SetInt(flags.Int() | int64(r.flags))
+
+	raw, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf("[0, \"on\", null, %s]", string(raw))), nil
+}
+
 func bitfinexPositionToModel(po interface{}) (*models.Position, bool) {
 	var p position.Position
 
@@ -180,6 +246,44 @@ func bitfinexCandleToModel(c *candle.Candle) (*models.Candle, bool) {
 	return rs, true
 }
 
+func bitfinexTradeToModel(t *trade.Trade) (*models.Trade, bool) {
+	// funding trades have rate instead of price
+	if t.Price == 0 {
+		return nil, false
+	}
+
+	rs := &models.Trade{
+		ID:     fmt.Sprint(t.ID),
+		Symbol: t.Pair,
+		Date:   tools.TimeFromMilliseconds(t.MTS),
+		Price:  t.Price,
+		Amount: t.Amount,
+	}
+
+	return rs, true
+}
+
+// bitfinexExecutionToModel fee of bitfinex is negative when paid
+func bitfinexExecutionToModel(te *tradeexecutionupdate.TradeExecutionUpdate) (*models.Execution, bool) {
+	if te.OrderID == 0 {
+		return nil, false
+	}
+
+	rs := &models.Execution{
+		ID:          fmt.Sprint(te.ID),
+		OrderID:     fmt.Sprint(te.OrderID),
+		Symbol:      te.Pair,
+		Date:        tools.TimeFromMilliseconds(te.MTS),
+		Price:       te.ExecPrice,
+		Amount:      te.ExecAmount,
+		Fee:         -te.Fee,
+		FeeCurrency: te.FeeCurrency,
+		Maker:       te.Maker == 1,
+	}
+
+	return rs, true
+}
+
 func candleResolutionToBitfinex(c models.CandleResolution) (common.CandleResolution, error) {
 	switch c {
 	case models.OneMinute:
@@ -237,3 +341,57 @@ func candleBitfinexResolutionToModel(c common.CandleResolution) models.CandleRes
 		panic(fmt.Errorf("could not convert string to resolution: %s", c))
 	}
 }
+
+// bitfinexBookToModel returns depth levels of each side of locally managed book
+func bitfinexBookToModel(ob *websocket.Orderbook, precision models.BookPrecision, depth int) *models.OrderBook {
+	rs := &models.OrderBook{
+		Symbol:    ob.Symbol(),
+		Precision: precision,
+		Bids:      make([]models.BookLevel, 0, depth),
+		Asks:      make([]models.BookLevel, 0, depth),
+		Date:      time.Now(),
+	}
+
+	for _, l := range ob.Bids() {
+		if len(rs.Bids) == depth {
+			break
+		}
+		rs.Bids = append(rs.Bids, models.BookLevel{Price: l.Price, Amount: math.Abs(l.Amount), Count: l.Count})
+	}
+	for _, l := range ob.Asks() {
+		if len(rs.Asks) == depth {
+			break
+		}
+		rs.Asks = append(rs.Asks, models.BookLevel{Price: l.Price, Amount: math.Abs(l.Amount), Count: l.Count})
+	}
+
+	return rs
+}
+
+func bookPrecisionToBitfinex(p models.BookPrecision) (common.BookPrecision, error) {
+	switch p {
+	case models.BookPrecisionP0:
+		return common.Precision0, nil
+	case models.BookPrecisionP1:
+		return common.Precision1, nil
+	case models.BookPrecisionP2:
+		return common.Precision2, nil
+	case models.BookPrecisionP3:
+		return common.Precision3, nil
+	case models.BookPrecisionP4:
+		// not declared by api client
+		return common.BookPrecision(p), nil
+	default:
+		return common.Precision0, fmt.Errorf("could not convert string to book precision: %s", p)
+	}
+}
+
+// bookLenToBitfinex returns the nearest supported number of price levels not less than depth
+func bookLenToBitfinex(depth int) int {
+	for _, l := range []int{1, 25, 100} {
+		if depth <= l {
+			return l
+		}
+	}
+	return 250
+}
@@ -0,0 +1,114 @@
+package bitfinex
+
+import (
+	"DaruBot/internal/exchanges/record"
+	"DaruBot/pkg/logger"
+	"fmt"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// recordFactory create websocket transports which record raw received messages of session,
+// messages of reconnected transports are written to the same file until session is closed
+type recordFactory struct {
+	factory websocket.AsynchronousFactory
+	dir     string
+	log     logger.Logger
+
+	mu       *sync.Mutex
+	recorder *record.Recorder
+}
+
+func newRecordFactory(factory websocket.AsynchronousFactory, dir string, lg logger.Logger) *recordFactory {
+	return &recordFactory{
+		factory: factory,
+		dir:     dir,
+		log:     lg,
+		mu:      &sync.Mutex{},
+	}
+}
+
+func (f *recordFactory) Create() websocket.Asynchronous {
+	return &recordTransport{
+		Asynchronous: f.factory.Create(),
+		factory:      f,
+		out:          make(chan []byte),
+		stop:         make(chan struct{}),
+		once:         &sync.Once{},
+		closeOnce:    &sync.Once{},
+	}
+}
+
+func (f *recordFactory) write(t time.Time, msg []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.recorder == nil {
+		path := filepath.Join(f.dir, fmt.Sprintf("bitfinex_%s.gz", t.Format("20060102_150405")))
+
+		r, err := record.NewRecorder(path)
+		if err != nil {
+			f.log.Error("could not record session", err)
+			return
+		}
+		f.log.Infof("recording session to %s", path)
+		f.recorder = r
+	}
+
+	if err := f.recorder.Write(t, msg); err != nil {
+		f.log.Error("record message", err)
+	}
+}
+
+// close file of session, the next message starts new one
+func (f *recordFactory) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.recorder == nil {
+		return
+	}
+	if err := f.recorder.Close(); err != nil {
+		f.log.Error("close session record", err)
+	}
+	f.recorder = nil
+}
+
+type recordTransport struct {
+	websocket.Asynchronous
+	factory *recordFactory
+
+	out       chan []byte
+	stop      chan struct{}
+	once      *sync.Once
+	closeOnce *sync.Once
+}
+
+// Listen forward received messages after they are recorded
+func (t *recordTransport) Listen() <-chan []byte {
+	t.once.Do(func() {
+		go t.forward(t.Asynchronous.Listen())
+	})
+	return t.out
+}
+
+func (t *recordTransport) forward(in <-chan []byte) {
+	for msg := range in {
+		t.factory.write(time.Now(), msg)
+
+		select {
+		case t.out <- msg:
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *recordTransport) Close() {
+	t.closeOnce.Do(func() {
+		close(t.stop)
+	})
+	t.Asynchronous.Close()
+}
@@ -0,0 +1,529 @@
+package bitfinex
+
+import (
+	"DaruBot/internal/config"
+	exchanges2 "DaruBot/internal/exchanges"
+	"DaruBot/internal/exchanges/record"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/bitfinexcom/bitfinex-api-go/pkg/models/common"
+	"github.com/bitfinexcom/bitfinex-api-go/v2/websocket"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	replayCredentials = "replay"
+	// requests of client are answered from queue, client may send requests while it handles replayed message
+	replayRequestsCapacity = 100
+	// subscription failed code of api
+	replaySubscribeErrorCode = 10300
+)
+
+var (
+	ErrNotRecorded = errors.New("REQUEST IS NOT RECORDED")
+
+	replayInfo = []byte(`{"event":"info","version":2,"platform":{"status":1}}`)
+)
+
+type replaySession struct {
+	*bitfinexWebsocket
+	transport *replayTransport
+}
+
+// NewReplaySession returns exchange streaming recorded session (see Bitfinex.Record) to api client
+// instead of websocket, so replayed messages are handled as live ones. All recorded channels are subscribed
+// on connect and streamed with original timing divided by speed, 0 replays without delays.
+// Events are emitted by name, requests of REST api are not available
+func NewReplaySession(ctx context.Context, wManager *watcher.Manager, lg logger.Logger, name string, path string, speed float64) (exchanges2.CryptoExchange, error) {
+	return newReplaySession(ctx, wManager, lg, name, path, speed)
+}
+
+func newReplaySession(ctx context.Context, wManager *watcher.Manager, lg logger.Logger, name string, path string, speed float64) (*replaySession, error) {
+	t, err := newReplayTransport(path, speed, lg.WithPrefix("exchange", name))
+	if err != nil {
+		return nil, err
+	}
+
+	p := newParameters(lg)
+	p.AutoReconnect = false
+	p.ResubscribeOnReconnect = false
+	// all channels of record are served by one transport, with authenticated channel
+	p.CapacityPerConnection = len(t.subscribed) + 2
+	// timing of record may be slowed down, gaps of messages must not disconnect
+	p.HeartbeatTimeout = time.Hour
+
+	ws := websocket.NewWithParamsAsyncFactory(p, t).Credentials(replayCredentials, replayCredentials)
+
+	b, err := newExchange(ctx, config.Configurations{}, wManager, lg, name, ws)
+	if err != nil {
+		return nil, err
+	}
+
+	return &replaySession{
+		bitfinexWebsocket: b,
+		transport:         t,
+	}, nil
+}
+
+func (s *replaySession) Connect() error {
+	if s.ready {
+		return nil
+	}
+
+	if err := s.bitfinexWebsocket.Connect(); err != nil {
+		return err
+	}
+
+	for _, sub := range s.transport.subscribed {
+		if err := s.subscribe(sub); err != nil {
+			s.log.Warn("recorded channel is not replayed", sub.Channel, sub.Symbol, sub.Key, err)
+		}
+	}
+
+	s.transport.begin()
+
+	return nil
+}
+
+// subscribe recorded channel
+func (s *replaySession) subscribe(sub websocket.SubscribeEvent) error {
+	switch sub.Channel {
+	case websocket.ChanTicker:
+		_, err := s.SubscribeTicker(sub.Symbol)
+		return err
+	case websocket.ChanTrades:
+		_, err := s.SubscribeTrades(sub.Symbol)
+		return err
+	case websocket.ChanCandles:
+		// key is trade:1m:tBTCUSD
+		parts := strings.Split(sub.Key, ":")
+		if len(parts) < 3 {
+			return errors.WrapMessage(exchanges2.ErrInvalidRequestParams, sub.Key)
+		}
+
+		res, err := common.CandleResolutionFromString(parts[1])
+		if err != nil {
+			return err
+		}
+
+		_, err = s.SubscribeCandles(parts[2], candleBitfinexResolutionToModel(res))
+		return err
+	case websocket.ChanBook:
+		depth, err := strconv.Atoi(sub.Len)
+		if err != nil {
+			return err
+		}
+
+		_, err = s.SubscribeOrderBook(sub.Symbol, models.BookPrecision(sub.Precision), depth)
+		return err
+	default:
+		return errors.WrapMessage(exchanges2.ErrInvalidRequestParams, sub.Channel)
+	}
+}
+
+func (s *replaySession) GetTicker(symbol string) (*models.Ticker, error) {
+	return nil, ErrNotRecorded
+}
+
+func (s *replaySession) GetCandles(symbol string, resolution models.CandleResolution, start time.Time, end time.Time) (*models.Candles, error) {
+	return nil, ErrNotRecorded
+}
+
+func (s *replaySession) GetLastCandle(symbol string, resolution models.CandleResolution) (*models.Candle, error) {
+	return nil, ErrNotRecorded
+}
+
+// replayTransport serve recorded messages to api client instead of websocket,
+// requests of client are answered by recorded events
+type replayTransport struct {
+	path  string
+	speed float64
+	log   logger.Logger
+
+	info       []byte
+	auth       []byte
+	subscribed []websocket.SubscribeEvent
+
+	mu     *sync.Mutex
+	active map[int64]bool // acknowledged channels
+
+	requests  chan []byte
+	out       chan []byte
+	runOnce   *sync.Once
+	start     chan struct{}
+	startOnce *sync.Once
+	stop      chan struct{}
+	stopOnce  *sync.Once
+	done      chan error
+}
+
+// replayEvent is event message of api
+type replayEvent struct {
+	Event string `json:"event"`
+}
+
+// replayRequest is request of api client, subscription or unsubscribe
+type replayRequest struct {
+	websocket.SubscriptionRequest
+	ChanID int64 `json:"chanId"`
+}
+
+// newReplayTransport read connection events and subscriptions of record
+func newReplayTransport(path string, speed float64, lg logger.Logger) (*replayTransport, error) {
+	r, err := record.NewReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	if speed < 0 {
+		speed = 0
+	}
+
+	t := &replayTransport{
+		path:      path,
+		speed:     speed,
+		log:       lg,
+		info:      replayInfo,
+		mu:        &sync.Mutex{},
+		active:    make(map[int64]bool),
+		requests:  make(chan []byte, replayRequestsCapacity),
+		out:       make(chan []byte),
+		runOnce:   &sync.Once{},
+		start:     make(chan struct{}),
+		startOnce: &sync.Once{},
+		stop:      make(chan struct{}),
+		stopOnce:  &sync.Once{},
+		done:      make(chan error, 1),
+	}
+
+	info := false
+
+	for {
+		_, msg, err := r.Next()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !isEvent(msg) {
+			continue
+		}
+
+		e := replayEvent{}
+		if err := json.Unmarshal(msg, &e); err != nil {
+			return nil, err
+		}
+
+		switch e.Event {
+		case "info":
+			if !info {
+				t.info = msg
+				info = true
+			}
+		case "auth":
+			if t.auth == nil {
+				t.auth = msg
+			}
+		case "subscribed":
+			sub := websocket.SubscribeEvent{}
+			if err := json.Unmarshal(msg, &sub); err != nil {
+				return nil, err
+			}
+			if _, ok := t.findSubscribed(sub.Channel, sub.Symbol, sub.Key, sub.Precision, sub.Len); !ok {
+				t.subscribed = append(t.subscribed, sub)
+			}
+		}
+	}
+}
+
+func isEvent(msg []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(msg), []byte("{"))
+}
+
+// Create implements websocket.AsynchronousFactory, record is served by one transport
+func (t *replayTransport) Create() websocket.Asynchronous {
+	return t
+}
+
+func (t *replayTransport) Connect() error {
+	t.runOnce.Do(func() {
+		go t.run()
+	})
+	return nil
+}
+
+// begin streaming of recorded channels, requests sent before are answered first
+func (t *replayTransport) begin() {
+	t.startOnce.Do(func() {
+		close(t.start)
+	})
+}
+
+func (t *replayTransport) run() {
+	if !t.send(t.info) {
+		return
+	}
+
+	// connection is authenticated and channels are subscribed before replay
+	for started := false; !started; {
+		select {
+		case msg := <-t.requests:
+			if !t.answer(msg) {
+				return
+			}
+		case <-t.start:
+			started = true
+		case <-t.stop:
+			return
+		}
+	}
+
+	t.replay()
+
+	// requests are answered after replay is finished
+	for {
+		select {
+		case msg := <-t.requests:
+			if !t.answer(msg) {
+				return
+			}
+		case <-t.stop:
+			return
+		}
+	}
+}
+
+func (t *replayTransport) replay() {
+	r, err := record.NewReader(t.path)
+	if err != nil {
+		t.log.Error("replay stopped", err)
+		return
+	}
+	defer r.Close()
+
+	var first time.Time
+	start := time.Now()
+
+	for {
+		tm, msg, err := r.Next()
+		if err == io.EOF {
+			t.log.Info("replay finished")
+			return
+		}
+		if err != nil {
+			t.log.Error("replay stopped", err)
+			return
+		}
+
+		if !t.isReplayed(msg) {
+			continue
+		}
+
+		if first.IsZero() {
+			first = tm
+		}
+
+		// wait original delay of message since first message, requests are answered meanwhile
+		if t.speed > 0 {
+			timer := time.NewTimer(time.Duration(float64(tm.Sub(first))/t.speed) - time.Since(start))
+
+		wait:
+			for {
+				select {
+				case <-timer.C:
+					break wait
+				case req := <-t.requests:
+					if !t.answer(req) {
+						timer.Stop()
+						return
+					}
+				case <-t.stop:
+					timer.Stop()
+					return
+				}
+			}
+		}
+
+		// pending requests are answered before message
+		for pending := true; pending; {
+			select {
+			case req := <-t.requests:
+				if !t.answer(req) {
+					return
+				}
+			default:
+				pending = false
+			}
+		}
+
+		if !t.send(msg) {
+			return
+		}
+	}
+}
+
+// isReplayed returns true for messages of subscribed channels, events are answered to requests
+func (t *replayTransport) isReplayed(msg []byte) bool {
+	if isEvent(msg) {
+		return false
+	}
+
+	raw := make([]json.RawMessage, 0, 3)
+	if err := json.Unmarshal(msg, &raw); err != nil || len(raw) == 0 {
+		return false
+	}
+
+	chanID, err := strconv.ParseInt(string(raw[0]), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active[chanID]
+}
+
+func (t *replayTransport) send(msg []byte) bool {
+	select {
+	case t.out <- msg:
+		return true
+	case <-t.stop:
+		return false
+	}
+}
+
+// answer request by recorded event
+func (t *replayTransport) answer(msg []byte) bool {
+	req := replayRequest{}
+	if err := json.Unmarshal(msg, &req); err != nil {
+		t.log.Error("replay request", err)
+		return true
+	}
+
+	var rs interface{}
+
+	switch req.Event {
+	case "auth":
+		auth := websocket.AuthEvent{Event: "auth", Status: "OK"}
+		if t.auth != nil {
+			if err := json.Unmarshal(t.auth, &auth); err != nil {
+				t.log.Error("replay auth", err)
+			}
+		}
+		auth.SubID = req.SubID
+
+		t.activate(auth.ChanID, true)
+		rs = auth
+	case websocket.EventSubscribe:
+		sub, ok := t.findSubscribed(req.Channel, req.Symbol, req.Key, req.Precision, req.Len)
+		if !ok {
+			rs = struct {
+				Event string `json:"event"`
+				websocket.ErrorEvent
+			}{
+				Event: "error",
+				ErrorEvent: websocket.ErrorEvent{
+					Code:    replaySubscribeErrorCode,
+					Message: "channel is not recorded",
+					SubID:   req.SubID,
+					Channel: req.Channel,
+					Symbol:  req.Symbol,
+					Key:     req.Key,
+				},
+			}
+			break
+		}
+		sub.SubID = req.SubID
+
+		t.activate(sub.ChanID, true)
+		rs = struct {
+			Event string `json:"event"`
+			websocket.SubscribeEvent
+		}{Event: "subscribed", SubscribeEvent: sub}
+	case "unsubscribe":
+		t.activate(req.ChanID, false)
+		rs = struct {
+			Event string `json:"event"`
+			websocket.UnsubscribeEvent
+		}{Event: "unsubscribed", UnsubscribeEvent: websocket.UnsubscribeEvent{Status: "OK", ChanID: req.ChanID}}
+	default:
+		return true
+	}
+
+	bs, err := json.Marshal(rs)
+	if err != nil {
+		t.log.Error("replay request", err)
+		return true
+	}
+
+	return t.send(bs)
+}
+
+func (t *replayTransport) activate(chanID int64, active bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if active {
+		t.active[chanID] = true
+	} else {
+		delete(t.active, chanID)
+	}
+}
+
+func (t *replayTransport) findSubscribed(channel, symbol, key, precision, length string) (websocket.SubscribeEvent, bool) {
+	for _, s := range t.subscribed {
+		if s.Channel == channel && s.Symbol == symbol && s.Key == key && s.Precision == precision && s.Len == length {
+			return s, true
+		}
+	}
+	return websocket.SubscribeEvent{}, false
+}
+
+// Send queue request to be answered, messages of orders are not answered
+func (t *replayTransport) Send(ctx context.Context, msg interface{}) error {
+	bs, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	if !isEvent(bs) {
+		return nil
+	}
+
+	select {
+	case t.requests <- bs:
+		return nil
+	case <-t.stop:
+		return exchanges2.ErrNoConnect
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *replayTransport) Listen() <-chan []byte {
+	return t.out
+}
+
+func (t *replayTransport) Close() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+		close(t.done)
+	})
+}
+
+func (t *replayTransport) Done() <-chan error {
+	return t.done
+}
@@ -19,6 +19,8 @@ var (
 
 	ErrPositionNotFound = errors.New("POSITION NOT FOUND")
 
+	ErrOrderBookNotSubscribed = errors.New("ORDER BOOK NOT SUBSCRIBED")
+
 	ErrSymbolIncorrect    = errors.New("PAIR INCORRECT")
 	ErrSymbolNotSupported = errors.New("PAIR NOT SUPPORTED")
 )
@@ -25,8 +25,16 @@ type CryptoExchange interface {
 	GetSubscriptions() *models.Subscriptions
 	SubscribeTicker(symbol string) (subID string, err error)
 	SubscribeCandles(symbol string, resolution models.CandleResolution) (subID string, err error)
+	// SubscribeOrderBook keep local book of symbol with depth levels of each side up to date,
+	// every change emitted as EventOrderBookUpdate
+	SubscribeOrderBook(symbol string, precision models.BookPrecision, depth int) (subID string, err error)
+	// SubscribeTrades every public trade of symbol emitted as EventTrade
+	SubscribeTrades(symbol string) (subID string, err error)
 	Unsubscribe(subID string) error
 
+	// GetOrderBook returns snapshot of subscribed book
+	GetOrderBook(symbol string) (*models.OrderBook, error)
+
 	/* Tools */
 	CheckSymbol(symbol string, margin bool) error
 
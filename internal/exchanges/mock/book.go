@@ -0,0 +1,57 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"math"
+	"time"
+)
+
+const (
+	bookSpreadPct = 0.0005 // spread of synthetic book relative to price
+	bookStepPct   = 0.0002 // distance between levels relative to price on P0 precision
+)
+
+// bookParams of subscribed synthetic book
+type bookParams struct {
+	precision models.BookPrecision
+	depth     int
+}
+
+// syntheticBook returns book of depth levels on each side around price. Levels are wider on
+// less precise books, amount of level is part of candle volume and grows with distance from price
+func syntheticBook(symbol string, params bookParams, price, volume float64, date time.Time) *models.OrderBook {
+	rs := &models.OrderBook{
+		Symbol:    symbol,
+		Precision: params.precision,
+		Bids:      make([]models.BookLevel, 0, params.depth),
+		Asks:      make([]models.BookLevel, 0, params.depth),
+		Date:      date,
+	}
+
+	step := price * bookStepPct * math.Pow(10, float64(bookPrecisionLevel(params.precision)))
+	half := price * bookSpreadPct / 2
+	base := math.Max(volume, 1) / float64(2*params.depth)
+
+	for i := 0; i < params.depth; i++ {
+		amount := base * (1 + float64(i)/float64(params.depth))
+		rs.Bids = append(rs.Bids, models.BookLevel{Price: price - half - float64(i)*step, Amount: amount, Count: int64(i + 1)})
+		rs.Asks = append(rs.Asks, models.BookLevel{Price: price + half + float64(i)*step, Amount: amount, Count: int64(i + 1)})
+	}
+
+	return rs
+}
+
+func bookPrecisionLevel(p models.BookPrecision) int {
+	switch p {
+	case models.BookPrecisionP1:
+		return 1
+	case models.BookPrecisionP2:
+		return 2
+	case models.BookPrecisionP3:
+		return 3
+	case models.BookPrecisionP4:
+		return 4
+	default:
+		return 0
+	}
+}
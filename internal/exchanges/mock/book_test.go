@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSyntheticBook(t *testing.T) {
+	date := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	ob := syntheticBook("BTCUSD", bookParams{precision: models.BookPrecisionP0, depth: 10}, 10000, 20, date)
+	if len(ob.Bids) != 10 || len(ob.Asks) != 10 {
+		t.Fatalf("expected 10 levels, got %d bids and %d asks", len(ob.Bids), len(ob.Asks))
+	}
+	if math.Abs(ob.Spread()-10000*bookSpreadPct) > 1e-9 || ob.Mid() != 10000 {
+		t.Fatalf("wrong spread %v or mid %v", ob.Spread(), ob.Mid())
+	}
+
+	for i := 1; i < 10; i++ {
+		if ob.Bids[i].Price >= ob.Bids[i-1].Price || ob.Asks[i].Price <= ob.Asks[i-1].Price {
+			t.Fatalf("levels not sorted from the best price at %d", i)
+		}
+		if ob.Bids[i].Amount <= ob.Bids[i-1].Amount {
+			t.Fatalf("amount not grows with distance at %d", i)
+		}
+	}
+
+	// all asks available to buy up to the last level
+	all := 0.0
+	for _, l := range ob.Asks {
+		all = all + l.Amount
+	}
+	if got := ob.Liquidity(1, ob.Asks[9].Price); math.Abs(got-all) > 1e-9 {
+		t.Fatalf("expected liquidity %v, got %v", all, got)
+	}
+	if got := ob.Liquidity(-1, ob.Bids[0].Price); got != ob.Bids[0].Amount {
+		t.Fatalf("expected liquidity %v, got %v", ob.Bids[0].Amount, got)
+	}
+
+	// less precise book is wider
+	wide := syntheticBook("BTCUSD", bookParams{precision: models.BookPrecisionP2, depth: 10}, 10000, 20, date)
+	if wide.Asks[9].Price-wide.Asks[0].Price <= ob.Asks[9].Price-ob.Asks[0].Price {
+		t.Fatal("P2 book not wider than P0")
+	}
+}
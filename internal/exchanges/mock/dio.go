@@ -6,100 +6,220 @@ import (
 	"time"
 )
 
+const (
+	defaultStep = time.Minute
+)
+
+// TheWorld simulated clock, moves from `from` to `to` by step.
+// Every step sent to channel and next step made only after consumer acknowledge previous one,
+// so simulation runs as fast as consumer process ticks. If tick > 0 world also sleeps tick between steps.
 type TheWorld struct {
-	lock *sync.Mutex
+	mu   *sync.Mutex
+	cond *sync.Cond
 
 	timePass time.Duration
 	from     time.Time
 	to       time.Time
 	tick     time.Duration
+	step     time.Duration
 
 	ch   chan time.Time
-	wait chan interface{}
-
-	work bool
+	ack  chan struct{}
+	stop chan struct{}
+	done chan struct{}
+
+	running bool
+	stopped bool
+	paused  bool
+	frozen  int
 }
 
 func NewTheWorld(from, to time.Time, tick time.Duration) *TheWorld {
-	max := to.Sub(from)
-	lock := &sync.Mutex{}
+	mu := &sync.Mutex{}
 
-	stand := &TheWorld{
-		lock:     lock,
+	return &TheWorld{
+		mu:       mu,
+		cond:     sync.NewCond(mu),
 		timePass: 0,
 		from:     from,
 		to:       to,
 		tick:     tick,
-		ch:       nil,
-		wait:     make(chan interface{}, 1),
-		work:     false,
+		step:     defaultStep,
+		ack:      make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run start time, does nothing if already started
+func (w *TheWorld) Run() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running || w.stopped {
+		return
 	}
+	w.running = true
+
+	go w.loop()
+}
 
-	lock.Lock()
+func (w *TheWorld) loop() {
+	defer close(w.done)
+
+	for {
+		w.mu.Lock()
+		for (w.paused || w.frozen > 0) && !w.stopped {
+			w.cond.Wait()
+		}
+		if w.stopped || !w.from.Add(w.timePass).Before(w.to) {
+			w.mu.Unlock()
+			return
+		}
 
-	go func() {
-		<-stand.wait
-		fmt.Println("MUDA MUDA MUDA MUDA MUDA MUDA MUDA MUDA MUDA MUDA MUDA MUDA!!!!")
-		for {
-			if stand.work == false {
+		w.timePass = w.timePass + w.step
+		t := w.from.Add(w.timePass)
+		ch := w.ch
+		tick := w.tick
+		w.mu.Unlock()
+
+		if ch != nil {
+			select {
+			case ch <- t:
+			case <-w.stop:
 				return
 			}
-			lock.Lock()
-			time.Sleep(stand.tick)
-			if stand.timePass > max {
+
+			select {
+			case <-w.ack:
+			case <-w.stop:
 				return
 			}
-			stand.timePass = stand.timePass + time.Minute
-			if stand.ch != nil {
-				stand.ch <- stand.CurrentTime()
-			}
-			lock.Unlock()
 		}
-	}()
 
-	return stand
+		if tick > 0 {
+			timer := time.NewTimer(tick)
+			select {
+			case <-timer.C:
+			case <-w.stop:
+				timer.Stop()
+				return
+			}
+		}
+	}
 }
 
-func (w *TheWorld) Run() {
-	if w.wait != nil {
-		close(w.wait)
-		w.wait = nil
-		w.work = true
+// Ack acknowledge processing of last time sent to channel
+func (w *TheWorld) Ack() {
+	select {
+	case w.ack <- struct{}{}:
+	default:
 	}
-	w.TimeStart()
 }
 
+// Stop time forever, Done will be closed
 func (w *TheWorld) Stop() {
-	w.work = false
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped {
+		return
+	}
+	w.stopped = true
+	close(w.stop)
+	w.cond.Broadcast()
+
+	if !w.running {
+		close(w.done)
+	}
 }
 
+// Pause stop moving of time until Resume
+func (w *TheWorld) Pause() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.paused = true
+}
+
+func (w *TheWorld) Resume() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.paused = false
+	w.cond.Broadcast()
+}
+
+// Seek set current time, it must be between from and to
+func (w *TheWorld) Seek(t time.Time) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if t.Before(w.from) || t.After(w.to) {
+		return fmt.Errorf("time %s out of range %s - %s", t, w.from, w.to)
+	}
+	w.timePass = t.Sub(w.from)
+
+	return nil
+}
+
+// SetTick set real time slept between steps, 0 - no sleeping
 func (w *TheWorld) SetTick(tick time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	w.tick = tick
 }
 
+// SetStep set simulated time of one step
+func (w *TheWorld) SetStep(step time.Duration) {
+	if step <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.step = step
+}
+
 // TimeStart Zero
 func (w *TheWorld) TimeStart() {
-	if w.wait != nil {
-		return
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.frozen > 0 {
+		w.frozen--
 	}
-	w.lock.Unlock()
+	w.cond.Broadcast()
 }
 
-// TimeStop ZA WARUDO!!!!
+// TimeStop ZA WARUDO!!!! Time will not move until TimeStart, calls can be nested
 func (w *TheWorld) TimeStop() {
-	if w.wait != nil {
-		return
-	}
-	w.lock.Lock()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.frozen++
 }
 
 func (w *TheWorld) GetChan() <-chan time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	if w.ch == nil {
 		w.ch = make(chan time.Time, 1)
 	}
 	return w.ch
 }
 
+// Done closed when time reached the end or stopped
+func (w *TheWorld) Done() <-chan struct{} {
+	return w.done
+}
+
 func (w *TheWorld) CurrentTime() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	return w.from.Add(w.timePass)
 }
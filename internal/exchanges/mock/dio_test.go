@@ -0,0 +1,234 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTheWorldSteps(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	w := NewTheWorld(from, to, 0)
+	w.SetStep(5 * time.Minute)
+	ch := w.GetChan()
+	w.Run()
+
+	got := make([]time.Time, 0)
+	timeout := time.After(5 * time.Second)
+
+loop:
+	for {
+		select {
+		case tm := <-ch:
+			got = append(got, tm)
+			if !w.CurrentTime().Equal(tm) {
+				t.Fatalf("world moved before ack: %s != %s", w.CurrentTime(), tm)
+			}
+			w.Ack()
+		case <-w.Done():
+			break loop
+		case <-timeout:
+			t.Fatal("timeout")
+		}
+	}
+
+	if len(got) != 12 {
+		t.Fatalf("expected 12 steps, got %d", len(got))
+	}
+	for i, tm := range got {
+		if !tm.Equal(from.Add(time.Duration(i+1) * 5 * time.Minute)) {
+			t.Fatalf("wrong step %d: %s", i, tm)
+		}
+	}
+}
+
+func TestTheWorldPauseSeek(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	w := NewTheWorld(from, to, 0)
+	ch := w.GetChan()
+
+	w.Pause()
+	w.Run()
+
+	select {
+	case <-ch:
+		t.Fatal("time moved on pause")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := w.Seek(to.Add(time.Minute)); err == nil {
+		t.Fatal("expected out of range error")
+	}
+	if err := w.Seek(to.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	w.TimeStop()
+	w.Resume()
+
+	select {
+	case <-ch:
+		t.Fatal("time moved while stopped")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.TimeStart()
+
+	select {
+	case tm := <-ch:
+		if !tm.Equal(to) {
+			t.Fatalf("expected %s, got %s", to, tm)
+		}
+		w.Ack()
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout")
+	}
+
+	select {
+	case <-w.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("world not done")
+	}
+}
+
+func TestTheWorldStop(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := NewTheWorld(from, from.Add(time.Hour), 0)
+	w.GetChan()
+	w.Run()
+
+	// nobody acknowledge, stop must not hang
+	w.Stop()
+	w.Stop()
+
+	select {
+	case <-w.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("world not done")
+	}
+}
+
+func TestPlutosListen(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	w := NewTheWorld(from, from.Add(24*time.Hour), 0)
+	p := NewPlutos(5, Fees{}, currency, nil, nil, nil)
+
+	go p.Listen(w.GetChan(), w.Ack)
+	defer p.Stop()
+	w.Run()
+
+	ticks := 0
+	timeout := time.After(5 * time.Second)
+
+loop:
+	for {
+		select {
+		case data := <-p.GetChan():
+			if d, ok := data.(*TickDone); ok {
+				ticks++
+				d.Ack()
+			}
+		case <-w.Done():
+			break loop
+		case <-timeout:
+			t.Fatal("timeout")
+		}
+	}
+
+	if ticks != 24*60 {
+		t.Fatalf("expected %d ticks, got %d", 24*60, ticks)
+	}
+}
+
+func TestPlutosFullChannel(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	p := newPlutos(nil, currency)
+	p.SubscribeCandle(testPair, models.OneMinute)
+	for len(p.GetChan()) < cap(p.GetChan()) {
+		p.GetChan() <- struct{}{}
+	}
+
+	ticks := make(chan time.Time, 1)
+	go p.Listen(ticks, nil)
+	defer p.Stop()
+	ticks <- from.Add(time.Minute)
+
+	// consumer of full channel calls Plutos before reading it, Listen must not hold lock while blocked
+	done := make(chan struct{})
+	go func() {
+		p.GetOrders()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Plutos locked while channel is full")
+	}
+
+	for i := 0; i < cap(p.GetChan()); i++ {
+		<-p.GetChan()
+	}
+	select {
+	case data := <-p.GetChan():
+		if _, ok := data.(*Candle); !ok {
+			t.Fatalf("expected candle, got %T", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("candle not sent")
+	}
+}
+
+func TestPlutosProcessError(t *testing.T) {
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	errTicker := fmt.Errorf("no ticker")
+
+	p := newPlutos(nil, currency)
+	p.SetTickerFunc((&fakePrice{price: 100}).ticker)
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 1, Price: 90}); err != nil {
+		t.Fatal(err)
+	}
+	for len(p.GetChan()) > 0 {
+		<-p.GetChan()
+	}
+	p.SetTickerFunc(func(symbol string, curTime time.Time) (*models.Ticker, error) {
+		return nil, errTicker
+	})
+
+	ticks := make(chan time.Time, 1)
+	go p.Listen(ticks, nil)
+	defer p.Stop()
+
+	// without handler error is sent to channel
+	ticks <- from.Add(time.Minute)
+	select {
+	case data := <-p.GetChan():
+		if data != errTicker {
+			t.Fatalf("expected error, got %v", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error not sent")
+	}
+
+	handled := make(chan error, 1)
+	p.SetErrorHandler(func(err error) {
+		handled <- err
+	})
+	ticks <- from.Add(2 * time.Minute)
+	select {
+	case err := <-handled:
+		if err != errTicker {
+			t.Fatalf("expected %v, got %v", errTicker, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error not handled")
+	}
+}
@@ -12,29 +12,34 @@ import (
 	"DaruBot/pkg/watcher"
 	"context"
 	"github.com/markcheno/go-quote"
+	"sync"
 	"time"
 )
 
 var (
-	downloadCandles quoteFunc
-
 	supportEvents = watcher.EventsMap{
 		models.EventError,
 
 		models.EventTickerState,
 		models.EventCandleState,
+		models.EventOrderBookUpdate,
+		models.EventTrade,
+
+		models.EventOrderNew,
+		models.EventOrderFilled,
+		models.EventOrderCancel,
+		models.EventOrderPartiallyFilled,
+		models.EventOrderUpdate,
 
-		//models.EventOrderNew,
-		//models.EventOrderFilled,
-		//models.EventOrderCancel,
-		//models.EventOrderPartiallyFilled,
-		//models.EventOrderUpdate,
+		models.EventOwnTradeExecuted,
 
-		//models.EventPositionNew,
-		//models.EventPositionClosed,
-		//models.EventPositionUpdate,
+		models.EventPositionNew,
+		models.EventPositionClosed,
+		models.EventPositionUpdate,
 
-		//models.EventWalletUpdate,
+		models.EventWalletUpdate,
+
+		models.EventTickDone,
 	}
 )
 
@@ -43,10 +48,9 @@ type (
 )
 
 type exchange struct {
-	market    string
-	quoteFunc quoteFunc
-	dio       *TheWorld
-	plutos    *Plutos
+	source QuoteSource
+	dio    *TheWorld
+	plutos *Plutos
 
 	ctx context.Context
 	log logger.Logger
@@ -60,38 +64,36 @@ type exchange struct {
 
 	lastUpdate    time.Time
 	subscriptions models.Subscriptions
+	books         sync.Map // symbol - bookParams
+	syncTicks     bool
 }
 
 func NewExchangeMock(ctx context.Context,
 	wManager *watcher.Manager,
 	lg logger.Logger,
 	cfg config.Configurations,
-	market string, candlesCache *candles.Cache, quoteF quoteFunc,
+	source QuoteSource, candlesCache *candles.Cache,
 	stand *TheWorld, plutos *Plutos) (exchanges2.CryptoExchange, error) {
-	downloadCandles = quoteF
-	return newExchangeMock(ctx, wManager, lg, cfg, market, candlesCache, stand, plutos)
+	return newExchangeMock(ctx, wManager, lg, cfg, source, candlesCache, stand, plutos)
 }
 
 func newExchangeMock(ctx context.Context,
 	wManager *watcher.Manager,
 	lg logger.Logger,
 	cfg config.Configurations,
-	market string, candlesCache *candles.Cache,
+	source QuoteSource, candlesCache *candles.Cache,
 	stand *TheWorld, plutos *Plutos) (*exchange, error) {
 
-	if !quote.ValidMarket(market) {
-		return nil, errors.New("market not supported")
-	}
-
 	err := wManager.RegisterEvents(exchanges.ExchangeTypeMock.String(), supportEvents)
 	if err != nil {
 		return nil, err
 	}
 
-	mc := candlesCache.GetMarket(market, downloadQuote)
+	mc := candlesCache.GetMarket(source.Name(), source.Load)
+	mc.SetAggregate(cfg.Exchanges.Mock.Aggregate)
 
 	rs := &exchange{
-		market:        market,
+		source:        source,
 		lastUpdate:    time.Time{},
 		ctx:           ctx,
 		log:           lg.WithPrefix("exchange", "Mock"),
@@ -105,20 +107,33 @@ func newExchangeMock(ctx context.Context,
 		subscriptions: models.Subscriptions{},
 	}
 
+	plutos.SetTickerFunc(rs.getTicker)
+	plutos.SetCandleFunc(func(symbol string, curTime time.Time) (*models.Candle, error) {
+		return rs.getCandle(symbol, models.OneMinute, curTime)
+	})
+
 	return rs, nil
 }
 
+// SyncTicks make simulated time wait until listener (e.g. strategy) acknowledge EventTickDone,
+// so every tick is processed before next one. Must be called before Connect
+func SyncTicks(ex exchanges2.CryptoExchange) {
+	if e, ok := ex.(*exchange); ok {
+		e.syncTicks = true
+	}
+}
+
 func (e *exchange) Connect() error {
 	e.ready = true
 	close(e.readyChan)
 
-	e.work()
+	go e.work()
 
 	return nil
 }
 
 func (e *exchange) work() {
-	e.plutos.Listen(e.dio.GetChan())
+	go e.plutos.Listen(e.dio.GetChan(), e.dio.Ack)
 
 	e.dio.Run()
 
@@ -127,25 +142,54 @@ func (e *exchange) work() {
 		case data := <-e.plutos.GetChan():
 			switch d := data.(type) {
 			case *Ticker:
-				ticker, err := e.GetTicker(d.Symbol)
+				ticker, err := e.getTicker(d.Symbol, d.Time)
 				if err != nil {
 					e.emmit(models.EventError, err)
+					continue
 				}
-				e.emmit(models.EventTickerState, ticker)
+				e.emmit(models.EventTickerState, *ticker)
 			case *Candle:
-				cndls, err := e.GetCandles(d.Symbol, d.Res, d.Time.Add(-d.Res.ToDuration()), d.Time)
+				// candle closed at start of the next period
+				closed := d.Time.Add(-time.Second)
+				cndls, err := e.cacheCandles.Get(d.Res.PeriodStart(closed), closed, d.Symbol, d.Res)
 				if err != nil {
 					e.emmit(models.EventError, err)
+					continue
 				}
-				cndl := getCandle(cndls, d.Time)
-				e.emmit(models.EventCandleState, cndl)
-			case *models.Order:
-				// TODO new order (executed or placed)
-
-			case *models.WalletCurrency:
-				// TODO wallet state change
-
+				cndl := getCandle(cndls, closed)
+				if cndl == nil {
+					continue
+				}
+				e.emmit(models.EventCandleState, *cndl)
+			case *OrderBook:
+				ob, err := e.getOrderBook(d.Symbol, d.Time)
+				if err != nil {
+					e.emmit(models.EventError, err)
+					continue
+				}
+				e.emmit(models.EventOrderBookUpdate, *ob)
+			case *Trades:
+				trade, err := e.getTrade(d.Symbol, d.Time)
+				if err != nil {
+					e.emmit(models.EventError, err)
+					continue
+				}
+				e.emmit(models.EventTrade, *trade)
+			case *TickDone:
+				if e.syncTicks {
+					// listener acks after it processed all events emitted before
+					e.emmit(models.EventTickDone, models.NewTickDone(d.Time, d.Ack))
+					continue
+				}
+				d.Ack()
+			case error:
+				e.log.Error("process orders", d)
+				e.emmit(models.EventError, d)
 			default:
+				if head, payload, ok := PlutosEvent(d); ok {
+					e.emmit(head, payload)
+					continue
+				}
 				e.log.Tracef("unknown type %T", d)
 			}
 		case <-e.ctx.Done():
@@ -156,6 +200,72 @@ func (e *exchange) work() {
 
 }
 
+// PlutosEvent translate order, execution, position or wallet from Plutos channel to exchange event,
+// ok is false for other items
+func PlutosEvent(data interface{}) (head watcher.EventHead, payload interface{}, ok bool) {
+	switch d := data.(type) {
+	case *models.Order:
+		return orderEvent(d)
+	case *models.Execution:
+		return models.EventOwnTradeExecuted, *d, true
+	case *models.Position:
+		return positionEvent(d)
+	case *models.WalletCurrency:
+		return models.EventWalletUpdate, *d, true
+	default:
+		return nil, nil, false
+	}
+}
+
+func orderEvent(o *models.Order) (watcher.EventHead, interface{}, bool) {
+	order := *o
+	order.Meta = eventMeta(o.Meta)
+
+	if isNew, _ := o.Meta["new"].(bool); isNew {
+		return models.EventOrderNew, order, true
+	}
+
+	switch o.Meta["Status"] {
+	case orderStatusExecuted:
+		return models.EventOrderFilled, order, true
+	case orderStatusCanceled:
+		return models.EventOrderCancel, order, true
+	case orderStatusPartiallyFilled:
+		return models.EventOrderPartiallyFilled, order, true
+	case orderStatusActive:
+		return models.EventOrderUpdate, order, true
+	}
+
+	return nil, nil, false
+}
+
+func positionEvent(p *models.Position) (watcher.EventHead, interface{}, bool) {
+	pos := *p
+	pos.Meta = eventMeta(p.Meta)
+
+	if isNew, _ := p.Meta["new"].(bool); isNew {
+		return models.EventPositionNew, pos, true
+	}
+
+	if p.Meta["Status"] == positionStatusClosed {
+		return models.EventPositionClosed, pos, true
+	}
+
+	return models.EventPositionUpdate, pos, true
+}
+
+// eventMeta returns copy of meta without "new" flag, meta of item may be shared with Plutos state
+func eventMeta(meta map[string]interface{}) map[string]interface{} {
+	rs := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		if k == "new" {
+			continue
+		}
+		rs[k] = v
+	}
+	return rs
+}
+
 func (e *exchange) emmit(eventHead watcher.EventHead, data interface{}) {
 	err := e.watchers.Emmit(watcher.BuildEvent(eventHead, string(exchanges.ExchangeTypeMock), data))
 	if err != nil {
@@ -222,7 +332,7 @@ func (e *exchange) getTicker(symbol string, curTime time.Time) (*models.Ticker,
 
 	ticker := &models.Ticker{
 		Symbol:   symbol,
-		Price:    numbers.GetRandFloat(candle.Low, candle.High),
+		Price:    tickerPrice(candle, curTime),
 		Exchange: exchanges.ExchangeTypeMock,
 		State:    dayState,
 	}
@@ -255,6 +365,9 @@ func (e *exchange) getCandle(symbol string, res models.CandleResolution, dioTime
 	}
 
 	cndl := getCandle(cndls, dioTime)
+	if cndl == nil {
+		return nil, ErrNoData
+	}
 
 	return cndl, nil
 }
@@ -280,8 +393,8 @@ func (e *exchange) GetLastCandle(symbol string, resolution models.CandleResoluti
 	e.dio.TimeStop()
 	defer e.dio.TimeStart()
 
-	start := e.dio.CurrentTime().Add(-resolution.ToDuration())
 	end := e.dio.CurrentTime()
+	start := resolution.PeriodStart(end)
 
 	//e.log.Tracef("get last candle, from %s", end)
 
@@ -325,18 +438,81 @@ func (e *exchange) SubscribeCandles(symbol string, resolution models.CandleResol
 	return sid, nil
 }
 
+// SubscribeOrderBook synthetic book around ticker price, updated with tickers
+func (e *exchange) SubscribeOrderBook(symbol string, precision models.BookPrecision, depth int) (subID string, err error) {
+	if depth <= 0 {
+		return "", errors.WrapMessage(exchanges2.ErrInvalidRequestParams, "book depth must be positive")
+	}
+
+	e.dio.TimeStop()
+	defer e.dio.TimeStart()
+	sid := e.plutos.SubscribeOrderBook(symbol)
+	e.books.Store(symbol, bookParams{precision: precision, depth: depth})
+	e.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeOrderBook,
+	})
+	return sid, nil
+}
+
+func (e *exchange) GetOrderBook(symbol string) (*models.OrderBook, error) {
+	e.dio.TimeStop()
+	defer e.dio.TimeStart()
+
+	return e.getOrderBook(symbol, e.dio.CurrentTime())
+}
+
+func (e *exchange) getOrderBook(symbol string, curTime time.Time) (*models.OrderBook, error) {
+	params, ok := e.books.Load(symbol)
+	if !ok {
+		return nil, exchanges2.ErrOrderBookNotSubscribed
+	}
+
+	candle, err := e.getCandle(symbol, models.OneMinute, curTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return syntheticBook(symbol, params.(bookParams), tickerPrice(candle, curTime), candle.Volume, curTime), nil
+}
+
+// SubscribeTrades synthetic trade by ticker price emitted with tickers
+func (e *exchange) SubscribeTrades(symbol string) (subID string, err error) {
+	e.dio.TimeStop()
+	defer e.dio.TimeStart()
+	sid := e.plutos.SubscribeTrades(symbol)
+	e.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeTrades,
+	})
+	return sid, nil
+}
+
+func (e *exchange) getTrade(symbol string, curTime time.Time) (*models.Trade, error) {
+	candle, err := e.getCandle(symbol, models.OneMinute, curTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return syntheticTrade(candle, curTime), nil
+}
+
 func (e *exchange) Unsubscribe(subID string) error {
 	e.dio.TimeStop()
 	defer e.dio.TimeStart()
 	err := e.plutos.Unsubscribe(subID)
-	e.subscriptions.Delete(subID)
+	if sub := e.subscriptions.Delete(subID); sub != nil && sub.Type == models.SubTypeOrderBook {
+		e.books.Delete(sub.Symbol)
+	}
 	return err
 }
 
 func (e *exchange) CheckSymbol(symbol string, margin bool) error {
 	e.dio.TimeStop()
 	defer e.dio.TimeStart()
-	list, err := quote.NewMarketList(e.market)
+	list, err := e.source.Symbols()
 	if err != nil {
 		return err
 	}
@@ -386,17 +562,63 @@ func (e *exchange) HasUpdates(t time.Time) bool {
 }
 
 func (e *exchange) PutOrder(order *models.PutOrder) (*models.Order, error) {
-	panic("implement me")
+	e.dio.TimeStop()
+	defer e.dio.TimeStart()
+
+	o, err := e.plutos.PutOrder(order)
+	if err != nil {
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.lastUpdate = e.dio.CurrentTime()
+
+	return &o, nil
 }
 
+// UpdateOrder if price, priceStop and amount equals 0 - request do nothing
 func (e *exchange) UpdateOrder(orderID string, price float64, priceStop float64, amount float64) (*models.Order, error) {
-	panic("implement me")
+	e.dio.TimeStop()
+	defer e.dio.TimeStart()
+
+	o, err := e.plutos.UpdateOrder(orderID, price, priceStop, amount)
+	if err != nil {
+		if err == ErrOrderNotFound {
+			return nil, exchanges2.ErrOrderNotFound
+		}
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.lastUpdate = e.dio.CurrentTime()
+
+	return &o, nil
 }
 
 func (e *exchange) CancelOrder(order *models.Order) error {
-	panic("implement me")
+	e.dio.TimeStop()
+	defer e.dio.TimeStart()
+
+	_, err := e.plutos.CancelOrder(order.ID)
+	if err != nil {
+		if err == ErrOrderNotFound {
+			return exchanges2.ErrOrderNotFound
+		}
+		return errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.lastUpdate = e.dio.CurrentTime()
+
+	return nil
 }
 
 func (e *exchange) ClosePosition(position *models.Position) (*models.Position, error) {
-	panic("implement me")
+	e.dio.TimeStop()
+	defer e.dio.TimeStart()
+
+	pos, err := e.plutos.ClosePosition(position.ID)
+	if err != nil {
+		if err == ErrPositionNotFound {
+			return nil, exchanges2.ErrPositionNotFound
+		}
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.lastUpdate = e.dio.CurrentTime()
+
+	return &pos, nil
 }
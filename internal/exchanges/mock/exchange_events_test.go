@@ -0,0 +1,171 @@
+package mock
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/config"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTranslateEvents(t *testing.T) {
+	wManager := watcher.NewWatcherManager()
+	e := &exchange{
+		watchers: wManager,
+		log:      logger.New(os.Stdout, logger.ErrorLevel),
+	}
+
+	count := make(map[watcher.EventHead]int)
+	done := make(chan struct{})
+	pipe := wManager.MustNew("test", "", "").Listen()
+	go func() {
+		for evt := range pipe {
+			count[evt.EventHead]++
+		}
+		close(done)
+	}()
+
+	w := &models.Wallets{WalletType: models.WalletTypeMargin}
+	w.Update(&models.WalletCurrency{Name: currency, WalletType: models.WalletTypeMargin, Balance: 1000, Available: 1000})
+
+	price := &fakePrice{price: 100}
+	p := NewPlutos(5, Fees{}, currency, w, []models.Order{}, []models.Position{})
+	p.SetTickerFunc(price.ticker)
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 1, Price: 90, Margin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.UpdateOrder(o.ID, 95, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.CancelOrder(o.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 1, Margin: true}); err != nil {
+		t.Fatal(err)
+	}
+	p.mu.Lock()
+	err = p.processPositions()
+	p.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.ClosePosition(p.GetPositions()[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for len(p.GetChan()) > 0 {
+		if head, payload, ok := PlutosEvent(<-p.GetChan()); ok {
+			e.emmit(head, payload)
+		}
+	}
+
+	wManager.Remove("test")
+	<-done
+
+	want := map[watcher.EventHead]int{
+		models.EventOrderNew:         2,
+		models.EventOrderUpdate:      1,
+		models.EventOrderCancel:      1,
+		models.EventOrderFilled:      2,
+		models.EventOwnTradeExecuted: 2,
+		models.EventPositionNew:      1,
+		models.EventPositionUpdate:   1,
+		models.EventPositionClosed:   1,
+	}
+	for head, n := range want {
+		if count[head] != n {
+			t.Errorf("%s: expected %d events, got %d", head.GetEventName(), n, count[head])
+		}
+	}
+	if count[models.EventWalletUpdate] == 0 {
+		t.Error("wallet events not emitted")
+	}
+}
+
+func TestPlutosEventMeta(t *testing.T) {
+	meta := map[string]interface{}{"new": true, "Status": orderStatusActive}
+
+	head, payload, ok := PlutosEvent(&models.Order{ID: "1", Meta: meta})
+	if !ok || head != models.EventOrderNew {
+		t.Fatalf("wrong event %v", head)
+	}
+	if _, ok := payload.(models.Order).Meta["new"]; ok {
+		t.Fatalf("new flag is not removed from event")
+	}
+	if _, ok := meta["new"]; !ok {
+		t.Fatalf("meta of order is changed")
+	}
+
+	head, payload, ok = PlutosEvent(&models.Position{ID: "1", Meta: meta})
+	if !ok || head != models.EventPositionNew {
+		t.Fatalf("wrong event %v", head)
+	}
+	if _, ok := payload.(models.Position).Meta["new"]; ok || len(meta) != 2 {
+		t.Fatalf("wrong meta of position event %v, source %v", payload.(models.Position).Meta, meta)
+	}
+}
+
+func TestSyncTicks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lg := logger.New(os.Stdout, logger.ErrorLevel)
+	cache, err := candles.NewCandleCache(filepath.Join(dir, "candles.cache"), lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	from := time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC)
+	stand := NewTheWorld(from, from.Add(5*time.Minute), 0)
+	wManager := watcher.NewWatcherManager()
+
+	ex, err := newExchangeMock(ctx, wManager, lg, config.GetDefaultConfig(), newTestLocalSource(t), cache, stand, newPlutos(nil, currency))
+	if err != nil {
+		t.Fatal(err)
+	}
+	SyncTicks(ex)
+
+	pipe := wManager.MustNew("test", "", "", models.EventTickDone).Listen()
+
+	if err := ex.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	ticks := 0
+	for ticks < 5 {
+		select {
+		case evt := <-pipe:
+			td := evt.Payload.(models.TickDone)
+			// slow listener, time must not move until ack
+			time.Sleep(5 * time.Millisecond)
+			if now := stand.CurrentTime(); !now.Equal(td.Time) {
+				t.Fatalf("time moved to %s before ack of %s", now, td.Time)
+			}
+			ticks++
+			td.Ack()
+		case <-time.After(time.Second):
+			t.Fatalf("tick %d not emitted", ticks+1)
+		}
+	}
+
+	select {
+	case <-stand.Done():
+	case <-time.After(time.Second):
+		t.Fatal("time not finished")
+	}
+}
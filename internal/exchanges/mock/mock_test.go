@@ -42,7 +42,7 @@ func newCacheCandles(t *testing.T, lg logger.Logger) *cacheCandle {
 }
 
 func (c *cacheCandle) Stop() {
-	err := c.cache.SaveCache()
+	err := c.cache.Close()
 	if err != nil {
 		panic(err)
 	}
@@ -58,15 +58,15 @@ func newEx(t *testing.T, level logger.Level, from, to time.Time, p *Plutos) (*ex
 
 	cfg := config.GetDefaultConfig()
 
-	downloadCandles = quoteFrom
-
 	cache := newCacheCandles(t, lg)
 
 	if p == nil {
 		p = newPlutos(nil, currency)
 	}
 
-	mk, err := newExchangeMock(ctx, wManager, lg, cfg, market, cache.cache, stand, p)
+	source := &remoteSource{market: market, download: quoteFrom}
+
+	mk, err := newExchangeMock(ctx, wManager, lg, cfg, source, cache.cache, stand, p)
 	p.SetTickerFunc(mk.getTicker)
 
 	stop := func() {
@@ -4,6 +4,7 @@ import (
 	"DaruBot/internal/models"
 	"fmt"
 	"github.com/google/uuid"
+	"math"
 	"strings"
 	"sync"
 	"time"
@@ -11,18 +12,34 @@ import (
 
 type TickerFunc func(symbol string, curTime time.Time) (*models.Ticker, error)
 
+// Fees trading fees in percent of executed cost, limit orders filled as maker, market and stop orders as taker
+type Fees struct {
+	Maker float64
+	Taker float64
+}
+
 type Plutos struct {
 	SubscribeManager *subscribeManager
 	mu               *sync.Mutex
-	work             bool
+	stop             chan struct{}
+	stopOnce         *sync.Once
 	channel          chan interface{}
 	getTicker        TickerFunc
+	getCandle        CandleFunc
+	fill             FillModel
+	onError          func(err error)
+	pending          []interface{} // events collected under mu, sent to channel after unlock
+
+	processInterval time.Duration
+	balanceInterval time.Duration
+	balanceHistory  []BalanceSnapshot
 
 	maxLeverage uint8
-	taxFee      float64 // TODO implement
+	fees        Fees
 	wallets     *models.Wallets
 	positions   []models.Position
 	orders      []models.Order
+	history     []models.Order
 
 	currentTime time.Time
 
@@ -30,11 +47,17 @@ type Plutos struct {
 	currency string
 }
 
+const (
+	orderStatusActive          = "ACTIVE"
+	orderStatusExecuted        = "EXECUTED"
+	orderStatusPartiallyFilled = "PARTIALLY FILLED"
+)
+
 var (
 	ErrNotExecuted = fmt.Errorf("order not executed")
 )
 
-func NewPlutos(maxLeverage uint8, taxFee float64, currency string, w *models.Wallets, o []models.Order, p []models.Position) *Plutos {
+func NewPlutos(maxLeverage uint8, fees Fees, currency string, w *models.Wallets, o []models.Order, p []models.Position) *Plutos {
 	sm := &subscribeManager{
 		subs: []*subscription{},
 	}
@@ -42,13 +65,16 @@ func NewPlutos(maxLeverage uint8, taxFee float64, currency string, w *models.Wal
 	return &Plutos{
 		SubscribeManager: sm,
 		mu:               &sync.Mutex{},
-		work:             true,
+		stop:             make(chan struct{}),
+		stopOnce:         &sync.Once{},
 		channel:          make(chan interface{}, 100),
+		fill:             PerfectFill{},
+		processInterval:  time.Minute,
 
 		maxLeverage: maxLeverage,
 		wallets:     w,
 		positions:   p,
-		taxFee:      taxFee,
+		fees:        fees,
 		orders:      o,
 		currency:    currency,
 	}
@@ -58,42 +84,84 @@ func (p *Plutos) SetTickerFunc(tf TickerFunc) {
 	p.getTicker = tf
 }
 
+// SetCandleFunc set source of candles for fill model
+func (p *Plutos) SetCandleFunc(cf CandleFunc) {
+	p.getCandle = cf
+}
+
+func (p *Plutos) SetFillModel(fm FillModel) {
+	p.fill = fm
+}
+
+// SetErrorHandler set handler of orders and positions processing errors, called under lock of Plutos.
+// If not set errors are sent to channel
+func (p *Plutos) SetErrorHandler(f func(err error)) {
+	p.onError = f
+}
+
+// SetProcessInterval set how often orders and positions are checked, one minute by default
+func (p *Plutos) SetProcessInterval(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.processInterval = d
+}
+
 func (p *Plutos) GetChan() chan interface{} {
 	return p.channel
 }
 
-func (p *Plutos) Listen(ch <-chan time.Time) {
+// Listen process time ticks until Stop, after every tick TickDone pushed to channel with ack func
+func (p *Plutos) Listen(ch <-chan time.Time, ack func()) {
 	for {
 		select {
 		case t := <-ch:
 			p.mu.Lock()
-			p.SubscribeManager.trigger(t, p.channel)
+			p.SubscribeManager.trigger(t, p.emit)
 			p.currentTime = t
 
-			if checkResTiming(1*time.Minute, t) {
+			if checkResTiming(p.processInterval, t) {
 				if len(p.orders) > 0 {
 					if err := p.processOrders(); err != nil {
-						panic(err)
+						p.processError(err)
 					}
 				}
 				if len(p.positions) > 0 {
 					if err := p.processPositions(); err != nil {
-						panic(err)
+						p.processError(err)
 					}
 				}
-
+				if p.balanceInterval > 0 && checkResTiming(p.balanceInterval, t) {
+					p.snapshotBalance(t)
+				}
 			}
-			p.mu.Unlock()
-		default:
-			if p.work == false {
-				return
+			p.unlock()
+
+			if ack != nil {
+				p.channel <- &TickDone{Time: t, ack: ack}
 			}
+		case <-p.stop:
+			return
 		}
 	}
 }
 
+func (p *Plutos) processError(err error) {
+	if p.onError == nil {
+		p.emit(err)
+		return
+	}
+	p.onError(err)
+}
+
 func (p *Plutos) Stop() {
-	p.work = false
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
 }
 
 func (p *Plutos) GetOrders() []*models.Order {
@@ -151,10 +219,18 @@ func (p *Plutos) GetBalance(curTime time.Time) (*models.BalanceUSD, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	rs, _, err := p.getBalance(curTime)
+
+	return rs, err
+}
+
+// getBalance returns balance and value of assets and positions in market
+func (p *Plutos) getBalance(curTime time.Time) (*models.BalanceUSD, float64, error) {
 	rs := &models.BalanceUSD{
 		Total:    0,
 		NetWorth: 0,
 	}
+	invested := 0.0
 
 	ws := p.getWallets()
 
@@ -176,32 +252,51 @@ func (p *Plutos) GetBalance(curTime time.Time) (*models.BalanceUSD, error) {
 	for _, po := range pos {
 		ticker, err := p.getTicker(fmt.Sprintf("%s", po.Symbol), curTime)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		rs.NetWorth = rs.NetWorth + po.Amount*ticker.Price
+		rs.NetWorth = rs.NetWorth + (ticker.Price-po.Price)*po.Amount
+		invested = invested + math.Abs(po.Amount)*ticker.Price
 	}
 
 	for name, value := range mapCurs {
+		if value == 0 {
+			continue
+		}
 		ticker, err := p.getTicker(fmt.Sprintf("%s%s", name, p.currency), curTime)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		rs.NetWorth = rs.NetWorth + value*ticker.Price
+		invested = invested + value*ticker.Price
 	}
 
-	return rs, nil
+	return rs, invested, nil
 }
 
 func (p *Plutos) PutOrder(putOrder *models.PutOrder) (models.Order, error) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
+	defer p.unlock()
 
 	if putOrder.Amount == 0 {
 		return models.Order{}, fmt.Errorf("wrong amount")
 	}
-	if putOrder.Price == 0 && putOrder.Type != models.OrderTypeMarket {
+	if putOrder.Price == 0 && putOrder.Type != models.OrderTypeMarket && putOrder.Type != models.OrderTypeStop &&
+		putOrder.Type != models.OrderTypeTrailingStop {
 		return models.Order{}, fmt.Errorf("wrong price")
 	}
+	if putOrder.StopPrice == 0 && (putOrder.Type == models.OrderTypeStop || putOrder.Type == models.OrderTypeStopLimit ||
+		putOrder.Type == models.OrderTypeOCO) {
+		return models.Order{}, fmt.Errorf("stop price are not specified")
+	}
+	if putOrder.TrailingDistance <= 0 && putOrder.Type == models.OrderTypeTrailingStop {
+		return models.Order{}, fmt.Errorf("trailing distance are not specified")
+	}
+	if putOrder.PostOnly && putOrder.Type != models.OrderTypeLimit && putOrder.Type != models.OrderTypeOCO {
+		return models.Order{}, fmt.Errorf("post only supported by limit orders")
+	}
+	if putOrder.ReduceOnly && !putOrder.Margin {
+		return models.Order{}, fmt.Errorf("reduce only supported by margin orders")
+	}
 
 	o := models.Order{
 		ID:             uuid.Must(uuid.NewUUID()).String(),
@@ -214,153 +309,524 @@ func (p *Plutos) PutOrder(putOrder *models.PutOrder) (models.Order, error) {
 		AmountOriginal: putOrder.Amount,
 		Date:           p.currentTime,
 		Updated:        p.currentTime,
-		Meta:           map[string]interface{}{"stop_price": putOrder.StopPrice},
+		Meta: map[string]interface{}{
+			"stop_price":  putOrder.StopPrice,
+			"margin":      putOrder.Margin,
+			"post_only":   putOrder.PostOnly,
+			"hidden":      putOrder.Hidden,
+			"reduce_only": putOrder.ReduceOnly,
+			"Status":      orderStatusActive,
+		},
 	}
 
 	ticker, err := p.getTicker(putOrder.Symbol, p.currentTime)
 	if err != nil {
 		return models.Order{}, err
 	}
-	executedCost := ticker.Price * o.AmountOriginal
 
-	walletAsset, walletCurrency := p.relatedWallets(putOrder.Symbol)
-	sell := o.IsSellOrder()
-	if sell && walletAsset.Available < o.AmountOriginal {
-		return models.Order{}, fmt.Errorf("insufficient %s balance", walletAsset.Name)
-	} else if walletCurrency.Available < executedCost {
-		return models.Order{}, fmt.Errorf("insufficient %s balance", walletCurrency.Name)
+	switch o.Type {
+	case models.OrderTypeMarket, models.OrderTypeStop, models.OrderTypeLimit, models.OrderTypeStopLimit,
+		models.OrderTypeFOK, models.OrderTypeIOC, models.OrderTypeOCO:
+	case models.OrderTypeTrailingStop:
+		// initial stop price by current price, moved after price on ticks
+		o.Meta["trailing_distance"] = putOrder.TrailingDistance
+		if o.IsSellOrder() {
+			o.Meta["stop_price"] = ticker.Price - putOrder.TrailingDistance
+		} else {
+			o.Meta["stop_price"] = ticker.Price + putOrder.TrailingDistance
+		}
+	default:
+		return models.Order{}, fmt.Errorf("order type not supported")
+	}
+
+	if putOrder.PostOnly && limitReached(&o, ticker.Price) {
+		return models.Order{}, fmt.Errorf("post only order would be executed at once")
+	}
+	if putOrder.ReduceOnly && p.reducible(&o) == 0 {
+		return models.Order{}, fmt.Errorf("reduce only order would not reduce position")
+	}
+
+	if err := p.reserve(&o, ticker); err != nil {
+		return models.Order{}, err
 	}
 
+	o.Meta["new"] = true
+	p.orderEvent(o)
+	delete(o.Meta, "new")
+
 	switch o.Type {
-	case models.OrderTypeMarket:
-		rs, err := p.executeOrder(&o, ticker)
-		if err != nil {
+	case models.OrderTypeMarket, models.OrderTypeFOK, models.OrderTypeIOC:
+		if _, err := p.executeOrder(&o, ticker); err != nil && err != ErrNotExecuted {
 			return models.Order{}, err
 		}
-		return *rs, nil
-	case models.OrderTypeStop, models.OrderTypeLimit:
-		p.orders = append(p.orders, o)
+		if o.IsFilled() || isCanceled(&o) {
+			return o, nil
+		}
+		if o.Type != models.OrderTypeMarket {
+			// rest of immediate order is not placed
+			p.cancelOrder(&o)
+			return o, nil
+		}
+	}
 
-		if sell {
-			walletAsset.Available = walletAsset.Available - o.AmountOriginal
-			p.walletEvent(*walletAsset)
-		} else {
-			walletCurrency.Available = walletCurrency.Available - executedCost
-			p.walletEvent(*walletCurrency)
+	// market order not filled at once will be filled on next ticks
+	p.orders = append(p.orders, o)
+
+	return o, nil
+}
+
+// reserve lock funds needed to execute order
+func (p *Plutos) reserve(o *models.Order, ticker *models.Ticker) error {
+	walletAsset, walletCurrency := p.relatedWallets(o.Symbol)
+	amount := math.Abs(o.AmountCurrent)
+
+	if isMargin(o) {
+		return p.reserveMargin(o, ticker, walletCurrency)
+	}
+
+	if o.IsSellOrder() {
+		if walletAsset.Available < amount {
+			return fmt.Errorf("insufficient %s balance", walletAsset.Name)
 		}
-		p.wallets.Update(walletAsset)
-		p.wallets.Update(walletCurrency)
+		walletAsset.Available = walletAsset.Available - amount
+		o.Meta["reserved"] = amount
+		p.updateWallet(walletAsset)
+		return nil
+	}
+
+	cost := reservePrice(o, ticker) * amount
+	cost = cost + p.fee(o, cost)
+	if walletCurrency.Available < cost {
+		return fmt.Errorf("insufficient %s balance", walletCurrency.Name)
+	}
+	walletCurrency.Available = walletCurrency.Available - cost
+	o.Meta["reserved"] = cost
+	p.updateWallet(walletCurrency)
+
+	return nil
+}
 
-		p.orderEvent(o)
+func reservePrice(o *models.Order, ticker *models.Ticker) float64 {
+	switch o.Type {
+	case models.OrderTypeLimit, models.OrderTypeStopLimit, models.OrderTypeFOK, models.OrderTypeIOC:
+		return o.Price
+	case models.OrderTypeStop, models.OrderTypeTrailingStop:
+		return o.Meta["stop_price"].(float64)
+	case models.OrderTypeOCO:
+		// the most expensive of legs
+		return math.Max(o.Price, o.Meta["stop_price"].(float64))
+	default:
+		return ticker.Price
+	}
+}
+
+// fee returns fee of order for executed cost
+func (p *Plutos) fee(o *models.Order, cost float64) float64 {
+	if isMaker(o) {
+		return cost * p.fees.Maker / 100
+	}
+	return cost * p.fees.Taker / 100
+}
 
+// isMaker returns true if order rests in book before execution, triggered stop leg of OCO order is taker
+func isMaker(o *models.Order) bool {
+	switch o.Type {
+	case models.OrderTypeLimit, models.OrderTypeStopLimit:
+		return true
+	case models.OrderTypeOCO:
+		triggered, _ := o.Meta["triggered"].(bool)
+		return !triggered
 	default:
-		return models.Order{}, fmt.Errorf("order type not supported")
+		return false
+	}
+}
+
+func isMargin(o *models.Order) bool {
+	margin, _ := o.Meta["margin"].(bool)
+	return margin
+}
+
+func isReduceOnly(o *models.Order) bool {
+	reduceOnly, _ := o.Meta["reduce_only"].(bool)
+	return reduceOnly
+}
+
+func isCanceled(o *models.Order) bool {
+	return o.Meta["Status"] == orderStatusCanceled
+}
+
+// limitReached returns true if order can be executed by price as limit order
+func limitReached(o *models.Order, price float64) bool {
+	if o.IsSellOrder() {
+		return price >= o.Price
 	}
+	return price <= o.Price
+}
 
-	return o, fmt.Errorf("order not processed")
+// reducible returns absolute amount of position which order can reduce, 0 if order would open or increase position
+func (p *Plutos) reducible(o *models.Order) float64 {
+	i := p.findPosition(o.Symbol)
+	if i < 0 || math.Signbit(p.positions[i].Amount) == o.IsSellOrder() {
+		return 0
+	}
+	return math.Abs(p.positions[i].Amount)
 }
 
+// processOrders execute triggered orders, on error order stays as is and first error returned
 func (p *Plutos) processOrders() error {
 	tickers := make(map[string]*models.Ticker, 0)
-	var err error
+	remaining := make([]models.Order, 0, len(p.orders))
+	var rsErr error
 
-	for i, order := range p.orders {
-		var executed, ok bool
-		var ticker *models.Ticker
-
-		if ticker, ok = tickers[order.Symbol]; !ok {
+	for _, order := range p.orders {
+		ticker, ok := tickers[order.Symbol]
+		if !ok {
+			var err error
 			ticker, err = p.getTicker(order.Symbol, p.currentTime)
 			if err != nil {
-				return err
+				if rsErr == nil {
+					rsErr = err
+				}
+				remaining = append(remaining, order)
+				continue
 			}
 			tickers[order.Symbol] = ticker
 		}
 
-		if _, err = p.executeOrder(&order, ticker); err != nil {
-			if err == ErrNotExecuted {
-				err = nil
-			} else {
-				return err
-			}
-		} else {
-			executed = true
+		o := order
+		if _, err := p.executeOrder(&o, ticker); err != nil && err != ErrNotExecuted && rsErr == nil {
+			rsErr = err
 		}
-
-		if executed {
-			p.orders = append(p.orders[:i], p.orders[i+1:]...)
+		if !o.IsFilled() && !isCanceled(&o) {
+			remaining = append(remaining, o)
 		}
 	}
 
-	return nil
+	p.orders = remaining
+
+	return rsErr
 }
 
+// executeOrder fill triggered order by fill model, order can be filled partially
 func (p *Plutos) executeOrder(order *models.Order, ticker *models.Ticker) (*models.Order, error) {
 	sell := order.IsSellOrder()
 
 	switch order.Type {
 	case models.OrderTypeMarket:
-		return p.orderApply(order, ticker, sell)
 
-	case models.OrderTypeLimit:
-		if ticker.Price >= order.Price && sell {
-			return p.orderApply(order, ticker, sell)
-		}
-		if ticker.Price <= order.Price && !sell {
-			return p.orderApply(order, ticker, sell)
+	case models.OrderTypeLimit, models.OrderTypeFOK, models.OrderTypeIOC:
+		if !limitReached(order, ticker.Price) {
+			return nil, ErrNotExecuted
 		}
 	case models.OrderTypeStop:
-		val, ok := order.Meta["stop_price"]
-		if !ok {
-			return nil, fmt.Errorf("stop price are not specified")
+		// triggered stop order filled as market until done
+		triggered, err := p.triggerStop(order, ticker)
+		if err != nil {
+			return nil, err
+		}
+		if !triggered {
+			return nil, ErrNotExecuted
+		}
+	case models.OrderTypeStopLimit:
+		// triggered stop limit order rest as limit order
+		triggered, err := p.triggerStop(order, ticker)
+		if err != nil {
+			return nil, err
 		}
-		stopPrice, _ := val.(float64)
-		if ticker.Price <= stopPrice && sell {
-			return p.orderApply(order, ticker, sell)
+		if !triggered {
+			return nil, ErrNotExecuted
 		}
-		if ticker.Price >= stopPrice && !sell {
-			return p.orderApply(order, ticker, sell)
+		if !limitReached(order, ticker.Price) {
+			return nil, ErrNotExecuted
+		}
+	case models.OrderTypeTrailingStop:
+		// stop price follows price, triggered order filled as market until done
+		p.trailStop(order, ticker)
+		triggered, err := p.triggerStop(order, ticker)
+		if err != nil {
+			return nil, err
+		}
+		if !triggered {
+			return nil, ErrNotExecuted
+		}
+	case models.OrderTypeOCO:
+		// leg executed first cancel other one: triggered stop filled as market,
+		// order partially filled by limit stays limit
+		triggered, _ := order.Meta["triggered"].(bool)
+		if !triggered && order.AmountCurrent == order.AmountOriginal {
+			var err error
+			triggered, err = p.triggerStop(order, ticker)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !triggered && !limitReached(order, ticker.Price) {
+			return nil, ErrNotExecuted
 		}
 	default:
 		return nil, fmt.Errorf("order type not supported")
 	}
 
-	return nil, ErrNotExecuted
+	// reduce only order filled up to position, canceled when position is closed or flipped
+	maxAmount := math.Abs(order.AmountCurrent)
+	if isReduceOnly(order) {
+		if maxAmount = p.reducible(order); maxAmount == 0 {
+			p.cancelOrder(order)
+			return order, nil
+		}
+	}
+
+	var candle *models.Candle
+	if p.getCandle != nil {
+		var err error
+		candle, err = p.getCandle(order.Symbol, p.currentTime)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	amount, price := p.fill.Fill(order, ticker.Price, candle)
+	amount = math.Min(amount, maxAmount)
+	if amount <= 0 {
+		return nil, ErrNotExecuted
+	}
+	if order.Type == models.OrderTypeFOK && amount < math.Abs(order.AmountCurrent) {
+		return nil, ErrNotExecuted
+	}
+
+	// funds are reserved by price of placing, fill price with slippage can cost more:
+	// affordable part is filled by whole reserve and rest of order canceled
+	affordable := p.affordable(order, amount, price)
+	if affordable >= amount {
+		return p.orderApply(order, amount, price, sell)
+	}
+	if affordable <= 0 || order.Type == models.OrderTypeFOK {
+		p.cancelOrder(order)
+		return order, nil
+	}
+
+	p.unreserve(order)
+	if _, err := p.orderApply(order, affordable, price, sell); err != nil {
+		return nil, err
+	}
+	p.cancelOrder(order)
+
+	return order, nil
+}
+
+// affordable returns part of amount which can be paid at price by available funds and reserve of order,
+// part reducing margin position costs fee only, sold asset is reserved exactly
+func (p *Plutos) affordable(order *models.Order, amount float64, price float64) float64 {
+	if !isMargin(order) && order.IsSellOrder() {
+		return amount
+	}
+
+	_, walletCurrency := p.relatedWallets(order.Symbol)
+	reserved, _ := order.Meta["reserved"].(float64)
+	funds := walletCurrency.Available + reserved
+
+	fee := p.fee(order, price)
+	unit := price + fee
+	reduced := 0.0
+	if isMargin(order) {
+		unit = price/p.leverage() + fee
+		if i := p.findPosition(order.Symbol); i >= 0 && math.Signbit(p.positions[i].Amount) != order.IsSellOrder() {
+			reduced = math.Min(amount, math.Abs(p.positions[i].Amount))
+		}
+	}
+
+	if reduced*fee+(amount-reduced)*unit <= funds {
+		return amount
+	}
+	if reduced*fee >= funds {
+		return math.Min(reduced, funds/fee)
+	}
+
+	return reduced + (funds-reduced*fee)/unit
+}
+
+// triggerStop check stop price of order, order triggered once
+func (p *Plutos) triggerStop(order *models.Order, ticker *models.Ticker) (bool, error) {
+	if triggered, _ := order.Meta["triggered"].(bool); triggered {
+		return true, nil
+	}
+
+	val, ok := order.Meta["stop_price"]
+	if !ok {
+		return false, fmt.Errorf("stop price are not specified")
+	}
+	stopPrice, _ := val.(float64)
+
+	if (order.IsSellOrder() && ticker.Price > stopPrice) || (!order.IsSellOrder() && ticker.Price < stopPrice) {
+		return false, nil
+	}
+
+	order.Meta["triggered"] = true
+	if order.Type == models.OrderTypeStopLimit || order.Type == models.OrderTypeOCO {
+		p.orderEvent(*order)
+	}
+
+	return true, nil
 }
 
-func (p *Plutos) orderApply(order *models.Order, ticker *models.Ticker, sell bool) (*models.Order, error) {
-	order.AmountCurrent = 0
+// trailStop move stop price of not triggered order after price by trailing distance, stop price never moves back
+func (p *Plutos) trailStop(order *models.Order, ticker *models.Ticker) {
+	if triggered, _ := order.Meta["triggered"].(bool); triggered {
+		return
+	}
+
+	distance, _ := order.Meta["trailing_distance"].(float64)
+	stopPrice, _ := order.Meta["stop_price"].(float64)
+
+	next := math.Min(stopPrice, ticker.Price+distance)
+	if order.IsSellOrder() {
+		next = math.Max(stopPrice, ticker.Price-distance)
+	}
+	if next == stopPrice {
+		return
+	}
+
+	order.Meta["stop_price"] = next
+	order.Updated = p.currentTime
+	p.orderEvent(*order)
+}
+
+// orderApply fill amount of order by price, reserve of filled part returned to wallet
+func (p *Plutos) orderApply(order *models.Order, amount float64, price float64, sell bool) (*models.Order, error) {
+	remains := math.Abs(order.AmountCurrent)
+	amount = math.Min(amount, remains)
+
+	reserved, _ := order.Meta["reserved"].(float64)
+	released := reserved * amount / remains
+	order.Meta["reserved"] = reserved - released
+
+	filled := math.Abs(order.AmountOriginal) - remains
+	order.PriceAvg = (order.PriceAvg*filled + price*amount) / (filled + amount)
+	order.AmountCurrent = math.Copysign(remains-amount, order.AmountOriginal)
 	order.Updated = p.currentTime
-	order.PriceAvg = ticker.Price
 
 	walletAsset, walletCurrency := p.relatedWallets(order.Symbol)
 
-	executedCost := order.AmountOriginal * ticker.Price
+	executedCost := amount * price
+	fee := p.fee(order, executedCost)
 
-	// TODO if margin make position
+	order.Fee = order.Fee + fee
+	order.FeeCurrency = p.currency
 
+	signed := amount
 	if sell {
-		walletAsset.Balance = walletAsset.Balance - order.AmountOriginal
-		walletCurrency.Balance = walletCurrency.Balance + executedCost
-		walletCurrency.Available = walletCurrency.Available + executedCost
+		signed = -amount
+	}
+	p.executionEvent(order, signed, price, fee)
+
+	if order.IsFilled() {
+		order.Meta["Status"] = orderStatusExecuted
 	} else {
-		walletAsset.Balance = walletAsset.Balance + order.AmountOriginal
-		walletAsset.Available = walletAsset.Available + order.AmountOriginal
-		walletCurrency.Balance = walletCurrency.Balance - executedCost
+		order.Meta["Status"] = orderStatusPartiallyFilled
 	}
 
-	p.walletEvent(*walletAsset)
-	p.walletEvent(*walletCurrency)
-	p.orderEvent(*order)
+	if isMargin(order) {
+		walletCurrency.Available = walletCurrency.Available + released
+		p.updateWallet(walletCurrency)
+
+		p.addPosition(order.Symbol, signed, price)
+
+		walletCurrency = p.currencyWallet()
+		walletCurrency.Balance = walletCurrency.Balance - fee
+		walletCurrency.Available = walletCurrency.Available - fee
+		p.updateWallet(walletCurrency)
+
+		p.orderFilled(*order)
+
+		return order, nil
+	}
+
+	if sell {
+		walletAsset.Balance = walletAsset.Balance - amount
+		walletCurrency.Balance = walletCurrency.Balance + executedCost - fee
+		walletCurrency.Available = walletCurrency.Available + executedCost - fee
+	} else {
+		walletAsset.Balance = walletAsset.Balance + amount
+		walletAsset.Available = walletAsset.Available + amount
+		walletCurrency.Balance = walletCurrency.Balance - executedCost - fee
+		walletCurrency.Available = walletCurrency.Available + released - executedCost - fee
+	}
+
+	p.updateWallet(walletAsset)
+	p.updateWallet(walletCurrency)
+
+	p.orderFilled(*order)
 
 	return order, nil
 }
 
+// orderFilled emit fill of order, fully filled orders moved to history
+func (p *Plutos) orderFilled(order models.Order) {
+	if order.IsFilled() {
+		p.history = append(p.history, order)
+	}
+	p.orderEvent(order)
+}
+
+func (p *Plutos) updateWallet(wc *models.WalletCurrency) {
+	p.wallets.Update(wc)
+	p.walletEvent(*wc)
+}
+
+// GetHistory returns executed orders
+func (p *Plutos) GetHistory() []*models.Order {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rs := make([]*models.Order, 0, len(p.history))
+	for _, order := range p.history {
+		o := order
+		rs = append(rs, &o)
+	}
+	return rs
+}
+
 func (p *Plutos) orderEvent(order models.Order) {
-	p.channel <- &order
+	meta := make(map[string]interface{}, len(order.Meta))
+	for k, v := range order.Meta {
+		meta[k] = v
+	}
+	order.Meta = meta
+	p.emit(&order)
+}
+
+// executionEvent emit fill of order by amount (negative for sell) and price
+func (p *Plutos) executionEvent(order *models.Order, amount float64, price float64, fee float64) {
+	p.emit(&models.Execution{
+		ID:          uuid.Must(uuid.NewUUID()).String(),
+		OrderID:     order.ID,
+		Symbol:      order.Symbol,
+		Date:        p.currentTime,
+		Price:       price,
+		Amount:      amount,
+		Fee:         fee,
+		FeeCurrency: p.currency,
+		Maker:       isMaker(order),
+	})
 }
 
 func (p *Plutos) walletEvent(wc models.WalletCurrency) {
-	p.channel <- &wc
+	p.emit(&wc)
+}
+
+// emit queue event, it is sent to channel by unlock, so consumer of full channel can call Plutos meanwhile
+func (p *Plutos) emit(item interface{}) {
+	p.pending = append(p.pending, item)
+}
+
+// unlock release mu and send events emitted under it
+func (p *Plutos) unlock() {
+	pending := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+
+	for _, item := range pending {
+		p.channel <- item
+	}
 }
 
 func (p *Plutos) relatedWallets(pair string) (*models.WalletCurrency, *models.WalletCurrency) {
@@ -370,7 +836,7 @@ func (p *Plutos) relatedWallets(pair string) (*models.WalletCurrency, *models.Wa
 	if walletAsset == nil {
 		walletAsset = &models.WalletCurrency{
 			Name:       asset,
-			WalletType: models.WalletTypeNone,
+			WalletType: p.wallets.WalletType,
 			Balance:    0,
 			Available:  0,
 		}
@@ -379,7 +845,7 @@ func (p *Plutos) relatedWallets(pair string) (*models.WalletCurrency, *models.Wa
 	if walletCurrency == nil {
 		walletCurrency = &models.WalletCurrency{
 			Name:       p.currency,
-			WalletType: models.WalletTypeNone,
+			WalletType: p.wallets.WalletType,
 			Balance:    0,
 			Available:  0,
 		}
@@ -387,16 +853,3 @@ func (p *Plutos) relatedWallets(pair string) (*models.WalletCurrency, *models.Wa
 
 	return walletAsset, walletCurrency
 }
-
-func (p *Plutos) addPosition() {
-
-}
-
-func (p *Plutos) processPositions() error {
-
-	return nil
-}
-
-func (p *Plutos) positionClose() {
-
-}
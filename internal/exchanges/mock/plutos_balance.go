@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"time"
+)
+
+// BalanceSnapshot state of balance at time, Invested is value of assets and positions in market
+type BalanceSnapshot struct {
+	Time     time.Time
+	Total    float64
+	NetWorth float64
+	Invested float64
+}
+
+// SetBalanceInterval enable balance snapshots every interval of simulated time, disabled if 0
+func (p *Plutos) SetBalanceInterval(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.balanceInterval = d
+}
+
+func (p *Plutos) snapshotBalance(t time.Time) {
+	rs, invested, err := p.getBalance(t)
+	if err != nil {
+		// no quote for time, skip snapshot
+		return
+	}
+
+	p.balanceHistory = append(p.balanceHistory, BalanceSnapshot{
+		Time:     t,
+		Total:    rs.Total,
+		NetWorth: rs.NetWorth,
+		Invested: invested,
+	})
+}
+
+// GetBalanceHistory returns balance snapshots
+func (p *Plutos) GetBalanceHistory() []BalanceSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rs := make([]BalanceSnapshot, len(p.balanceHistory))
+	copy(rs, p.balanceHistory)
+
+	return rs
+}
@@ -0,0 +1,52 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"math"
+	"time"
+)
+
+// CandleFunc returns minute candle of symbol for time
+type CandleFunc func(symbol string, curTime time.Time) (*models.Candle, error)
+
+// FillModel decide how triggered order executed: returns absolute amount filled on current tick
+// (not greater than order remains, zero if nothing filled) and execution price,
+// candle can be nil if not available
+type FillModel interface {
+	Fill(o *models.Order, price float64, candle *models.Candle) (amount float64, fillPrice float64)
+}
+
+// PerfectFill execute whole order by ticker price
+type PerfectFill struct{}
+
+func (PerfectFill) Fill(o *models.Order, price float64, _ *models.Candle) (float64, float64) {
+	return math.Abs(o.AmountCurrent), price
+}
+
+// SlippageFill shift price of market and stop orders against trader
+// and limit amount filled per tick by part of candle volume
+type SlippageFill struct {
+	Fixed       float64 // price offset
+	Percent     float64 // price offset in percent of price
+	VolumeShare float64 // max part (0..1] of candle volume filled per tick, unlimited if 0
+}
+
+func (f SlippageFill) Fill(o *models.Order, price float64, candle *models.Candle) (float64, float64) {
+	amount := math.Abs(o.AmountCurrent)
+
+	if f.VolumeShare > 0 && candle != nil {
+		amount = math.Min(amount, candle.Volume*f.VolumeShare)
+	}
+
+	// price of limit orders is bounded by limit
+	if isMaker(o) || o.Type == models.OrderTypeFOK || o.Type == models.OrderTypeIOC {
+		return amount, price
+	}
+
+	slippage := f.Fixed + price*f.Percent/100
+	if o.IsSellOrder() {
+		return amount, math.Max(0, price-slippage)
+	}
+
+	return amount, price + slippage
+}
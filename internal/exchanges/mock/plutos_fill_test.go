@@ -0,0 +1,119 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSlippageFill(t *testing.T) {
+	fm := SlippageFill{Fixed: 1, Percent: 1, VolumeShare: 0.5}
+	candle := &models.Candle{Volume: 4}
+
+	tests := []struct {
+		name   string
+		order  models.Order
+		amount float64
+		price  float64
+	}{
+		{"market buy", models.Order{Type: models.OrderTypeMarket, AmountCurrent: 1}, 1, 102},
+		{"market sell", models.Order{Type: models.OrderTypeMarket, AmountCurrent: -1}, 1, 98},
+		{"stop sell capped", models.Order{Type: models.OrderTypeStop, AmountCurrent: -3}, 2, 98},
+		{"limit buy capped", models.Order{Type: models.OrderTypeLimit, AmountCurrent: 5}, 2, 100},
+	}
+
+	for _, tt := range tests {
+		amount, price := fm.Fill(&tt.order, 100, candle)
+		if !equal(amount, tt.amount) || !equal(price, tt.price) {
+			t.Errorf("%s: expected %v by %v, got %v by %v", tt.name, tt.amount, tt.price, amount, price)
+		}
+	}
+
+	amount, _ := fm.Fill(&models.Order{Type: models.OrderTypeMarket, AmountCurrent: 10}, 100, nil)
+	if amount != 10 {
+		t.Errorf("without candle expected full amount, got %v", amount)
+	}
+}
+
+func TestPartialFill(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+	p.SetFillModel(SlippageFill{VolumeShare: 0.5})
+	p.SetCandleFunc(func(symbol string, curTime time.Time) (*models.Candle, error) {
+		return &models.Candle{Symbol: symbol, Volume: 2}, nil
+	})
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(o.AmountCurrent, 2) || o.Meta["Status"] != orderStatusPartiallyFilled {
+		t.Fatalf("expected partially filled order, got %+v", o)
+	}
+
+	wc := p.wallets.Get(currency)
+	if !equal(wc.Balance, 900) || !equal(wc.Available, 700) {
+		t.Fatalf("wrong wallet after partial fill %+v", wc)
+	}
+
+	for i := 0; i < 2; i++ {
+		price.price = 110
+		p.mu.Lock()
+		err = p.processOrders()
+		p.unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(p.GetOrders()) != 0 {
+		t.Fatal("order not filled")
+	}
+
+	history := p.GetHistory()
+	if len(history) != 1 || !equal(history[0].PriceAvg, (100+110+110)/3.0) {
+		t.Fatalf("wrong history %+v", history)
+	}
+
+	wc = p.wallets.Get(currency)
+	if !equal(wc.Balance, 1000-320) || !equal(wc.Available, wc.Balance) {
+		t.Fatalf("wrong wallet after fill %+v", wc)
+	}
+}
+
+func TestFillShortOfFunds(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{Taker: 1})
+	p.SetFillModel(SlippageFill{Percent: 10})
+
+	// reserved 990 by ticker price, 1100 needed by fill price
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 9.8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isCanceled(&o) || !equal(o.AmountCurrent, 9.8-1000/111.1) {
+		t.Fatalf("expected affordable part filled and rest canceled, got %+v", o)
+	}
+
+	wc := p.wallets.Get(currency)
+	if !equal(wc.Balance, 0) || !equal(wc.Available, 0) {
+		t.Fatalf("wrong wallet %+v", wc)
+	}
+	if len(p.GetOrders()) != 0 || len(p.GetHistory()) != 1 {
+		t.Fatal("canceled order is not moved to history")
+	}
+	// margin order pays collateral by leverage
+	p = newTestPlutos(t, price, models.WalletTypeMargin, Fees{Taker: 1})
+	p.SetFillModel(SlippageFill{Percent: 10})
+
+	o, err = p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 45, Margin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isCanceled(&o) || !equal(o.AmountCurrent, 45-1000/23.1) {
+		t.Fatalf("expected affordable part filled and rest canceled, got %+v", o)
+	}
+	if wc = p.wallets.Get(currency); !equal(wc.Available, 0) {
+		t.Fatalf("wrong wallet %+v", wc)
+	}
+}
@@ -0,0 +1,145 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"fmt"
+	"math"
+)
+
+const (
+	orderStatusCanceled = "CANCELED"
+)
+
+var (
+	ErrOrderNotFound = fmt.Errorf("order not found")
+)
+
+func (p *Plutos) findOrder(id string) int {
+	for i, o := range p.orders {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// unreserve return funds reserved for rest of order
+func (p *Plutos) unreserve(o *models.Order) {
+	reserved, _ := o.Meta["reserved"].(float64)
+	if reserved == 0 {
+		return
+	}
+
+	walletAsset, walletCurrency := p.relatedWallets(o.Symbol)
+
+	if !isMargin(o) && o.IsSellOrder() {
+		walletAsset.Available = walletAsset.Available + reserved
+		p.updateWallet(walletAsset)
+	} else {
+		walletCurrency.Available = walletCurrency.Available + reserved
+		p.updateWallet(walletCurrency)
+	}
+
+	o.Meta["reserved"] = 0.0
+}
+
+// CancelOrder cancel active order and release its reserve, partially filled order moved to history
+func (p *Plutos) CancelOrder(id string) (models.Order, error) {
+	p.mu.Lock()
+	defer p.unlock()
+
+	i := p.findOrder(id)
+	if i < 0 {
+		return models.Order{}, ErrOrderNotFound
+	}
+
+	o := p.orders[i]
+	p.orders = append(p.orders[:i], p.orders[i+1:]...)
+
+	p.cancelOrder(&o)
+
+	return o, nil
+}
+
+// cancelOrder release reserve of order which is not active anymore, partially filled order moved to history
+func (p *Plutos) cancelOrder(o *models.Order) {
+	p.unreserve(o)
+
+	o.Updated = p.currentTime
+	o.Meta["Status"] = orderStatusCanceled
+
+	if o.AmountCurrent != o.AmountOriginal {
+		p.history = append(p.history, *o)
+	}
+	p.orderEvent(*o)
+}
+
+// UpdateOrder change price, stop price or rest amount of active order, zero values are ignored.
+// Funds reserved again for new values, on failure order stay unchanged
+func (p *Plutos) UpdateOrder(id string, price float64, priceStop float64, amount float64) (models.Order, error) {
+	p.mu.Lock()
+	defer p.unlock()
+
+	i := p.findOrder(id)
+	if i < 0 {
+		return models.Order{}, ErrOrderNotFound
+	}
+
+	o := p.orders[i]
+
+	if price != 0 && (o.Type == models.OrderTypeMarket || o.Type == models.OrderTypeStop || o.Type == models.OrderTypeTrailingStop) {
+		return models.Order{}, fmt.Errorf("order has no price")
+	}
+	if priceStop != 0 && o.Type != models.OrderTypeStop && o.Type != models.OrderTypeStopLimit && o.Type != models.OrderTypeOCO {
+		return models.Order{}, fmt.Errorf("order has no stop price")
+	}
+	if amount != 0 && math.Signbit(amount) != o.IsSellOrder() {
+		return models.Order{}, fmt.Errorf("wrong amount")
+	}
+
+	ticker, err := p.getTicker(o.Symbol, p.currentTime)
+	if err != nil {
+		return models.Order{}, err
+	}
+
+	if postOnly, _ := o.Meta["post_only"].(bool); postOnly && price != 0 {
+		moved := o
+		moved.Price = price
+		if limitReached(&moved, ticker.Price) {
+			return models.Order{}, fmt.Errorf("post only order would be executed at once")
+		}
+	}
+
+	prev := o
+	prev.Meta = make(map[string]interface{}, len(o.Meta))
+	for k, v := range o.Meta {
+		prev.Meta[k] = v
+	}
+
+	p.unreserve(&o)
+
+	if price != 0 {
+		o.Price = price
+	}
+	if priceStop != 0 {
+		o.Meta["stop_price"] = priceStop
+	}
+	if amount != 0 {
+		o.AmountOriginal = o.AmountOriginal - o.AmountCurrent + amount
+		o.AmountCurrent = amount
+	}
+
+	if err := p.reserve(&o, ticker); err != nil {
+		if err := p.reserve(&prev, ticker); err != nil {
+			return models.Order{}, err
+		}
+		p.orders[i] = prev
+		return models.Order{}, err
+	}
+
+	o.Updated = p.currentTime
+	p.orders[i] = o
+	p.orderEvent(o)
+
+	return o, nil
+}
@@ -0,0 +1,332 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"testing"
+	"time"
+)
+
+func processTick(t *testing.T, p *Plutos) {
+	p.mu.Lock()
+	err := p.processOrders()
+	p.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStopLimitOrder(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeStopLimit, Amount: 1, StopPrice: 110, Price: 105})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// limit price reached, but stop not triggered
+	price.price = 104
+	processTick(t, p)
+	if len(p.GetOrders()) != 1 {
+		t.Fatal("order executed before stop triggered")
+	}
+
+	// stop triggered, limit price not reached
+	price.price = 111
+	processTick(t, p)
+	orders := p.GetOrders()
+	if len(orders) != 1 || orders[0].Meta["triggered"] != true {
+		t.Fatalf("order not armed %+v", orders)
+	}
+
+	price.price = 104
+	processTick(t, p)
+
+	history := p.GetHistory()
+	if len(p.GetOrders()) != 0 || len(history) != 1 || history[0].ID != o.ID || !equal(history[0].PriceAvg, 104) {
+		t.Fatalf("order not executed by limit %+v", history)
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 2, Price: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if wc := p.wallets.Get(currency); !equal(wc.Available, 820) {
+		t.Fatalf("wrong reserve %+v", wc)
+	}
+
+	c, err := p.CancelOrder(o.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Meta["Status"] != orderStatusCanceled {
+		t.Fatalf("wrong status %v", c.Meta["Status"])
+	}
+
+	if wc := p.wallets.Get(currency); !equal(wc.Available, 1000) || !equal(wc.Balance, 1000) {
+		t.Fatalf("reserve not released %+v", wc)
+	}
+	if len(p.GetOrders()) != 0 || len(p.GetHistory()) != 0 {
+		t.Fatal("canceled order not removed")
+	}
+
+	if _, err := p.CancelOrder(o.ID); err != ErrOrderNotFound {
+		t.Fatalf("expected %v, got %v", ErrOrderNotFound, err)
+	}
+}
+
+func TestUpdateOrder(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 2, Price: 90})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := p.UpdateOrder(o.ID, 80, 0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(u.Price, 80) || !equal(u.AmountCurrent, 5) || !equal(u.AmountOriginal, 5) {
+		t.Fatalf("order not updated %+v", u)
+	}
+	if wc := p.wallets.Get(currency); !equal(wc.Available, 600) {
+		t.Fatalf("wrong reserve after update %+v", wc)
+	}
+
+	// not enough funds, order stay unchanged
+	if _, err := p.UpdateOrder(o.ID, 0, 0, 20); err == nil {
+		t.Fatal("expected insufficient balance error")
+	}
+	orders := p.GetOrders()
+	if len(orders) != 1 || !equal(orders[0].AmountCurrent, 5) || !equal(orders[0].Price, 80) {
+		t.Fatalf("order changed after failed update %+v", orders)
+	}
+	if wc := p.wallets.Get(currency); !equal(wc.Available, 600) {
+		t.Fatalf("wrong reserve after failed update %+v", wc)
+	}
+
+	if _, err := p.UpdateOrder(o.ID, 0, 70, 0); err == nil {
+		t.Fatal("expected error on stop price of limit order")
+	}
+}
+
+func TestTrailingStopOrder(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeTrailingStop, Amount: 1}); err == nil {
+		t.Fatal("expected error on empty trailing distance")
+	}
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeTrailingStop, Amount: 1, TrailingDistance: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(o.Meta["stop_price"].(float64), 105) {
+		t.Fatalf("wrong initial stop price %v", o.Meta["stop_price"])
+	}
+	if wc := p.wallets.Get(currency); !equal(wc.Available, 895) {
+		t.Fatalf("wrong reserve %+v", wc)
+	}
+
+	// stop follows price down, but not back
+	price.price = 90
+	processTick(t, p)
+	price.price = 94
+	processTick(t, p)
+	orders := p.GetOrders()
+	if len(orders) != 1 || !equal(orders[0].Meta["stop_price"].(float64), 95) {
+		t.Fatalf("stop price not moved %+v", orders)
+	}
+
+	price.price = 96
+	processTick(t, p)
+
+	history := p.GetHistory()
+	if len(p.GetOrders()) != 0 || len(history) != 1 || !equal(history[0].PriceAvg, 96) {
+		t.Fatalf("order not executed by stop %+v", history)
+	}
+	if wc := p.wallets.Get(currency); !equal(wc.Balance, 904) || !equal(wc.Available, wc.Balance) {
+		t.Fatalf("wrong wallet %+v", wc)
+	}
+}
+
+func TestImmediateOrders(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+	p.SetFillModel(SlippageFill{VolumeShare: 0.5})
+	p.SetCandleFunc(func(symbol string, curTime time.Time) (*models.Candle, error) {
+		return &models.Candle{Symbol: symbol, Volume: 2}, nil
+	})
+
+	// one of three filled, rest canceled
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeIOC, Amount: 3, Price: 101})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(o.AmountCurrent, 2) || o.Meta["Status"] != orderStatusCanceled {
+		t.Fatalf("wrong IOC order %+v", o)
+	}
+
+	// can't be filled entirely
+	o, err = p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeFOK, Amount: 3, Price: 101})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(o.AmountCurrent, 3) || o.Meta["Status"] != orderStatusCanceled {
+		t.Fatalf("wrong FOK order %+v", o)
+	}
+
+	o, err = p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeFOK, Amount: 1, Price: 101})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !o.IsFilled() {
+		t.Fatalf("FOK order not filled %+v", o)
+	}
+
+	// limit not reached
+	o, err = p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeIOC, Amount: 1, Price: 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(o.AmountCurrent, 1) || o.Meta["Status"] != orderStatusCanceled {
+		t.Fatalf("wrong IOC order %+v", o)
+	}
+
+	if len(p.GetOrders()) != 0 || len(p.GetHistory()) != 2 {
+		t.Fatalf("immediate orders rest %+v, history %+v", p.GetOrders(), p.GetHistory())
+	}
+	if wc := p.wallets.Get(currency); !equal(wc.Balance, 800) || !equal(wc.Available, wc.Balance) {
+		t.Fatalf("wrong wallet %+v", wc)
+	}
+}
+
+func TestOCOOrder(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeOCO, Amount: 1, Price: 90}); err == nil {
+		t.Fatal("expected error on empty stop price")
+	}
+
+	// stop leg executed
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeOCO, Amount: 1, Price: 90, StopPrice: 110}); err != nil {
+		t.Fatal(err)
+	}
+	if wc := p.wallets.Get(currency); !equal(wc.Available, 890) {
+		t.Fatalf("wrong reserve %+v", wc)
+	}
+
+	price.price = 95
+	processTick(t, p)
+	if len(p.GetOrders()) != 1 {
+		t.Fatal("order executed between legs")
+	}
+
+	price.price = 111
+	processTick(t, p)
+	history := p.GetHistory()
+	if len(history) != 1 || !equal(history[0].PriceAvg, 111) || history[0].Meta["triggered"] != true {
+		t.Fatalf("order not executed by stop %+v", history)
+	}
+
+	// limit leg executed
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeOCO, Amount: 1, Price: 90, StopPrice: 120})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.UpdateOrder(o.ID, 0, 125, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	price.price = 89
+	processTick(t, p)
+	history = p.GetHistory()
+	if len(history) != 2 || !equal(history[1].PriceAvg, 89) || history[1].Meta["triggered"] == true {
+		t.Fatalf("order not executed by limit %+v", history)
+	}
+
+	if wc := p.wallets.Get(currency); !equal(wc.Balance, 1000-111-89) || !equal(wc.Available, wc.Balance) {
+		t.Fatalf("wrong wallet %+v", wc)
+	}
+}
+
+func TestPostOnlyOrder(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{})
+
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 1, PostOnly: true}); err == nil {
+		t.Fatal("expected error on post only market order")
+	}
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 1, Price: 101, PostOnly: true}); err == nil {
+		t.Fatal("expected error on post only order executed at once")
+	}
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 1, Price: 99, PostOnly: true, Hidden: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.UpdateOrder(o.ID, 101, 0, 0); err == nil {
+		t.Fatal("expected error on update of post only order executed at once")
+	}
+	if _, err := p.UpdateOrder(o.ID, 98, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReduceOnlyOrder(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeMargin, Fees{})
+
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: -1, ReduceOnly: true}); err == nil {
+		t.Fatal("expected error on reduce only exchange order")
+	}
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: -1, Margin: true, ReduceOnly: true}); err == nil {
+		t.Fatal("expected error on reduce only order without position")
+	}
+
+	putMarginOrder(t, p, 10)
+
+	if _, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 1, Price: 90, Margin: true, ReduceOnly: true}); err == nil {
+		t.Fatal("expected error on reduce only order increasing position")
+	}
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: -15, Price: 110, Margin: true, ReduceOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// position closed, order not flip it
+	price.price = 110
+	processTick(t, p)
+	if len(p.GetPositions()) != 0 {
+		t.Fatalf("position not closed %+v", p.GetPositions())
+	}
+	orders := p.GetOrders()
+	if len(orders) != 1 || !equal(orders[0].AmountCurrent, -5) {
+		t.Fatalf("wrong rest of order %+v", orders)
+	}
+
+	// rest canceled
+	processTick(t, p)
+	history := p.GetHistory()
+	if len(p.GetOrders()) != 0 || len(history) != 2 || history[1].ID != o.ID || history[1].Meta["Status"] != orderStatusCanceled {
+		t.Fatalf("rest of order not canceled %+v", history)
+	}
+	if len(p.GetPositions()) != 0 {
+		t.Fatalf("position opened %+v", p.GetPositions())
+	}
+
+	if wc := p.wallets.Get(currency); !equal(wc.Balance, 1100) || !equal(wc.Available, wc.Balance) {
+		t.Fatalf("wrong wallet %+v", wc)
+	}
+}
@@ -0,0 +1,293 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+)
+
+const (
+	// maintenanceMargin part of position cost, when equity of position falls below it position liquidated
+	maintenanceMargin = 0.005
+
+	positionStatusActive = "ACTIVE"
+	positionStatusClosed = "CLOSED"
+)
+
+var (
+	ErrPositionNotFound = fmt.Errorf("position not found")
+)
+
+func (p *Plutos) leverage() float64 {
+	if p.maxLeverage == 0 {
+		return 1
+	}
+	return float64(p.maxLeverage)
+}
+
+// reserveMargin lock collateral for part of order which open or increase position and fee of whole order,
+// part which reduce existing position does not require collateral
+func (p *Plutos) reserveMargin(o *models.Order, ticker *models.Ticker, walletCurrency *models.WalletCurrency) error {
+	amount := math.Abs(o.AmountCurrent)
+
+	if i := p.findPosition(o.Symbol); i >= 0 && math.Signbit(p.positions[i].Amount) != o.IsSellOrder() {
+		amount = math.Max(0, amount-math.Abs(p.positions[i].Amount))
+	}
+
+	price := reservePrice(o, ticker)
+	collateral := price*amount/p.leverage() + p.fee(o, price*math.Abs(o.AmountCurrent))
+	if walletCurrency.Available < collateral {
+		return fmt.Errorf("insufficient %s balance", walletCurrency.Name)
+	}
+
+	walletCurrency.Available = walletCurrency.Available - collateral
+	o.Meta["reserved"] = collateral
+	p.updateWallet(walletCurrency)
+
+	return nil
+}
+
+func (p *Plutos) currencyWallet() *models.WalletCurrency {
+	_, walletCurrency := p.relatedWallets(p.currency)
+	return walletCurrency
+}
+
+func (p *Plutos) findPosition(symbol string) int {
+	for i, pos := range p.positions {
+		if pos.Symbol == symbol {
+			return i
+		}
+	}
+	return -1
+}
+
+func positionCollateral(pos *models.Position) float64 {
+	c, _ := pos.Meta["collateral"].(float64)
+	return c
+}
+
+// addPosition open, increase, reduce or flip position of symbol by signed amount
+func (p *Plutos) addPosition(symbol string, amount float64, price float64) {
+	i := p.findPosition(symbol)
+
+	if i < 0 {
+		p.openPosition(symbol, amount, price)
+		return
+	}
+
+	pos := &p.positions[i]
+
+	if math.Signbit(pos.Amount) == math.Signbit(amount) {
+		// increase
+		collateral := math.Abs(amount) * price / p.leverage()
+		p.lockCollateral(collateral)
+
+		total := pos.Amount + amount
+		pos.Price = (pos.Price*math.Abs(pos.Amount) + price*math.Abs(amount)) / math.Abs(total)
+		pos.Amount = total
+		pos.Meta["collateral"] = positionCollateral(pos) + collateral
+
+		p.updatePositionState(pos, price)
+		p.positionEvent(*pos)
+		return
+	}
+
+	closed := math.Min(math.Abs(amount), math.Abs(pos.Amount))
+	rest := math.Abs(amount) - closed
+
+	if closed == math.Abs(pos.Amount) {
+		p.positionClose(i, price, false)
+	} else {
+		p.reducePosition(pos, closed, price)
+		p.updatePositionState(pos, price)
+		p.positionEvent(*pos)
+	}
+
+	if rest > 0 {
+		// flip
+		p.openPosition(symbol, math.Copysign(rest, amount), price)
+	}
+}
+
+func (p *Plutos) openPosition(symbol string, amount float64, price float64) {
+	collateral := math.Abs(amount) * price / p.leverage()
+	p.lockCollateral(collateral)
+
+	pos := models.Position{
+		ID:     uuid.Must(uuid.NewUUID()).String(),
+		Symbol: symbol,
+		Price:  price,
+		Amount: amount,
+		Meta: map[string]interface{}{
+			"collateral": collateral,
+			"Status":     positionStatusActive,
+			"new":        true,
+		},
+	}
+	p.updatePositionState(&pos, price)
+
+	p.positions = append(p.positions, pos)
+	p.positionEvent(pos)
+
+	delete(pos.Meta, "new")
+}
+
+// reducePosition realize profit of closed part and release its collateral
+func (p *Plutos) reducePosition(pos *models.Position, closed float64, price float64) {
+	collateral := positionCollateral(pos)
+	released := collateral * closed / math.Abs(pos.Amount)
+	pnl := (price - pos.Price) * math.Copysign(closed, pos.Amount)
+
+	pos.Amount = pos.Amount - math.Copysign(closed, pos.Amount)
+	pos.Meta["collateral"] = collateral - released
+
+	p.realize(released, pnl)
+}
+
+// lockCollateral lock collateral of position, reserve of order must be returned before
+func (p *Plutos) lockCollateral(collateral float64) {
+	walletCurrency := p.currencyWallet()
+	walletCurrency.Available = walletCurrency.Available - collateral
+	p.updateWallet(walletCurrency)
+}
+
+// realize return released collateral and profit (or loss) to wallet,
+// loss can't exceed collateral
+func (p *Plutos) realize(released float64, pnl float64) {
+	pnl = math.Max(pnl, -released)
+
+	walletCurrency := p.currencyWallet()
+	walletCurrency.Balance = walletCurrency.Balance + pnl
+	walletCurrency.Available = walletCurrency.Available + released + pnl
+	p.updateWallet(walletCurrency)
+}
+
+func (p *Plutos) updatePositionState(pos *models.Position, price float64) {
+	collateral := positionCollateral(pos)
+	amount := math.Abs(pos.Amount)
+
+	pos.ProfitLoss = (price - pos.Price) * pos.Amount
+	if collateral > 0 {
+		pos.ProfitLossPercentage = pos.ProfitLoss / collateral * 100
+	}
+
+	equity := collateral + pos.ProfitLoss
+	if equity > 0 {
+		pos.MarginLevel = price * amount / equity
+	} else {
+		pos.MarginLevel = math.Inf(1)
+	}
+
+	if amount == 0 {
+		pos.LiqPrice = 0
+		return
+	}
+
+	if pos.Amount > 0 {
+		pos.LiqPrice = math.Max(0, (pos.Price*amount-collateral)/(amount*(1-maintenanceMargin)))
+	} else {
+		pos.LiqPrice = (pos.Price*amount + collateral) / (amount * (1 + maintenanceMargin))
+	}
+}
+
+func (p *Plutos) processPositions() error {
+	for i := 0; i < len(p.positions); i++ {
+		pos := &p.positions[i]
+
+		ticker, err := p.getTicker(pos.Symbol, p.currentTime)
+		if err != nil {
+			return err
+		}
+
+		p.updatePositionState(pos, ticker.Price)
+
+		liquidate := (pos.Amount > 0 && ticker.Price <= pos.LiqPrice) ||
+			(pos.Amount < 0 && ticker.Price >= pos.LiqPrice)
+
+		if liquidate {
+			p.positionClose(i, ticker.Price, true)
+			i--
+			continue
+		}
+
+		p.positionEvent(*pos)
+	}
+
+	return nil
+}
+
+// positionClose close whole position by price
+func (p *Plutos) positionClose(i int, price float64, liquidated bool) {
+	pos := p.positions[i]
+
+	p.updatePositionState(&pos, price)
+	p.realize(positionCollateral(&pos), pos.ProfitLoss)
+
+	pos.Meta["Status"] = positionStatusClosed
+	if liquidated {
+		pos.Meta["liquidated"] = true
+	}
+
+	p.positions = append(p.positions[:i], p.positions[i+1:]...)
+	p.positionEvent(pos)
+}
+
+// ClosePosition close position by market price, returns position state before close
+func (p *Plutos) ClosePosition(id string) (models.Position, error) {
+	p.mu.Lock()
+	defer p.unlock()
+
+	i := -1
+	for n, pos := range p.positions {
+		if pos.ID == id {
+			i = n
+			break
+		}
+	}
+	if i < 0 {
+		return models.Position{}, ErrPositionNotFound
+	}
+
+	pos := p.positions[i]
+
+	ticker, err := p.getTicker(pos.Symbol, p.currentTime)
+	if err != nil {
+		return models.Position{}, err
+	}
+
+	p.updatePositionState(&pos, ticker.Price)
+	prevState := pos
+	prevState.Meta = make(map[string]interface{}, len(pos.Meta))
+	for k, v := range pos.Meta {
+		prevState.Meta[k] = v
+	}
+
+	o := models.Order{
+		ID:             uuid.Must(uuid.NewUUID()).String(),
+		Symbol:         pos.Symbol,
+		Type:           models.OrderTypeMarket,
+		AmountCurrent:  -pos.Amount,
+		AmountOriginal: -pos.Amount,
+		Date:           p.currentTime,
+		Updated:        p.currentTime,
+		Meta:           map[string]interface{}{"margin": true, "reserved": 0.0, "Status": orderStatusActive},
+	}
+
+	// position closed at once, volume of candle ignored
+	amount, price := p.fill.Fill(&o, ticker.Price, nil)
+	if _, err := p.orderApply(&o, amount, price, o.IsSellOrder()); err != nil {
+		return models.Position{}, err
+	}
+
+	return prevState, nil
+}
+
+func (p *Plutos) positionEvent(pos models.Position) {
+	meta := make(map[string]interface{}, len(pos.Meta))
+	for k, v := range pos.Meta {
+		meta[k] = v
+	}
+	pos.Meta = meta
+	p.emit(&pos)
+}
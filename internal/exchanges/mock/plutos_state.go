@@ -0,0 +1,77 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+)
+
+// PlutosState account of Plutos, can be saved and restored by NewPlutosFromState
+type PlutosState struct {
+	WalletType models.WalletType
+	Wallets    []models.WalletCurrency
+	Orders     []models.Order
+	Positions  []models.Position
+	History    []models.Order
+}
+
+func NewPlutosFromState(maxLeverage uint8, fees Fees, currency string, st PlutosState) *Plutos {
+	w := &models.Wallets{WalletType: st.WalletType}
+	for _, wc := range st.Wallets {
+		cur := wc
+		w.Update(&cur)
+	}
+
+	orders := append([]models.Order{}, st.Orders...)
+	positions := append([]models.Position{}, st.Positions...)
+
+	p := NewPlutos(maxLeverage, fees, currency, w, orders, positions)
+	p.history = append(p.history, st.History...)
+
+	return p
+}
+
+// State returns copy of account
+func (p *Plutos) State() PlutosState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rs := PlutosState{
+		WalletType: p.wallets.WalletType,
+		Wallets:    make([]models.WalletCurrency, 0),
+		Orders:     make([]models.Order, 0, len(p.orders)),
+		Positions:  make([]models.Position, 0, len(p.positions)),
+		History:    make([]models.Order, 0, len(p.history)),
+	}
+
+	for _, wc := range p.wallets.GetAll() {
+		rs.Wallets = append(rs.Wallets, *wc)
+	}
+	for _, o := range p.orders {
+		rs.Orders = append(rs.Orders, copyOrder(o))
+	}
+	for _, pos := range p.positions {
+		rs.Positions = append(rs.Positions, copyPosition(pos))
+	}
+	for _, o := range p.history {
+		rs.History = append(rs.History, copyOrder(o))
+	}
+
+	return rs
+}
+
+func copyOrder(o models.Order) models.Order {
+	meta := make(map[string]interface{}, len(o.Meta))
+	for k, v := range o.Meta {
+		meta[k] = v
+	}
+	o.Meta = meta
+	return o
+}
+
+func copyPosition(p models.Position) models.Position {
+	meta := make(map[string]interface{}, len(p.Meta))
+	for k, v := range p.Meta {
+		meta[k] = v
+	}
+	p.Meta = meta
+	return p
+}
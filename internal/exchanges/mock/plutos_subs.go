@@ -12,12 +12,32 @@ type Ticker struct {
 	Symbol string
 }
 
+type OrderBook struct {
+	Time   time.Time
+	Symbol string
+}
+
+type Trades struct {
+	Time   time.Time
+	Symbol string
+}
+
 type Candle struct {
 	Time   time.Time
 	Symbol string
 	Res    models.CandleResolution
 }
 
+// TickDone pushed after all events of tick, consumer must call Ack to let world move on
+type TickDone struct {
+	Time time.Time
+	ack  func()
+}
+
+func (t *TickDone) Ack() {
+	t.ack()
+}
+
 type subscribeManager struct {
 	subs    []*subscription
 	seconds uint8
@@ -30,25 +50,43 @@ type subscription struct {
 	sRes   models.CandleResolution
 }
 
-func (p *subscribeManager) trigger(t time.Time, ch chan interface{}) {
+func (p *subscribeManager) trigger(t time.Time, emit func(item interface{})) {
 	p.seconds++
+	tenth := p.seconds >= 10
+	if tenth {
+		p.seconds = 0
+	}
+
 	for _, s := range p.subs {
 		switch s.sType {
 		case models.SubTypeTicker:
-			if p.seconds == 10 {
-				ch <- &Ticker{
+			if tenth {
+				emit(&Ticker{
+					Time:   t,
+					Symbol: s.symbol,
+				})
+			}
+		case models.SubTypeOrderBook:
+			if tenth {
+				emit(&OrderBook{
+					Time:   t,
+					Symbol: s.symbol,
+				})
+			}
+		case models.SubTypeTrades:
+			if tenth {
+				emit(&Trades{
 					Time:   t,
 					Symbol: s.symbol,
-				}
-				p.seconds = 0
+				})
 			}
 		case models.SubTypeCandle:
-			if checkResTiming(s.sRes.ToDuration(), t) {
-				ch <- &Candle{
+			if checkCandleTiming(s.sRes, t) {
+				emit(&Candle{
 					Time:   t,
 					Symbol: s.symbol,
 					Res:    s.sRes,
-				}
+				})
 			}
 		}
 	}
@@ -86,6 +124,38 @@ func (p *Plutos) SubscribeCandle(symbol string, resolution models.CandleResoluti
 	return s.id
 }
 
+func (p *Plutos) SubscribeOrderBook(symbol string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &subscription{
+		id:     uuid.Must(uuid.NewUUID()).String(),
+		symbol: symbol,
+		sType:  models.SubTypeOrderBook,
+		sRes:   "",
+	}
+
+	p.SubscribeManager.subs = append(p.SubscribeManager.subs, s)
+
+	return s.id
+}
+
+func (p *Plutos) SubscribeTrades(symbol string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &subscription{
+		id:     uuid.Must(uuid.NewUUID()).String(),
+		symbol: symbol,
+		sType:  models.SubTypeTrades,
+		sRes:   "",
+	}
+
+	p.SubscribeManager.subs = append(p.SubscribeManager.subs, s)
+
+	return s.id
+}
+
 func (p *Plutos) Unsubscribe(id string) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -1,6 +1,11 @@
 package mock
 
-import "DaruBot/internal/models"
+import (
+	"DaruBot/internal/models"
+	"math"
+	"testing"
+	"time"
+)
 
 func newPlutos(w *models.Wallets, currency string) *Plutos {
 	ors := make([]models.Order, 0)
@@ -20,5 +25,236 @@ func newPlutos(w *models.Wallets, currency string) *Plutos {
 		w.Update(wCur)
 	}
 
-	return NewPlutos(5, 0.2, currency, w, ors, pos)
+	return NewPlutos(5, Fees{Maker: 0.1, Taker: 0.2}, currency, w, ors, pos)
+}
+
+type fakePrice struct {
+	price float64
+}
+
+func (f *fakePrice) ticker(symbol string, curTime time.Time) (*models.Ticker, error) {
+	return &models.Ticker{Symbol: symbol, Price: f.price}, nil
+}
+
+func newTestPlutos(t *testing.T, price *fakePrice, wt models.WalletType, fees Fees) *Plutos {
+	w := &models.Wallets{WalletType: wt}
+	w.Update(&models.WalletCurrency{Name: currency, WalletType: wt, Balance: 1000, Available: 1000})
+
+	p := NewPlutos(5, fees, currency, w, []models.Order{}, []models.Position{})
+	p.SetTickerFunc(price.ticker)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-p.GetChan():
+			case <-done:
+				return
+			}
+		}
+	}()
+	t.Cleanup(func() { close(done) })
+
+	return p
+}
+
+func putMarginOrder(t *testing.T, p *Plutos, amount float64) {
+	_, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: amount, Margin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func equal(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMarginPosition(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeMargin, Fees{})
+
+	putMarginOrder(t, p, 10)
+
+	price.price = 200
+	putMarginOrder(t, p, 10)
+
+	pos := p.GetPositions()
+	if len(pos) != 1 {
+		t.Fatalf("expected 1 position, got %d", len(pos))
+	}
+	if !equal(pos[0].Amount, 20) || !equal(pos[0].Price, 150) {
+		t.Fatalf("wrong position %+v", pos[0])
+	}
+
+	w := p.wallets.Get(currency)
+	if !equal(w.Available, 1000-200-400) {
+		t.Fatalf("wrong collateral locked, available %v", w.Available)
+	}
+
+	// reduce by half and realize profit 10 * (200 - 150)
+	putMarginOrder(t, p, -10)
+
+	w = p.wallets.Get(currency)
+	if !equal(w.Balance, 1500) || !equal(w.Available, 1500-300) {
+		t.Fatalf("wrong wallet after reduce %+v", w)
+	}
+
+	// flip to short 5
+	putMarginOrder(t, p, -15)
+
+	pos = p.GetPositions()
+	if len(pos) != 1 || !equal(pos[0].Amount, -5) || !equal(pos[0].Price, 200) {
+		t.Fatalf("wrong flipped position %+v", pos)
+	}
+
+	w = p.wallets.Get(currency)
+	if !equal(w.Balance, 2000) || !equal(w.Available, 2000-200) {
+		t.Fatalf("wrong wallet after flip %+v", w)
+	}
+
+	price.price = 180
+	prev, err := p.ClosePosition(pos[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(prev.ProfitLoss, 100) {
+		t.Fatalf("expected profit 100, got %v", prev.ProfitLoss)
+	}
+
+	if len(p.GetPositions()) != 0 {
+		t.Fatal("position not closed")
+	}
+
+	w = p.wallets.Get(currency)
+	if !equal(w.Balance, 2100) || !equal(w.Available, 2100) {
+		t.Fatalf("wrong wallet after close %+v", w)
+	}
+
+	if _, err := p.ClosePosition(pos[0].ID); err != ErrPositionNotFound {
+		t.Fatalf("expected %v, got %v", ErrPositionNotFound, err)
+	}
+}
+
+func TestMarginLiquidation(t *testing.T) {
+	price := &fakePrice{price: 100}
+	p := newTestPlutos(t, price, models.WalletTypeMargin, Fees{})
+
+	putMarginOrder(t, p, 10)
+
+	pos := p.GetPositions()
+	liq := pos[0].LiqPrice
+	if liq <= 80 || liq >= 100 {
+		t.Fatalf("wrong liquidation price %v", liq)
+	}
+
+	price.price = liq + 1
+	p.mu.Lock()
+	err := p.processPositions()
+	p.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.GetPositions()) != 1 {
+		t.Fatal("position liquidated too early")
+	}
+
+	price.price = liq - 1
+	p.mu.Lock()
+	err = p.processPositions()
+	p.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.GetPositions()) != 0 {
+		t.Fatal("position not liquidated")
+	}
+
+	w := p.wallets.Get(currency)
+	if w.Balance < 800 || w.Balance > 1000 || !equal(w.Balance, w.Available) {
+		t.Fatalf("wrong wallet after liquidation %+v", w)
+	}
+}
+
+func TestFees(t *testing.T) {
+	price := &fakePrice{price: 100}
+
+	p := newTestPlutos(t, price, models.WalletTypeExchange, Fees{Maker: 0.1, Taker: 0.2})
+
+	o, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equal(o.Fee, 0.4) || o.FeeCurrency != currency {
+		t.Fatalf("wrong taker fee %v %s", o.Fee, o.FeeCurrency)
+	}
+
+	wc := p.wallets.Get(currency)
+	if !equal(wc.Balance, 1000-200-0.4) || !equal(wc.Available, wc.Balance) {
+		t.Fatalf("wrong wallet after buy %+v", wc)
+	}
+
+	if _, err = p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: -2, Price: 150}); err != nil {
+		t.Fatal(err)
+	}
+
+	price.price = 150
+	p.mu.Lock()
+	err = p.processOrders()
+	p.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := p.GetHistory()
+	if len(history) != 2 || !equal(history[1].Fee, 0.3) {
+		t.Fatalf("wrong maker fee %+v", history)
+	}
+
+	wc = p.wallets.Get(currency)
+	if !equal(wc.Balance, 1000-200-0.4+300-0.3) || !equal(wc.Available, wc.Balance) {
+		t.Fatalf("wrong wallet after sell %+v", wc)
+	}
+}
+
+func TestExecutions(t *testing.T) {
+	price := &fakePrice{price: 100}
+
+	w := &models.Wallets{WalletType: models.WalletTypeExchange}
+	w.Update(&models.WalletCurrency{Name: currency, WalletType: models.WalletTypeExchange, Balance: 1000, Available: 1000})
+	p := NewPlutos(5, Fees{Maker: 0.1, Taker: 0.2}, currency, w, []models.Order{}, []models.Position{})
+	p.SetTickerFunc(price.ticker)
+
+	buy, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, err := p.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: -2, Price: 150})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	price.price = 150
+	p.mu.Lock()
+	err = p.processOrders()
+	p.unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executions := make([]models.Execution, 0)
+	for len(p.GetChan()) > 0 {
+		if e, ok := (<-p.GetChan()).(*models.Execution); ok {
+			executions = append(executions, *e)
+		}
+	}
+
+	if len(executions) != 2 {
+		t.Fatalf("expected 2 executions, got %+v", executions)
+	}
+	if e := executions[0]; e.OrderID != buy.ID || e.Amount != 2 || e.Price != 100 || !equal(e.Fee, 0.4) || e.FeeCurrency != currency || e.Maker {
+		t.Fatalf("wrong buy execution %+v", e)
+	}
+	if e := executions[1]; e.OrderID != sell.ID || e.Amount != -2 || e.Price != 150 || !equal(e.Fee, 0.3) || !e.Maker || !equal(e.Cost(), 300) {
+		t.Fatalf("wrong sell execution %+v", e)
+	}
 }
@@ -0,0 +1,115 @@
+package mock
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/errors"
+	"github.com/markcheno/go-quote"
+	"time"
+)
+
+var (
+	ErrNoData = errors.New("NO CANDLES DATA")
+
+	// resolutions of go-quote used to aggregate not supported ones, biggest first
+	quoteBaseResolutions = []models.CandleResolution{
+		models.TwelveHours,
+		models.SixHours,
+		models.OneHour,
+		models.ThirtyMinutes,
+		models.FifteenMinutes,
+		models.FiveMinutes,
+		models.OneMinute,
+	}
+)
+
+// QuoteSource provide historical candles for mock exchange
+type QuoteSource interface {
+	// Name used as market name of candles cache
+	Name() string
+	// Load returns candles of symbol in period, ErrNoData if there is no candles
+	Load(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error)
+	Symbols() ([]string, error)
+}
+
+// NewSource returns local source if dataDir set, otherwise remote source of market
+func NewSource(market string, dataDir string) (QuoteSource, error) {
+	if dataDir != "" {
+		return NewLocalSource(dataDir)
+	}
+	return NewRemoteSource(market)
+}
+
+// remoteSource download candles by go-quote
+type remoteSource struct {
+	market   string
+	download quoteFunc
+}
+
+// NewRemoteSource returns source downloading candles from go-quote market (e.g. binance-usdt)
+func NewRemoteSource(market string) (QuoteSource, error) {
+	if !quote.ValidMarket(market) {
+		return nil, errors.New("market not supported")
+	}
+
+	download, err := quoteFuncByMarket(market)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteSource{
+		market:   market,
+		download: download,
+	}, nil
+}
+
+func (s *remoteSource) Name() string {
+	return s.market
+}
+
+func (s *remoteSource) Load(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	qRes, err := resolution.ToQuoteModel()
+	if err != nil {
+		// not supported by go-quote (e.g. 3h), aggregated from smaller resolution
+		for _, base := range quoteBaseResolutions {
+			if base.CanAggregate(resolution) {
+				src, err := s.Load(resolution.PeriodStart(from), to, symbol, base)
+				if err != nil {
+					return nil, err
+				}
+				return candles.Aggregate(src, resolution), nil
+			}
+		}
+		return nil, err
+	}
+
+	format := timeFormat
+	if resolution.ToDuration() >= models.OneDay.ToDuration() {
+		format = timeFormatD
+	}
+
+	start := quoteFormat(from.UTC(), format)
+	end := quoteFormat(to.UTC(), format)
+
+	q, err := s.download(symbol, start, end, qRes)
+	if err != nil {
+		return nil, err
+	}
+
+	cndls := models.QuoteToModels(&q, symbol)
+
+	if len(cndls.Candles) == 0 {
+		return nil, ErrNoData
+	}
+
+	if len(cndls.Candles) == 1 {
+		cndls.Resolution = resolution
+		cndls.Candles[0].Resolution = resolution
+	}
+
+	return cndls, nil
+}
+
+func (s *remoteSource) Symbols() ([]string, error) {
+	return quote.NewMarketList(s.market)
+}
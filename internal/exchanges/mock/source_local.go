@@ -0,0 +1,297 @@
+package mock
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/errors"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnknownDataFormat = errors.New("UNKNOWN CANDLES DATA FORMAT")
+)
+
+// LocalSource load candles from files of directory, so backtests can run offline.
+// File name is <symbol>_<resolution>.<csv|json> (e.g. BTCUSDT_1m.csv, BTC_USD_5m.csv), supported formats:
+// go-quote csv, Binance klines csv (with or without header) and json of models.Candles.
+// Resolution not found in files aggregated from smaller one
+type LocalSource struct {
+	dir string
+
+	mu     *sync.Mutex
+	files  map[string]map[models.CandleResolution]string // normalized symbol to files by resolution
+	names  map[string]string                             // normalized symbol to symbol from file name
+	loaded map[string]*models.Candles
+}
+
+func NewLocalSource(dir string) (*LocalSource, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &LocalSource{
+		dir:    dir,
+		mu:     &sync.Mutex{},
+		files:  make(map[string]map[models.CandleResolution]string),
+		names:  make(map[string]string),
+		loaded: make(map[string]*models.Candles),
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if ext != ".csv" && ext != ".json" {
+			continue
+		}
+
+		base := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		i := strings.LastIndex(base, "_")
+		if i <= 0 {
+			continue
+		}
+
+		res, err := models.CandleResolutionFromString(base[i+1:])
+		if err != nil {
+			continue
+		}
+
+		symbol := base[:i]
+		key := normalizeSymbol(symbol)
+
+		if _, ok := s.files[key]; !ok {
+			s.files[key] = make(map[models.CandleResolution]string)
+		}
+		s.files[key][res] = filepath.Join(dir, e.Name())
+		s.names[key] = symbol
+	}
+
+	if len(s.files) == 0 {
+		return nil, errors.WrapMessage(ErrNoData, fmt.Sprintf("no candles files in %s", dir))
+	}
+
+	return s, nil
+}
+
+func normalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", "_", "", "/", "", ":", "").Replace(symbol))
+}
+
+func (s *LocalSource) Name() string {
+	return "local:" + s.dir
+}
+
+func (s *LocalSource) Symbols() ([]string, error) {
+	rs := make([]string, 0, len(s.names))
+	for _, name := range s.names {
+		rs = append(rs, name)
+	}
+	sort.Strings(rs)
+
+	return rs, nil
+}
+
+func (s *LocalSource) Load(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	all, err := s.candles(symbol, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &models.Candles{
+		Symbol:     symbol,
+		Resolution: resolution,
+		Candles:    make([]*models.Candle, 0),
+	}
+
+	i := sort.Search(len(all.Candles), func(i int) bool {
+		return !all.Candles[i].Date.Before(from)
+	})
+	for ; i < len(all.Candles) && !all.Candles[i].Date.After(to); i++ {
+		c := *all.Candles[i]
+		c.Symbol = symbol
+		rs.Candles = append(rs.Candles, &c)
+	}
+
+	if len(rs.Candles) == 0 {
+		return nil, ErrNoData
+	}
+
+	return rs, nil
+}
+
+// candles returns all candles of symbol with resolution, parsed files are kept in memory
+func (s *LocalSource) candles(symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := normalizeSymbol(symbol)
+	loadedKey := key + "_" + resolution.String()
+
+	if c, ok := s.loaded[loadedKey]; ok {
+		return c, nil
+	}
+
+	files, ok := s.files[key]
+	if !ok {
+		return nil, errors.WrapMessage(ErrNoData, symbol)
+	}
+
+	var rs *models.Candles
+	var err error
+
+	if path, ok := files[resolution]; ok {
+		rs, err = readCandlesFile(path, symbol, resolution)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// biggest resolution which can be aggregated
+		var base models.CandleResolution
+		for res := range files {
+			if res.CanAggregate(resolution) && (base == "" || res.ToDuration() > base.ToDuration()) {
+				base = res
+			}
+		}
+		if base == "" {
+			return nil, errors.WrapMessage(ErrNoData, fmt.Sprintf("%s %s", symbol, resolution))
+		}
+
+		src, err := readCandlesFile(files[base], symbol, base)
+		if err != nil {
+			return nil, err
+		}
+		rs = candles.Aggregate(src, resolution)
+	}
+
+	s.loaded[loadedKey] = rs
+
+	return rs, nil
+}
+
+func readCandlesFile(path string, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rs *models.Candles
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		rs, err = readCandlesJSON(f)
+	} else {
+		rs, err = readCandlesCSV(f)
+	}
+	if err != nil {
+		return nil, errors.WrapMessage(err, path)
+	}
+
+	rs.Symbol = symbol
+	rs.Resolution = resolution
+
+	for _, c := range rs.Candles {
+		c.Symbol = symbol
+		c.Resolution = resolution
+	}
+
+	sort.Slice(rs.Candles, func(i, j int) bool {
+		return rs.Candles[i].Date.Before(rs.Candles[j].Date)
+	})
+
+	return rs, nil
+}
+
+func readCandlesJSON(r io.Reader) (*models.Candles, error) {
+	rs := &models.Candles{}
+	if err := json.NewDecoder(r).Decode(rs); err != nil {
+		return nil, err
+	}
+	for _, c := range rs.Candles {
+		c.Date = c.Date.Local()
+	}
+	return rs, nil
+}
+
+// readCandlesCSV parse go-quote csv (datetime,open,high,low,close,volume)
+// or Binance klines csv (open_time,open,high,low,close,volume,...)
+func readCandlesCSV(r io.Reader) (*models.Candles, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	rs := &models.Candles{
+		Candles: make([]*models.Candle, 0),
+	}
+
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 6 {
+			return nil, errors.WrapMessage(ErrUnknownDataFormat, fmt.Sprintf("line %d", line))
+		}
+
+		date, err := parseCandleDate(record[0])
+		if err != nil {
+			if line == 1 {
+				// header
+				continue
+			}
+			return nil, errors.WrapMessage(ErrUnknownDataFormat, fmt.Sprintf("line %d: %v", line, err))
+		}
+
+		c := &models.Candle{Date: date}
+		values := []*float64{&c.Open, &c.High, &c.Low, &c.Close, &c.Volume}
+		for i, v := range values {
+			*v, err = strconv.ParseFloat(record[i+1], 64)
+			if err != nil {
+				return nil, errors.WrapMessage(ErrUnknownDataFormat, fmt.Sprintf("line %d: %v", line, err))
+			}
+		}
+
+		rs.Candles = append(rs.Candles, c)
+	}
+
+	return rs, nil
+}
+
+// parseCandleDate parse go-quote date in UTC or unix timestamp in seconds, milliseconds or microseconds
+func parseCandleDate(s string) (time.Time, error) {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		switch {
+		case ts > 1e14:
+			return time.Unix(0, ts*int64(time.Microsecond)), nil
+		case ts > 1e11:
+			return time.Unix(0, ts*int64(time.Millisecond)), nil
+		default:
+			return time.Unix(ts, 0), nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Local(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("wrong date %q", s)
+}
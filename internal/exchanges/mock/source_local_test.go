@@ -0,0 +1,158 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"DaruBot/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const (
+	goQuoteCSV = `datetime,open,high,low,close,volume
+2021-02-20 00:00,100.00,110.00,95.00,105.00,10.00
+2021-02-20 00:01,105.00,106.00,101.00,102.00,5.00
+2021-02-20 00:02,102.00,103.00,99.00,100.00,1.00
+2021-02-20 00:03,100.00,104.00,100.00,103.00,2.00
+2021-02-20 00:04,103.00,103.00,90.00,91.00,3.00
+2021-02-20 00:05,91.00,92.00,91.00,92.00,4.00
+`
+	// 2021-02-20 00:00 and 00:01 UTC, without header
+	binanceCSV = `1613779200000,50000.00,50100.00,49900.00,50050.00,12.5,1613779259999,625000.0,100,6.0,300000.0,0
+1613779260000,50050.00,50200.00,50000.00,50150.00,7.5,1613779319999,376000.0,80,3.0,150000.0,0
+`
+	jsonCandles = `{"Symbol":"ETHUSD","Resolution":"1h","Candles":[
+{"Date":"2021-02-20T01:00:00Z","Open":2,"Close":3,"High":4,"Low":1,"Volume":20},
+{"Date":"2021-02-20T00:00:00Z","Open":1,"Close":2,"High":3,"Low":1,"Volume":10}
+]}`
+)
+
+func newTestLocalSource(t *testing.T) *LocalSource {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "candles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	files := map[string]string{
+		"BTC_USD_1m.csv":  goQuoteCSV,
+		"BTCUSDT_1m.csv":  binanceCSV,
+		"ETHUSD_1h.json":  jsonCandles,
+		"README.md":       "not candles",
+		"BTC_USD_bad.csv": "wrong resolution",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := NewLocalSource(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func TestLocalSource(t *testing.T) {
+	s := newTestLocalSource(t)
+
+	symbols, err := s.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(symbols) != 3 || symbols[0] != "BTCUSDT" || symbols[1] != "BTC_USD" || symbols[2] != "ETHUSD" {
+		t.Fatalf("wrong symbols %v", symbols)
+	}
+
+	day := time.Date(2021, 2, 20, 0, 0, 0, 0, time.UTC)
+
+	t.Run("go-quote", func(t *testing.T) {
+		c, err := s.Load(day.Add(time.Minute), day.Add(3*time.Minute), "BTC-USD", models.OneMinute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Symbol != "BTC-USD" || c.Resolution != models.OneMinute {
+			t.Fatalf("wrong candles %s %s", c.Symbol, c.Resolution)
+		}
+		if len(c.Candles) != 3 {
+			t.Fatalf("expected 3 candles, got %d", len(c.Candles))
+		}
+		first := c.Candles[0]
+		if !first.Date.Equal(day.Add(time.Minute)) || first.Open != 105 || first.High != 106 || first.Low != 101 || first.Close != 102 || first.Volume != 5 {
+			t.Fatalf("wrong candle %+v", first)
+		}
+		if first.Symbol != "BTC-USD" {
+			t.Fatalf("wrong candle symbol %s", first.Symbol)
+		}
+	})
+
+	t.Run("binance", func(t *testing.T) {
+		c, err := s.Load(day, day.Add(time.Hour), "btcusdt", models.OneMinute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Candles) != 2 {
+			t.Fatalf("expected 2 candles, got %d", len(c.Candles))
+		}
+		last := c.Candles[1]
+		if !last.Date.Equal(day.Add(time.Minute)) || last.Open != 50050 || last.Close != 50150 || last.Volume != 7.5 {
+			t.Fatalf("wrong candle %+v", last)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		c, err := s.Load(day, day.Add(time.Hour), "ETHUSD", models.OneHour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Candles) != 2 {
+			t.Fatalf("expected 2 candles, got %d", len(c.Candles))
+		}
+		if !c.Candles[0].Date.Equal(day) || c.Candles[0].Open != 1 || c.Candles[1].Volume != 20 {
+			t.Fatalf("candles not sorted %+v %+v", c.Candles[0], c.Candles[1])
+		}
+	})
+
+	t.Run("aggregate", func(t *testing.T) {
+		c, err := s.Load(day, day.Add(time.Hour), "BTC_USD", models.FiveMinutes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Candles) != 2 {
+			t.Fatalf("expected 2 candles, got %d", len(c.Candles))
+		}
+		first := c.Candles[0]
+		if !first.Date.Equal(day) || first.Open != 100 || first.High != 110 || first.Low != 90 || first.Close != 91 || first.Volume != 21 {
+			t.Fatalf("wrong aggregated candle %+v", first)
+		}
+		if first.Resolution != models.FiveMinutes {
+			t.Fatalf("wrong resolution %s", first.Resolution)
+		}
+	})
+
+	t.Run("no data", func(t *testing.T) {
+		_, err := s.Load(day.Add(24*time.Hour), day.Add(48*time.Hour), "BTC_USD", models.OneMinute)
+		if errors.Cause(err) != ErrNoData {
+			t.Fatalf("expected ErrNoData, got %v", err)
+		}
+
+		_, err = s.Load(day, day.Add(time.Hour), "XRPUSD", models.OneMinute)
+		if errors.Cause(err) != ErrNoData {
+			t.Fatalf("expected ErrNoData, got %v", err)
+		}
+
+		// smaller resolution can't be made from bigger one
+		_, err = s.Load(day, day.Add(time.Hour), "ETHUSD", models.OneMinute)
+		if errors.Cause(err) != ErrNoData {
+			t.Fatalf("expected ErrNoData, got %v", err)
+		}
+	})
+}
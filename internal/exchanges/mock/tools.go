@@ -2,7 +2,10 @@ package mock
 
 import (
 	"DaruBot/internal/models"
+	"fmt"
+	"github.com/markcheno/go-quote"
 	"math"
+	"strings"
 	"time"
 )
 
@@ -11,6 +14,7 @@ var (
 	timeFormatD = "2006-01-02"
 )
 
+// getCandle returns candle of period containing t, the last candle if t is zero
 func getCandle(q *models.Candles, t time.Time) *models.Candle {
 	if len(q.Candles) == 0 {
 		return nil
@@ -20,12 +24,14 @@ func getCandle(q *models.Candles, t time.Time) *models.Candle {
 		return q.Candles[len(q.Candles)-1]
 	}
 
-	d := q.Resolution.ToDuration()
-
-	for i := 0; i < len(q.Candles); i++ {
-		if q.Candles[i].Date.Sub(t) <= d {
+	for i := len(q.Candles) - 1; i >= 0; i-- {
+		if q.Candles[i].Date.After(t) {
+			continue
+		}
+		if t.Before(q.Resolution.NextPeriod(q.Candles[i].Date)) {
 			return q.Candles[i]
 		}
+		return nil
 	}
 
 	return nil
@@ -35,40 +41,41 @@ func quoteFormat(t time.Time, format string) string {
 	return t.Format(format)
 }
 
-func downloadQuote(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
-	qRes, err := resolution.ToQuoteModel()
-	if err != nil {
-		return nil, err
-	}
-
-	format := timeFormat
-	if resolution.ToDuration() >= models.OneDay.ToDuration() {
-		format = timeFormatD
-	}
-
-	start := quoteFormat(from.UTC(), format)
-	end := quoteFormat(to.UTC(), format)
+// checkCandleTiming returns true if t is start of candle period, weeks and months are calendar periods
+func checkCandleTiming(res models.CandleResolution, t time.Time) bool {
+	return res.IsPeriodStart(t)
+}
 
-	q, err := downloadCandles(symbol, start, end, qRes)
-	if err != nil {
-		return nil, err
+func checkResTiming(d time.Duration, t time.Time) bool {
+	switch {
+	case math.Mod(float64(t.Unix()), d.Seconds()) == 0:
+		return true
+	default:
+		return false
 	}
+}
 
-	cndls := models.QuoteToModels(&q, symbol)
+// tickerPrice emulate price inside minute candle, price depends only on time, so runs are reproducible
+func tickerPrice(c *models.Candle, t time.Time) float64 {
+	// splitmix64
+	x := uint64(t.UnixNano()) + 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	x = x ^ (x >> 31)
 
-	if len(cndls.Candles) == 1 {
-		cndls.Resolution = resolution
-		cndls.Candles[0].Resolution = resolution
-	}
+	frac := float64(x>>11) / (1 << 53)
 
-	return cndls, nil
+	return c.Low + frac*(c.High-c.Low)
 }
 
-func checkResTiming(d time.Duration, t time.Time) bool {
+// quoteFuncByMarket returns go-quote download function for market
+func quoteFuncByMarket(market string) (quoteFunc, error) {
 	switch {
-	case math.Mod(float64(t.Unix()), d.Seconds()) == 0:
-		return true
+	case strings.HasPrefix(market, "binance"):
+		return quote.NewQuoteFromBinance, nil
+	case market == "coinbase":
+		return quote.NewQuoteFromCoinbase, nil
 	default:
-		return false
+		return nil, fmt.Errorf("market %s not supported", market)
 	}
 }
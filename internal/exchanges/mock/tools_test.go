@@ -0,0 +1,53 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"testing"
+	"time"
+)
+
+func TestGetCandleMonth(t *testing.T) {
+	q := &models.Candles{Symbol: "BTCUSD", Resolution: models.OneMonth}
+	for m := time.November; m <= time.December+2; m++ {
+		q.Candles = append(q.Candles, &models.Candle{Date: time.Date(2020, m, 1, 0, 0, 0, 0, time.UTC), Close: float64(m)})
+	}
+
+	tests := map[time.Time]float64{
+		time.Date(2020, time.November, 30, 23, 0, 0, 0, time.UTC): float64(time.November),
+		time.Date(2020, time.December, 31, 12, 0, 0, 0, time.UTC): float64(time.December),
+		time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC):    float64(time.December + 1),
+		time.Date(2021, time.February, 28, 0, 0, 0, 0, time.UTC):  float64(time.December + 2),
+	}
+	for date, want := range tests {
+		c := getCandle(q, date)
+		if c == nil || c.Close != want {
+			t.Fatalf("wrong candle for %s: %+v", date, c)
+		}
+	}
+
+	if c := getCandle(q, time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)); c != nil {
+		t.Fatalf("candle of not loaded period: %+v", c)
+	}
+	if c := getCandle(q, time.Date(2020, time.October, 31, 0, 0, 0, 0, time.UTC)); c != nil {
+		t.Fatalf("candle of not loaded period: %+v", c)
+	}
+}
+
+func TestCheckCandleTiming(t *testing.T) {
+	if !checkCandleTiming(models.OneMonth, time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("month start not detected")
+	}
+	if checkCandleTiming(models.OneMonth, time.Date(2021, time.March, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("28 days after month start detected as month start")
+	}
+	// 2021-01-04 is Monday
+	if !checkCandleTiming(models.OneWeek, time.Date(2021, time.January, 4, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("week start not detected")
+	}
+	if checkCandleTiming(models.OneWeek, time.Date(2021, time.January, 7, 0, 0, 0, 0, time.UTC)) {
+		t.Fatal("thursday detected as week start")
+	}
+	if !checkCandleTiming(models.FiveMinutes, time.Date(2021, time.January, 7, 0, 5, 0, 0, time.UTC)) {
+		t.Fatal("5m start not detected")
+	}
+}
@@ -0,0 +1,30 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"fmt"
+	"time"
+)
+
+const (
+	tradesPerMinute = 6 // synthetic trades are emitted every ten seconds
+)
+
+// syntheticTrade returns trade by ticker price of minute candle, amount is part of candle volume,
+// trade is sell if price below open of candle
+func syntheticTrade(c *models.Candle, t time.Time) *models.Trade {
+	price := tickerPrice(c, t)
+
+	amount := c.Volume / tradesPerMinute
+	if price < c.Open {
+		amount = -amount
+	}
+
+	return &models.Trade{
+		ID:     fmt.Sprint(t.UnixNano()),
+		Symbol: c.Symbol,
+		Date:   t,
+		Price:  price,
+		Amount: amount,
+	}
+}
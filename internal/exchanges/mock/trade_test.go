@@ -0,0 +1,27 @@
+package mock
+
+import (
+	"DaruBot/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSyntheticTrade(t *testing.T) {
+	date := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	c := &models.Candle{Symbol: testPair, Resolution: models.OneMinute, Date: date, Open: 100, High: 110, Low: 90, Close: 105, Volume: 60}
+
+	for i := 0; i < tradesPerMinute; i++ {
+		tm := date.Add(time.Duration(i*10) * time.Second)
+		tr := syntheticTrade(c, tm)
+
+		if tr.Price < c.Low || tr.Price > c.High {
+			t.Fatalf("price out of candle %v", tr.Price)
+		}
+		if tr.IsSell() != (tr.Price < c.Open) || tr.Amount*tr.Amount != 100 {
+			t.Fatalf("wrong amount %v by price %v", tr.Amount, tr.Price)
+		}
+		if tr.Symbol != testPair || !tr.Date.Equal(tm) {
+			t.Fatalf("wrong trade %+v", tr)
+		}
+	}
+}
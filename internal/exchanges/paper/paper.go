@@ -0,0 +1,534 @@
+/*
+Paper exchange trade on simulated account of Plutos with market data of another exchange (live or recorded),
+so strategies can run forward in real time without risking funds.
+Tickers, candles and subscriptions are passed to market exchange, orders and positions are simulated,
+account saved to storage and restored on next start.
+*/
+package paper
+
+import (
+	"DaruBot/internal/config"
+	exchanges2 "DaruBot/internal/exchanges"
+	"DaruBot/internal/exchanges/mock"
+	"DaruBot/internal/models"
+	"DaruBot/internal/models/exchanges"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"DaruBot/storage"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	stateKey      = "account"
+	marketWatcher = "paper_market"
+
+	minInterval = time.Second
+)
+
+var (
+	ErrNoTicker = errors.New("NO STREAMED TICKER OF SYMBOL")
+
+	supportEvents = watcher.EventsMap{
+		models.EventError,
+
+		models.EventTickerState,
+		models.EventCandleState,
+		models.EventOrderBookUpdate,
+		models.EventTrade,
+
+		models.EventOrderNew,
+		models.EventOrderFilled,
+		models.EventOrderCancel,
+		models.EventOrderPartiallyFilled,
+		models.EventOrderUpdate,
+
+		models.EventOwnTradeExecuted,
+
+		models.EventPositionNew,
+		models.EventPositionClosed,
+		models.EventPositionUpdate,
+
+		models.EventWalletUpdate,
+	}
+)
+
+type queuedEvent struct {
+	head    watcher.EventHead
+	payload interface{}
+}
+
+type exchange struct {
+	market     exchanges2.CryptoExchange
+	marketName string
+	plutos     *mock.Plutos
+	store      storage.CustomStorage
+	interval   time.Duration
+
+	ctx context.Context
+	log logger.Logger
+
+	watchers *watcher.Manager
+
+	mu         *sync.Mutex
+	ready      bool
+	readyChan  chan interface{}
+	stop       chan struct{}
+	done       chan struct{}
+	dirty      bool
+	lastUpdate time.Time
+
+	// last streamed tickers of market, Plutos fills orders by them without requests under its lock
+	tickers map[string]models.Ticker
+	watched map[string]bool
+
+	// events queue of market and errors, so watcher pipe of market exchange never blocks
+	queue       []queuedEvent
+	queueNotify chan struct{}
+}
+
+// NewPaper returns paper exchange, market is exchange with name marketName used for market data,
+// it connected and disconnected with paper exchange. Account is not saved if st is nil
+func NewPaper(ctx context.Context,
+	wManager *watcher.Manager,
+	lg logger.Logger,
+	cfg config.Configurations,
+	market exchanges2.CryptoExchange, marketName string,
+	st storage.CustomStorage) (exchanges2.CryptoExchange, error) {
+	return newPaper(ctx, wManager, lg, cfg, market, marketName, st)
+}
+
+func newPaper(ctx context.Context,
+	wManager *watcher.Manager,
+	lg logger.Logger,
+	cfg config.Configurations,
+	market exchanges2.CryptoExchange, marketName string,
+	st storage.CustomStorage) (*exchange, error) {
+
+	pCfg := cfg.Exchanges.Paper
+	fees := mock.Fees{Maker: pCfg.MakerFee, Taker: pCfg.TakerFee}
+	log := lg.WithPrefix("exchange", "Paper")
+
+	var plutos *mock.Plutos
+
+	state := mock.PlutosState{}
+	err := storage.ErrNotFound
+	if st != nil {
+		err = st.Load(stateKey, &state)
+	}
+	switch {
+	case err == nil:
+		plutos = mock.NewPlutosFromState(pCfg.MaxLeverage, fees, pCfg.Currency, state)
+		log.Infof("account restored, %d orders, %d positions", len(state.Orders), len(state.Positions))
+	case errors.Cause(err) == storage.ErrNotFound:
+		w := &models.Wallets{WalletType: models.WalletTypeExchange}
+		w.Update(&models.WalletCurrency{
+			Name:       pCfg.Currency,
+			WalletType: models.WalletTypeExchange,
+			Balance:    pCfg.Deposit,
+			Available:  pCfg.Deposit,
+		})
+		plutos = mock.NewPlutos(pCfg.MaxLeverage, fees, pCfg.Currency, w, []models.Order{}, []models.Position{})
+		log.Infof("new account, deposit %v %s", pCfg.Deposit, pCfg.Currency)
+	default:
+		return nil, errors.WrapMessage(err, "load paper account")
+	}
+
+	interval := pCfg.Interval.Truncate(time.Second)
+	if interval < minInterval {
+		interval = minInterval
+	}
+
+	plutos.SetFillModel(mock.SlippageFill{Fixed: pCfg.Slippage, Percent: pCfg.SlippagePct})
+	plutos.SetProcessInterval(interval)
+	if err := wManager.RegisterEvents(exchanges.ExchangeTypePaper.String(), supportEvents); err != nil {
+		return nil, err
+	}
+
+	rs := &exchange{
+		market:      market,
+		marketName:  marketName,
+		plutos:      plutos,
+		store:       st,
+		interval:    interval,
+		ctx:         ctx,
+		log:         log,
+		watchers:    wManager,
+		mu:          &sync.Mutex{},
+		readyChan:   make(chan interface{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		queueNotify: make(chan struct{}, 1),
+		tickers:     make(map[string]models.Ticker),
+		watched:     make(map[string]bool),
+	}
+
+	plutos.SetTickerFunc(rs.lastTicker)
+	// market data errors must not stop simulation
+	plutos.SetErrorHandler(func(err error) {
+		rs.log.Error("process orders", err)
+		rs.enqueue(models.EventError, err)
+	})
+
+	return rs, nil
+}
+
+func (e *exchange) Connect() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.ready {
+		return nil
+	}
+
+	if !e.market.IsReady() {
+		if err := e.market.Connect(); err != nil {
+			return err
+		}
+	}
+
+	wh, err := e.watchers.New(marketWatcher, models.EventsModuleExchange, e.marketName,
+		models.EventTickerState, models.EventCandleState, models.EventOrderBookUpdate, models.EventTrade, models.EventError)
+	if err != nil {
+		return err
+	}
+	pipe := wh.Listen()
+
+	go func() {
+		for evt := range pipe {
+			if t, ok := evt.Payload.(models.Ticker); ok {
+				e.setTicker(t, true)
+			}
+			e.enqueue(evt.EventHead, evt.Payload)
+		}
+	}()
+
+	go e.work()
+
+	e.ready = true
+	close(e.readyChan)
+
+	return nil
+}
+
+func (e *exchange) work() {
+	defer close(e.done)
+
+	// restored orders and positions are processed by streamed tickers too
+	for _, o := range e.plutos.GetOrders() {
+		if err := e.watchTicker(o.Symbol); err != nil {
+			e.log.Error("watch ticker", o.Symbol, err)
+		}
+	}
+	for _, p := range e.plutos.GetPositions() {
+		if err := e.watchTicker(p.Symbol); err != nil {
+			e.log.Error("watch ticker", p.Symbol, err)
+		}
+	}
+
+	// time aligned to interval, so plutos process orders on every tick
+	sec := int64(e.interval / time.Second)
+	ticks := make(chan time.Time, 1)
+	ticks <- time.Unix(time.Now().Unix()/sec*sec, 0)
+
+	go e.plutos.Listen(ticks, nil)
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case t := <-ticker.C:
+			select {
+			case ticks <- time.Unix(t.Unix()/sec*sec, 0):
+			default:
+			}
+			e.save(false)
+		case data := <-e.plutos.GetChan():
+			if head, payload, ok := mock.PlutosEvent(data); ok {
+				e.emmit(head, payload)
+
+				e.mu.Lock()
+				e.dirty = true
+				e.mu.Unlock()
+			}
+		case <-e.queueNotify:
+			e.mu.Lock()
+			queue := e.queue
+			e.queue = nil
+			e.mu.Unlock()
+
+			for _, evt := range queue {
+				e.emmit(evt.head, evt.payload)
+			}
+		case <-e.stop:
+			return
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+// watchTicker subscribe ticker of symbol once, current ticker requested until first update is streamed
+func (e *exchange) watchTicker(symbol string) error {
+	e.mu.Lock()
+	watched := e.watched[symbol]
+	e.mu.Unlock()
+
+	if !watched {
+		if _, err := e.market.SubscribeTicker(symbol); err != nil {
+			return err
+		}
+
+		e.mu.Lock()
+		e.watched[symbol] = true
+		e.mu.Unlock()
+	}
+
+	if _, err := e.lastTicker(symbol, time.Time{}); err == nil {
+		return nil
+	}
+
+	t, err := e.market.GetTicker(symbol)
+	if err != nil {
+		return err
+	}
+	e.setTicker(*t, false)
+
+	return nil
+}
+
+// setTicker save ticker of market, requested one does not replace streamed
+func (e *exchange) setTicker(t models.Ticker, streamed bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, ok := e.tickers[t.Symbol]; ok && !streamed {
+		return
+	}
+	e.tickers[t.Symbol] = t
+}
+
+func (e *exchange) lastTicker(symbol string, _ time.Time) (*models.Ticker, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	t, ok := e.tickers[symbol]
+	if !ok {
+		return nil, errors.WrapMessage(ErrNoTicker, symbol)
+	}
+
+	return &t, nil
+}
+
+// enqueue event to be emitted by work, never blocks
+func (e *exchange) enqueue(head watcher.EventHead, payload interface{}) {
+	e.mu.Lock()
+	e.queue = append(e.queue, queuedEvent{head: head, payload: payload})
+	e.mu.Unlock()
+
+	select {
+	case e.queueNotify <- struct{}{}:
+	default:
+	}
+}
+
+// save account to storage if it changed or force
+func (e *exchange) save(force bool) {
+	e.mu.Lock()
+	dirty := e.dirty
+	e.dirty = false
+	e.mu.Unlock()
+
+	if (!dirty && !force) || e.store == nil {
+		return
+	}
+
+	if err := e.store.Save(stateKey, e.plutos.State()); err != nil {
+		e.log.Error("save paper account", err)
+	}
+}
+
+func (e *exchange) emmit(eventHead watcher.EventHead, data interface{}) {
+	err := e.watchers.Emmit(watcher.BuildEvent(eventHead, exchanges.ExchangeTypePaper.String(), data))
+	if err != nil {
+		e.log.Error(err)
+	}
+}
+
+func (e *exchange) Disconnect() {
+	e.mu.Lock()
+	if !e.ready {
+		e.mu.Unlock()
+		return
+	}
+	e.ready = false
+	e.readyChan = make(chan interface{}, 1)
+	e.mu.Unlock()
+
+	close(e.stop)
+	<-e.done
+	e.plutos.Stop()
+	e.watchers.Remove(marketWatcher)
+
+	// events are not needed anymore, plutos may be blocked on full channel
+	for len(e.plutos.GetChan()) > 0 {
+		<-e.plutos.GetChan()
+	}
+
+	e.save(true)
+
+	e.market.Disconnect()
+}
+
+func (e *exchange) IsReady() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.ready
+}
+
+func (e *exchange) Ready() <-chan interface{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.readyChan
+}
+
+func (e *exchange) SupportEvents() watcher.EventsMap {
+	return supportEvents
+}
+
+func (e *exchange) GetTicker(symbol string) (*models.Ticker, error) {
+	return e.market.GetTicker(symbol)
+}
+
+func (e *exchange) GetLastCandle(symbol string, resolution models.CandleResolution) (*models.Candle, error) {
+	return e.market.GetLastCandle(symbol, resolution)
+}
+
+func (e *exchange) GetCandles(symbol string, resolution models.CandleResolution, from time.Time, to time.Time) (*models.Candles, error) {
+	return e.market.GetCandles(symbol, resolution, from, to)
+}
+
+func (e *exchange) GetSubscriptions() *models.Subscriptions {
+	return e.market.GetSubscriptions()
+}
+
+func (e *exchange) SubscribeTicker(symbol string) (subID string, err error) {
+	return e.market.SubscribeTicker(symbol)
+}
+
+func (e *exchange) SubscribeCandles(symbol string, resolution models.CandleResolution) (subID string, err error) {
+	return e.market.SubscribeCandles(symbol, resolution)
+}
+
+func (e *exchange) SubscribeOrderBook(symbol string, precision models.BookPrecision, depth int) (subID string, err error) {
+	return e.market.SubscribeOrderBook(symbol, precision, depth)
+}
+
+func (e *exchange) SubscribeTrades(symbol string) (subID string, err error) {
+	return e.market.SubscribeTrades(symbol)
+}
+
+func (e *exchange) GetOrderBook(symbol string) (*models.OrderBook, error) {
+	return e.market.GetOrderBook(symbol)
+}
+
+func (e *exchange) Unsubscribe(subID string) error {
+	return e.market.Unsubscribe(subID)
+}
+
+func (e *exchange) CheckSymbol(symbol string, margin bool) error {
+	return e.market.CheckSymbol(symbol, margin)
+}
+
+func (e *exchange) GetOrders() ([]*models.Order, error) {
+	return e.plutos.GetOrders(), nil
+}
+
+func (e *exchange) GetPositions() ([]*models.Position, error) {
+	return e.plutos.GetPositions(), nil
+}
+
+func (e *exchange) GetWallets() ([]*models.Wallets, error) {
+	return e.plutos.GetWallets(), nil
+}
+
+func (e *exchange) GetBalance() (*models.BalanceUSD, error) {
+	return e.plutos.GetBalance(time.Now())
+}
+
+func (e *exchange) HasUpdates(t time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return t.Before(e.lastUpdate)
+}
+
+func (e *exchange) updated() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.lastUpdate = time.Now()
+}
+
+func (e *exchange) PutOrder(order *models.PutOrder) (*models.Order, error) {
+	if err := e.watchTicker(order.Symbol); err != nil {
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+
+	o, err := e.plutos.PutOrder(order)
+	if err != nil {
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.updated()
+
+	return &o, nil
+}
+
+// UpdateOrder if price, priceStop and amount equals 0 - request do nothing
+func (e *exchange) UpdateOrder(orderID string, price float64, priceStop float64, amount float64) (*models.Order, error) {
+	o, err := e.plutos.UpdateOrder(orderID, price, priceStop, amount)
+	if err != nil {
+		if err == mock.ErrOrderNotFound {
+			return nil, exchanges2.ErrOrderNotFound
+		}
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.updated()
+
+	return &o, nil
+}
+
+func (e *exchange) CancelOrder(order *models.Order) error {
+	_, err := e.plutos.CancelOrder(order.ID)
+	if err != nil {
+		if err == mock.ErrOrderNotFound {
+			return exchanges2.ErrOrderNotFound
+		}
+		return errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.updated()
+
+	return nil
+}
+
+func (e *exchange) ClosePosition(position *models.Position) (*models.Position, error) {
+	if err := e.watchTicker(position.Symbol); err != nil {
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+
+	pos, err := e.plutos.ClosePosition(position.ID)
+	if err != nil {
+		if err == mock.ErrPositionNotFound {
+			return nil, exchanges2.ErrPositionNotFound
+		}
+		return nil, errors.WrapMessage(exchanges2.ErrRequestError, err)
+	}
+	e.updated()
+
+	return &pos, nil
+}
@@ -0,0 +1,245 @@
+package paper
+
+import (
+	"DaruBot/internal/config"
+	exchanges2 "DaruBot/internal/exchanges"
+	"DaruBot/internal/models"
+	"DaruBot/internal/models/exchanges"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"DaruBot/storage"
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testPair   = "BTCUSD"
+	marketName = "Fake"
+)
+
+type fakeMarket struct {
+	exchanges2.CryptoExchange
+
+	mu       *sync.Mutex
+	price    float64
+	ready    bool
+	requests int
+	subs     []string
+}
+
+func (m *fakeMarket) Connect() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ready = true
+	return nil
+}
+
+func (m *fakeMarket) Disconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ready = false
+}
+
+func (m *fakeMarket) IsReady() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ready
+}
+
+func (m *fakeMarket) GetTicker(symbol string) (*models.Ticker, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	return &models.Ticker{Symbol: symbol, Price: m.price}, nil
+}
+
+func (m *fakeMarket) SubscribeTicker(symbol string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subs = append(m.subs, symbol)
+	return symbol, nil
+}
+
+func (m *fakeMarket) tickerRequests() (int, []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests, append([]string{}, m.subs...)
+}
+
+func (m *fakeMarket) setPrice(price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.price = price
+}
+
+type memStorage struct {
+	mu   *sync.Mutex
+	data map[string][]byte
+}
+
+func (s *memStorage) Save(key string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = b
+	return nil
+}
+
+func (s *memStorage) Load(key string, to interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.data[key]
+	if !ok {
+		return storage.ErrNotFound
+	}
+	return json.Unmarshal(b, to)
+}
+
+func testConfig() config.Configurations {
+	cfg := config.Configurations{}
+	cfg.Exchanges.Paper = config.Paper{
+		Enabled:     true,
+		Currency:    "USD",
+		Deposit:     1000,
+		MaxLeverage: 5,
+		Interval:    time.Second,
+	}
+	return cfg
+}
+
+func newTestPaper(t *testing.T, wManager *watcher.Manager, market *fakeMarket, st storage.CustomStorage) *exchange {
+	e, err := newPaper(context.Background(), wManager, logger.New(os.Stdout, logger.ErrorLevel), testConfig(), market, marketName, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func usd(t *testing.T, e exchanges2.CryptoExchange) float64 {
+	wallets, err := e.GetWallets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range wallets {
+		if c := w.Get("USD"); c != nil {
+			return c.Balance
+		}
+	}
+	return 0
+}
+
+func TestPaperPersist(t *testing.T) {
+	st := &memStorage{mu: &sync.Mutex{}, data: make(map[string][]byte)}
+	market := &fakeMarket{mu: &sync.Mutex{}, price: 100}
+
+	e := newTestPaper(t, watcher.NewWatcherManager(), market, st)
+	if !market.IsReady() {
+		t.Fatal("market not connected")
+	}
+
+	if _, err := e.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if balance := usd(t, e); balance != 800 {
+		t.Fatalf("expected 800 USD after buy, got %v", balance)
+	}
+	if _, err := e.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: -1, Price: 150}); err != nil {
+		t.Fatal(err)
+	}
+
+	e.Disconnect()
+	if market.IsReady() {
+		t.Fatal("market not disconnected")
+	}
+
+	restored := newTestPaper(t, watcher.NewWatcherManager(), market, st)
+	defer restored.Disconnect()
+
+	if balance := usd(t, restored); balance != 800 {
+		t.Fatalf("expected restored 800 USD, got %v", balance)
+	}
+	orders, _ := restored.GetOrders()
+	if len(orders) != 1 || orders[0].Price != 150 {
+		t.Fatalf("expected restored limit order, got %+v", orders)
+	}
+}
+
+func TestPaperEvents(t *testing.T) {
+	wManager := watcher.NewWatcherManager()
+	if err := wManager.RegisterEvents(marketName, watcher.EventsMap{models.EventTickerState}); err != nil {
+		t.Fatal(err)
+	}
+
+	st := &memStorage{mu: &sync.Mutex{}, data: make(map[string][]byte)}
+	market := &fakeMarket{mu: &sync.Mutex{}, price: 100}
+
+	pipe := wManager.MustNew("test", models.EventsModuleExchange, exchanges.ExchangeTypePaper.String(),
+		models.EventTickerState, models.EventOrderFilled).Listen()
+
+	e := newTestPaper(t, wManager, market, st)
+	defer e.Disconnect()
+
+	if _, err := e.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeLimit, Amount: 1, Price: 90}); err != nil {
+		t.Fatal(err)
+	}
+
+	// order filled by streamed price, not requested one
+	market.setPrice(50)
+	err := wManager.Emmit(watcher.BuildEvent(models.EventTickerState, marketName, models.Ticker{Symbol: testPair, Price: 80}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ticker, filled bool
+	timeout := time.After(5 * time.Second)
+	for !ticker || !filled {
+		select {
+		case evt := <-pipe:
+			switch {
+			case evt.Is(models.EventTickerState):
+				ticker = true
+			case evt.Is(models.EventOrderFilled):
+				filled = true
+			}
+		case <-timeout:
+			t.Fatalf("events not received, ticker %v, filled %v", ticker, filled)
+		}
+	}
+
+	// limit order filled by market price
+	if balance := usd(t, e); balance != 920 {
+		t.Fatalf("expected 920 USD after limit buy, got %v", balance)
+	}
+	if requests, subs := market.tickerRequests(); requests != 1 || len(subs) != 1 || subs[0] != testPair {
+		t.Fatalf("expected one ticker request and subscription, got %d %v", requests, subs)
+	}
+}
+
+func TestPaperNotSaved(t *testing.T) {
+	market := &fakeMarket{mu: &sync.Mutex{}, price: 100}
+
+	e := newTestPaper(t, watcher.NewWatcherManager(), market, nil)
+	if _, err := e.PutOrder(&models.PutOrder{Symbol: testPair, Type: models.OrderTypeMarket, Amount: 2}); err != nil {
+		t.Fatal(err)
+	}
+	e.Disconnect()
+
+	again := newTestPaper(t, watcher.NewWatcherManager(), market, nil)
+	defer again.Disconnect()
+
+	if balance := usd(t, again); balance != 1000 {
+		t.Fatalf("expected new account with 1000 USD, got %v", balance)
+	}
+}
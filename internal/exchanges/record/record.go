@@ -0,0 +1,126 @@
+/*
+Record write raw messages of exchange websocket session with time of receive to compact file and read them back,
+so session can be replayed through the same decoding as live one
+*/
+package record
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	flushInterval = time.Second
+)
+
+// Record is one message of session, written as json line
+type Record struct {
+	Time    int64           `json:"t"` // unix nano
+	Message json.RawMessage `json:"m"`
+}
+
+// Recorder write messages of session to gzip file
+type Recorder struct {
+	mu        *sync.Mutex
+	file      *os.File
+	gz        *gzip.Writer
+	enc       *json.Encoder
+	lastFlush time.Time
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(f)
+
+	return &Recorder{
+		mu:        &sync.Mutex{},
+		file:      f,
+		gz:        gz,
+		enc:       json.NewEncoder(gz),
+		lastFlush: time.Now(),
+	}, nil
+}
+
+// Write message received at t, message must be json
+func (r *Recorder) Write(t time.Time, msg []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(Record{Time: t.UnixNano(), Message: msg}); err != nil {
+		return err
+	}
+
+	// flushed periodically, so crashed session is readable
+	if time.Since(r.lastFlush) >= flushInterval {
+		r.lastFlush = time.Now()
+		return r.gz.Flush()
+	}
+
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.gz.Close(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+
+	return r.file.Close()
+}
+
+// Reader read messages of file written by Recorder
+type Reader struct {
+	file *os.File
+	gz   *gzip.Reader
+	dec  *json.Decoder
+}
+
+func NewReader(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &Reader{
+		file: f,
+		gz:   gz,
+		dec:  json.NewDecoder(gz),
+	}, nil
+}
+
+// Next returns time and message of next record, io.EOF at end of file.
+// Unexpected end of file of not closed recorder treated as end of file
+func (r *Reader) Next() (time.Time, []byte, error) {
+	rec := Record{}
+	if err := r.dec.Decode(&rec); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return time.Time{}, nil, err
+	}
+
+	return time.Unix(0, rec.Time), rec.Message, nil
+}
+
+func (r *Reader) Close() error {
+	_ = r.gz.Close()
+	return r.file.Close()
+}
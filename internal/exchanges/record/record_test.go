@@ -0,0 +1,89 @@
+package record
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.gz")
+	start := time.Now()
+
+	msgs := []string{
+		`{"event":"info","version":2,"platform":{"status":1}}`,
+		`[10,[99,1,101,1,0,0,100,10,105,95]]`,
+		`[10,"hb"]`,
+	}
+
+	r, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range msgs {
+		if err := r.Write(start.Add(time.Duration(i)*time.Millisecond), []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not json is not recorded
+	if err := r.Write(start, []byte("hb")); err == nil {
+		t.Fatal("expected error of not json message")
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rd, err := NewReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	for i, want := range msgs {
+		tm, msg, err := rd.Next()
+		if err != nil {
+			t.Fatalf("record %d: %v", i, err)
+		}
+		if !tm.Equal(start.Add(time.Duration(i) * time.Millisecond)) {
+			t.Fatalf("record %d: wrong time %s", i, tm)
+		}
+		if string(msg) != want {
+			t.Fatalf("record %d: expected %s, got %s", i, want, msg)
+		}
+	}
+
+	if _, _, err := rd.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestReadNotClosed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.gz")
+
+	r, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Write(time.Now(), []byte(`[10,"hb"]`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.gz.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	// crashed session, gzip is not closed
+	defer r.file.Close()
+
+	rd, err := NewReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rd.Close()
+
+	if _, msg, err := rd.Next(); err != nil || string(msg) != `[10,"hb"]` {
+		t.Fatalf("expected flushed record, got %s %v", msg, err)
+	}
+	if _, _, err := rd.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
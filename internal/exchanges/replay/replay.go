@@ -0,0 +1,278 @@
+/*
+Replay exchange stream websocket session recorded by Bitfinex (see record package) through the same api client
+with original timing or accelerated, so issues of production can be reproduced and strategies tested offline
+on real stream of exchange. Orders, positions and wallets are restored by replayed messages, tickers and candles
+are kept from replayed events, requests are not supported, orders on replayed market are simulated by paper exchange.
+*/
+package replay
+
+import (
+	exchanges2 "DaruBot/internal/exchanges"
+	"DaruBot/internal/exchanges/bitfinex"
+	"DaruBot/internal/models"
+	"DaruBot/internal/models/exchanges"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"context"
+	"github.com/google/uuid"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	stateWatcher = "replay_state"
+)
+
+var (
+	ErrReadOnly = errors.New("REPLAY EXCHANGE IS READ ONLY")
+)
+
+type exchange struct {
+	session  exchanges2.CryptoExchange
+	watchers *watcher.Manager
+
+	mu            *sync.Mutex
+	subscriptions models.Subscriptions
+	tickers       map[string]models.Ticker
+	candles       map[string][]*models.Candle // by symbol and resolution, first == old
+}
+
+// NewReplay returns exchange replaying file recorded by Bitfinex, speed 1 keeps original timing,
+// greater is faster, 0 replays without delays
+func NewReplay(ctx context.Context, wManager *watcher.Manager, lg logger.Logger, path string, speed float64) (exchanges2.CryptoExchange, error) {
+	return newReplay(ctx, wManager, lg, path, speed)
+}
+
+func newReplay(ctx context.Context, wManager *watcher.Manager, lg logger.Logger, path string, speed float64) (*exchange, error) {
+	session, err := bitfinex.NewReplaySession(ctx, wManager, lg, exchanges.ExchangeTypeReplay.String(), path, speed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &exchange{
+		session:  session,
+		watchers: wManager,
+		mu:       &sync.Mutex{},
+		tickers:  make(map[string]models.Ticker),
+		candles:  make(map[string][]*models.Candle),
+	}, nil
+}
+
+// apply replayed event to state of exchange
+func (e *exchange) apply(payload interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	switch v := payload.(type) {
+	case models.Ticker:
+		e.tickers[v.Symbol] = v
+	case models.Candle:
+		e.addCandle(v)
+	}
+}
+
+// addCandle add candle or replace candle with the same date
+func (e *exchange) addCandle(c models.Candle) {
+	key := c.Symbol + c.Resolution.String()
+	candles := e.candles[key]
+
+	i := sort.Search(len(candles), func(i int) bool {
+		return !candles[i].Date.Before(c.Date)
+	})
+	if i < len(candles) && candles[i].Date.Equal(c.Date) {
+		candles[i] = &c
+		return
+	}
+
+	candles = append(candles, nil)
+	copy(candles[i+1:], candles[i:])
+	candles[i] = &c
+	e.candles[key] = candles
+}
+
+func (e *exchange) Connect() error {
+	if e.session.IsReady() {
+		return nil
+	}
+
+	wh, err := e.watchers.New(stateWatcher, models.EventsModuleExchange, exchanges.ExchangeTypeReplay.String(),
+		models.EventTickerState, models.EventCandleState)
+	if err != nil {
+		return err
+	}
+	pipe := wh.Listen()
+
+	go func() {
+		for evt := range pipe {
+			e.apply(evt.Payload)
+		}
+	}()
+
+	if err := e.session.Connect(); err != nil {
+		e.watchers.Remove(stateWatcher)
+		return err
+	}
+
+	return nil
+}
+
+func (e *exchange) Disconnect() {
+	e.session.Disconnect()
+	e.watchers.Remove(stateWatcher)
+}
+
+func (e *exchange) IsReady() bool {
+	return e.session.IsReady()
+}
+
+func (e *exchange) Ready() <-chan interface{} {
+	return e.session.Ready()
+}
+
+func (e *exchange) SupportEvents() watcher.EventsMap {
+	return e.session.SupportEvents()
+}
+
+func (e *exchange) GetTicker(symbol string) (*models.Ticker, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	t, ok := e.tickers[symbol]
+	if !ok {
+		return nil, exchanges2.ErrSymbolNotSupported
+	}
+
+	return &t, nil
+}
+
+func (e *exchange) GetLastCandle(symbol string, resolution models.CandleResolution) (*models.Candle, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	candles := e.candles[symbol+resolution.String()]
+	if len(candles) == 0 {
+		return nil, exchanges2.ErrSymbolNotSupported
+	}
+
+	c := *candles[len(candles)-1]
+
+	return &c, nil
+}
+
+// GetCandles returns replayed candles
+func (e *exchange) GetCandles(symbol string, resolution models.CandleResolution, from time.Time, to time.Time) (*models.Candles, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	rs := &models.Candles{
+		Symbol:     symbol,
+		Resolution: resolution,
+		Candles:    make([]*models.Candle, 0),
+	}
+
+	for _, c := range e.candles[symbol+resolution.String()] {
+		if c.Date.Before(from) || c.Date.After(to) {
+			continue
+		}
+		cc := *c
+		rs.Candles = append(rs.Candles, &cc)
+	}
+
+	return rs, nil
+}
+
+// GetSubscriptions subscriptions do not filter replayed events, all recorded channels are replayed
+func (e *exchange) GetSubscriptions() *models.Subscriptions {
+	return &e.subscriptions
+}
+
+func (e *exchange) SubscribeTicker(symbol string) (subID string, err error) {
+	sid := uuid.Must(uuid.NewUUID()).String()
+	e.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeTicker,
+	})
+	return sid, nil
+}
+
+func (e *exchange) SubscribeCandles(symbol string, resolution models.CandleResolution) (subID string, err error) {
+	sid := uuid.Must(uuid.NewUUID()).String()
+	e.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeCandle,
+	})
+	return sid, nil
+}
+
+func (e *exchange) SubscribeOrderBook(symbol string, precision models.BookPrecision, depth int) (subID string, err error) {
+	sid := uuid.Must(uuid.NewUUID()).String()
+	e.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeOrderBook,
+	})
+	return sid, nil
+}
+
+func (e *exchange) SubscribeTrades(symbol string) (subID string, err error) {
+	sid := uuid.Must(uuid.NewUUID()).String()
+	e.subscriptions.Add(&models.Subscription{
+		ID:     sid,
+		Symbol: symbol,
+		Type:   models.SubTypeTrades,
+	})
+	return sid, nil
+}
+
+func (e *exchange) GetOrderBook(symbol string) (*models.OrderBook, error) {
+	return e.session.GetOrderBook(symbol)
+}
+
+func (e *exchange) Unsubscribe(subID string) error {
+	e.subscriptions.Delete(subID)
+	return nil
+}
+
+func (e *exchange) CheckSymbol(symbol string, margin bool) error {
+	return nil
+}
+
+func (e *exchange) GetOrders() ([]*models.Order, error) {
+	return e.session.GetOrders()
+}
+
+func (e *exchange) GetPositions() ([]*models.Position, error) {
+	return e.session.GetPositions()
+}
+
+func (e *exchange) GetWallets() ([]*models.Wallets, error) {
+	return e.session.GetWallets()
+}
+
+func (e *exchange) GetBalance() (*models.BalanceUSD, error) {
+	return e.session.GetBalance()
+}
+
+func (e *exchange) HasUpdates(t time.Time) bool {
+	return e.session.HasUpdates(t)
+}
+
+func (e *exchange) PutOrder(order *models.PutOrder) (*models.Order, error) {
+	return nil, ErrReadOnly
+}
+
+func (e *exchange) UpdateOrder(orderID string, price float64, priceStop float64, amount float64) (*models.Order, error) {
+	return nil, ErrReadOnly
+}
+
+func (e *exchange) CancelOrder(order *models.Order) error {
+	return ErrReadOnly
+}
+
+func (e *exchange) ClosePosition(position *models.Position) (*models.Position, error) {
+	return nil, ErrReadOnly
+}
@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"DaruBot/internal/exchanges/record"
+	"DaruBot/internal/models"
+	"DaruBot/internal/models/exchanges"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testPair = "tBTCUSD"
+
+type testMessage struct {
+	offset time.Duration
+	msg    string
+}
+
+// testSession is raw session of websocket, messages of channels which are not subscribed are skipped
+func testSession() []testMessage {
+	return []testMessage{
+		{0, `{"event":"info","version":2,"serverId":"test","platform":{"status":1}}`},
+		{0, `{"event":"auth","status":"OK","chanId":0,"userId":1,"subId":"1","auth_id":"test","caps":{"orders":{"read":1,"write":1}}}`},
+		{0, `{"event":"subscribed","channel":"ticker","chanId":10,"symbol":"tBTCUSD","pair":"BTCUSD","subId":"2"}`},
+		{0, `{"event":"subscribed","channel":"candles","chanId":11,"key":"trade:1m:tBTCUSD","subId":"3"}`},
+		{0, `{"event":"subscribed","channel":"book","chanId":12,"symbol":"tBTCUSD","prec":"P0","freq":"F0","len":"25","pair":"BTCUSD","subId":"4"}`},
+		{0, `{"event":"subscribed","channel":"trades","chanId":13,"symbol":"tBTCUSD","pair":"BTCUSD","subId":"5"}`},
+		{0, `{"event":"conf","status":"OK","flags":131072}`},
+		{1 * time.Millisecond, `[0,"ws",[["margin","USD",1000,0,900,null,null]]]`},
+		{10 * time.Millisecond, `[10,[99,1,101,1,0,0,100,10,105,95]]`},
+		{15 * time.Millisecond, `[14,[1,2,3]]`},
+		{20 * time.Millisecond, `[11,[[1609459200000,100,100,100,100,1]]]`},
+		{30 * time.Millisecond, `[11,[1609459200000,100,101,101,100,2]]`},
+		{35 * time.Millisecond, `[12,[[99,1,1],[101,1,-2]]]`},
+		{37 * time.Millisecond, `[13,[[1,1609459200000,-0.5,100]]]`},
+		{40 * time.Millisecond, `[0,"on",[1,"tBTCUSD",1,1,"LIMIT","ACTIVE",90,0,1609459200000,null,null,null]]`},
+		{50 * time.Millisecond, `[0,"on",[2,"tBTCUSD",1,1,"LIMIT","ACTIVE",95,0,1609459200000,null,null,null]]`},
+		{60 * time.Millisecond, `[0,"oc",[2,"tBTCUSD",0,1,"LIMIT","EXECUTED @ 95.0(1.0)",95,95,1609459200000,null,null,null]]`},
+		{70 * time.Millisecond, `[0,"pn",["tBTCUSD","ACTIVE",1,95,0,0,0,0,0,1,null,7]]`},
+		{80 * time.Millisecond, `[10,"hb"]`},
+	}
+}
+
+func writeSession(t *testing.T, path string, start time.Time) {
+	r, err := record.NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range testSession() {
+		if err := r.Write(start.Add(m.offset), []byte(m.msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.gz")
+	writeSession(t, path, time.Now())
+
+	wManager := watcher.NewWatcherManager()
+	pipe := wManager.MustNew("test", models.EventsModuleExchange, exchanges.ExchangeTypeReplay.String()).Listen()
+
+	// accelerated twice
+	e, err := newReplay(context.Background(), wManager, logger.New(os.Stdout, logger.ErrorLevel), path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := e.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Disconnect()
+
+	expected := []watcher.EventHead{
+		models.EventTickerState,
+		models.EventCandleState,
+		models.EventCandleState,
+		models.EventOrderBookUpdate,
+		models.EventTrade,
+		models.EventOrderNew,
+		models.EventOrderNew,
+		models.EventOrderFilled,
+		models.EventPositionNew,
+	}
+	for i := range expected {
+		select {
+		case evt := <-pipe:
+			if !evt.Is(expected[i]) {
+				t.Fatalf("event %d: expected %s, got %s", i, expected[i].GetEventName(), evt.GetEventName())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not replayed", i)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("replay too fast %s", elapsed)
+	}
+
+	// state is updated by own watcher
+	time.Sleep(10 * time.Millisecond)
+
+	ticker, err := e.GetTicker(testPair)
+	if err != nil || ticker.Price != 100 {
+		t.Fatalf("wrong ticker %+v %v", ticker, err)
+	}
+
+	candle, err := e.GetLastCandle(testPair, models.OneMinute)
+	if err != nil || candle.Close != 101 {
+		t.Fatalf("wrong candle %+v %v", candle, err)
+	}
+
+	book, err := e.GetOrderBook(testPair)
+	if err != nil || book.Spread() != 2 || book.Mid() != 100 {
+		t.Fatalf("wrong book %+v %v", book, err)
+	}
+
+	orders, _ := e.GetOrders()
+	if len(orders) != 1 || orders[0].ID != "1" {
+		t.Fatalf("wrong orders %+v", orders)
+	}
+
+	positions, _ := e.GetPositions()
+	if len(positions) != 1 {
+		t.Fatalf("wrong positions %+v", positions)
+	}
+
+	wallets, _ := e.GetWallets()
+	if usd := wallets[1].Get("USD"); usd == nil || usd.Balance != 1000 || usd.Available != 900 {
+		t.Fatalf("wrong wallets %+v", wallets[1])
+	}
+
+	if _, err := e.PutOrder(&models.PutOrder{Symbol: testPair}); err != ErrReadOnly {
+		t.Fatalf("expected read only error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Indicators calculated on candles in batch (*Series functions) or incrementally, updated by every new candle in O(1).
+Candle with the same date as the last one replaces it, so updates of current candle from EventCandleState
+can be passed as they come. Candles older than the last one are ignored.
+*/
+package indicators
+
+import (
+	"DaruBot/internal/models"
+	"math"
+)
+
+type Indicator interface {
+	Update(c *models.Candle)
+	Ready() bool
+}
+
+type update uint8
+
+const (
+	updateSkip update = iota
+	updateNew
+	updateReplace
+)
+
+// tracker keep last two candles to tell new candle from update of the last one
+type tracker struct {
+	prev  *models.Candle // candle before the last one
+	last  *models.Candle
+	count int
+}
+
+func (t *tracker) track(c *models.Candle) update {
+	if t.last != nil && c.Date.Before(t.last.Date) {
+		return updateSkip
+	}
+
+	cc := *c
+	if t.last != nil && c.Date.Equal(t.last.Date) {
+		t.last = &cc
+		return updateReplace
+	}
+
+	t.prev = t.last
+	t.last = &cc
+	t.count++
+
+	return updateNew
+}
+
+func checkPeriod(period int) int {
+	if period < 1 {
+		return 1
+	}
+	return period
+}
+
+// floatSeries returns value of indicator for every candle, NaN if indicator is not ready
+func floatSeries(candles *models.Candles, ind Indicator, value func() float64) []float64 {
+	rs := make([]float64, len(candles.Candles))
+	for i, c := range candles.Candles {
+		ind.Update(c)
+		rs[i] = math.NaN()
+		if ind.Ready() {
+			rs[i] = value()
+		}
+	}
+	return rs
+}
+
+func typicalPrice(c *models.Candle) float64 {
+	return (c.High + c.Low + c.Close) / 3
+}
+
+// trueRange of candle, previous candle may be nil
+func trueRange(c, prev *models.Candle) float64 {
+	tr := c.High - c.Low
+	if prev == nil {
+		return tr
+	}
+	return math.Max(tr, math.Max(math.Abs(c.High-prev.Close), math.Abs(c.Low-prev.Close)))
+}
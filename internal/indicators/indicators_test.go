@@ -0,0 +1,253 @@
+package indicators
+
+import (
+	"DaruBot/internal/models"
+	"math"
+	"testing"
+	"time"
+)
+
+// testCandles hourly candles, reference values are calculated on them by straightforward (not incremental) formulas
+func testCandles() *models.Candles {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	rs := &models.Candles{Symbol: "BTCUSD", Resolution: models.OneHour}
+
+	for i := 0; i < 80; i++ {
+		c := 100 + 10*math.Sin(float64(i)/5) + float64(i)*0.3
+		rs.Candles = append(rs.Candles, &models.Candle{
+			Symbol:     "BTCUSD",
+			Resolution: models.OneHour,
+			Date:       start.Add(time.Duration(i) * time.Hour),
+			Open:       c,
+			Close:      c,
+			High:       c + 1 + float64(i%3),
+			Low:        c - 1 - float64(i%4),
+			Volume:     float64(100 + (i*37)%50),
+		})
+	}
+
+	return rs
+}
+
+var refIndexes = []int{30, 55, 79}
+
+func checkValue(t *testing.T, name string, i int, got, want float64) {
+	t.Helper()
+	if math.IsNaN(want) {
+		if !math.IsNaN(got) {
+			t.Fatalf("%s[%d]: expected NaN, got %v", name, i, got)
+		}
+		return
+	}
+	if math.Abs(got-want) > 1e-6 {
+		t.Fatalf("%s[%d]: expected %.6f, got %.6f", name, i, want, got)
+	}
+}
+
+func checkSeries(t *testing.T, name string, got []float64, want []float64) {
+	t.Helper()
+	for j, i := range refIndexes {
+		checkValue(t, name, i, got[i], want[j])
+	}
+}
+
+func TestReference(t *testing.T) {
+	candles := testCandles()
+	nan := math.NaN()
+
+	checkSeries(t, "SMA", SMASeries(candles, 10), []float64{99.846539, 109.881435, 128.442987})
+	checkSeries(t, "EMA", EMASeries(candles, 10), []float64{101.800201, 109.645197, 126.326824})
+	checkSeries(t, "WMA", WMASeries(candles, 10), []float64{101.323469, 108.210704, 127.015680})
+	checkSeries(t, "RSI", RSISeries(candles, 14), []float64{66.816609, 34.710377, 50.096231})
+	checkSeries(t, "ATR", ATRSeries(candles, 14), []float64{4.570930, 4.676004, 4.701388})
+	checkSeries(t, "VWAP", VWAPSeries(candles, false), []float64{104.472185, 108.898911, 112.907093})
+	checkSeries(t, "VWAP daily", VWAPSeries(candles, true), []float64{100.701578, 108.208739, 127.422728})
+	checkSeries(t, "OBV", OBVSeries(candles), []float64{361, 76, 1070})
+
+	macd := MACDSeries(candles, 12, 26, 9)
+	checkValue(t, "MACD signal", 30, macd[30].Signal, nan)
+	for j, want := range [][2]float64{{-1.226910, 0.349038}, {3.057191, 3.980451}} {
+		i := refIndexes[j+1]
+		checkValue(t, "MACD", i, macd[i].MACD, want[0])
+		checkValue(t, "MACD signal", i, macd[i].Signal, want[1])
+		checkValue(t, "MACD histogram", i, macd[i].Histogram, want[0]-want[1])
+	}
+
+	boll := BollingerSeries(candles, 20, 2)
+	for j, want := range [][3]float64{
+		{111.558133, 102.423506, 93.288879},
+		{126.714988, 115.103197, 103.491407},
+		{136.423453, 125.276594, 114.129734},
+	} {
+		i := refIndexes[j]
+		checkValue(t, "Bollinger upper", i, boll[i].Upper, want[0])
+		checkValue(t, "Bollinger middle", i, boll[i].Middle, want[1])
+		checkValue(t, "Bollinger lower", i, boll[i].Lower, want[2])
+	}
+
+	stoch := StochasticSeries(candles, 14, 3)
+	for j, want := range [][2]float64{{92.978886, 79.034720}, {20.259450, 15.317336}, {25.807559, 30.013103}} {
+		i := refIndexes[j]
+		checkValue(t, "Stochastic K", i, stoch[i].K, want[0])
+		checkValue(t, "Stochastic D", i, stoch[i].D, want[1])
+	}
+
+	adx := ADXSeries(candles, 14)
+	for j, want := range [][3]float64{
+		{17.345187, 19.643813, 14.284572},
+		{30.600101, 11.003039, 22.916569},
+		{31.555159, 15.955484, 20.610792},
+	} {
+		i := refIndexes[j]
+		checkValue(t, "ADX", i, adx[i].ADX, want[0])
+		checkValue(t, "ADX +DI", i, adx[i].PlusDI, want[1])
+		checkValue(t, "ADX -DI", i, adx[i].MinusDI, want[2])
+	}
+
+	ichimoku := IchimokuSeries(candles, 9, 26, 52, 26)
+	checkValue(t, "Ichimoku tenkan", 30, ichimoku[30].Tenkan, nan)
+	for j, want := range [][6]float64{
+		{109.923926, 113.603702, 111.763814, 108.835198, nan, nan},
+		{126.530599, 118.390182, 122.460391, 117.683800, 112.179269, 108.835198},
+	} {
+		i := refIndexes[j+1]
+		v := ichimoku[i]
+		checkValue(t, "Ichimoku tenkan", i, v.Tenkan, want[0])
+		checkValue(t, "Ichimoku kijun", i, v.Kijun, want[1])
+		checkValue(t, "Ichimoku senkou A", i, v.SenkouA, want[2])
+		checkValue(t, "Ichimoku senkou B", i, v.SenkouB, want[3])
+		checkValue(t, "Ichimoku cloud A", i, v.CloudA, want[4])
+		checkValue(t, "Ichimoku cloud B", i, v.CloudB, want[5])
+	}
+}
+
+// TestRSIWilder example of RSI calculation from StockCharts, StockCharts rounds averages, values here are not rounded
+func TestRSIWilder(t *testing.T) {
+	closes := []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08, 45.89, 46.03, 45.61, 46.28, 46.28,
+		46.00, 46.03, 46.41, 46.22, 45.64, 46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+		43.42, 42.66, 43.13,
+	}
+	want := []float64{
+		70.46, 66.25, 66.48, 69.35, 66.29, 57.92, 62.88, 63.21, 56.01, 62.34, 54.67, 50.39, 40.02, 41.49, 41.90,
+		45.50, 37.32, 33.09, 37.79,
+	}
+
+	rsi := NewRSI(14)
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, c := range closes {
+		rsi.Update(&models.Candle{Date: start.AddDate(0, 0, i), Close: c})
+		if i < 14 {
+			if rsi.Ready() {
+				t.Fatalf("RSI ready after %d candles", i+1)
+			}
+			continue
+		}
+		if math.Abs(rsi.Value()-want[i-14]) > 0.01 {
+			t.Fatalf("candle %d: expected %.2f, got %.2f", i, want[i-14], rsi.Value())
+		}
+	}
+}
+
+// TestIncrementalUpdates indicators updated by unfinished candles must be equal to batch calculation
+func TestIncrementalUpdates(t *testing.T) {
+	candles := testCandles()
+
+	type testCase struct {
+		ind   Indicator
+		value func() []float64
+	}
+
+	sma, ema, wma, rsi := NewSMA(10), NewEMA(10), NewWMA(10), NewRSI(14)
+	macd, boll, atr, stoch := NewMACD(12, 26, 9), NewBollinger(20, 2), NewATR(14), NewStochastic(14, 3)
+	adx, vwap, obv, ichimoku := NewADX(14), NewVWAP(true), NewOBV(), NewIchimoku(9, 26, 52, 26)
+
+	cases := map[string]testCase{
+		"SMA": {sma, func() []float64 { return []float64{sma.Value()} }},
+		"EMA": {ema, func() []float64 { return []float64{ema.Value()} }},
+		"WMA": {wma, func() []float64 { return []float64{wma.Value()} }},
+		"RSI": {rsi, func() []float64 { return []float64{rsi.Value()} }},
+		"MACD": {macd, func() []float64 {
+			v := macd.Value()
+			return []float64{v.MACD, v.Signal, v.Histogram}
+		}},
+		"Bollinger": {boll, func() []float64 {
+			v := boll.Value()
+			return []float64{v.Upper, v.Middle, v.Lower}
+		}},
+		"ATR": {atr, func() []float64 { return []float64{atr.Value()} }},
+		"Stochastic": {stoch, func() []float64 {
+			v := stoch.Value()
+			return []float64{v.K, v.D}
+		}},
+		"ADX": {adx, func() []float64 {
+			v := adx.Value()
+			return []float64{v.ADX, v.PlusDI, v.MinusDI}
+		}},
+		"VWAP": {vwap, func() []float64 { return []float64{vwap.Value()} }},
+		"OBV":  {obv, func() []float64 { return []float64{obv.Value()} }},
+		"Ichimoku": {ichimoku, func() []float64 {
+			v := ichimoku.Value()
+			return []float64{v.Tenkan, v.Kijun, v.SenkouA, v.SenkouB, v.CloudA, v.CloudB}
+		}},
+	}
+
+	macdBatch := MACDSeries(candles, 12, 26, 9)
+	bollBatch := BollingerSeries(candles, 20, 2)
+	stochBatch := StochasticSeries(candles, 14, 3)
+	adxBatch := ADXSeries(candles, 14)
+	ichimokuBatch := IchimokuSeries(candles, 9, 26, 52, 26)
+
+	batch := map[string]func(i int) []float64{
+		"SMA": func(i int) []float64 { return []float64{SMASeries(candles, 10)[i]} },
+		"EMA": func(i int) []float64 { return []float64{EMASeries(candles, 10)[i]} },
+		"WMA": func(i int) []float64 { return []float64{WMASeries(candles, 10)[i]} },
+		"RSI": func(i int) []float64 { return []float64{RSISeries(candles, 14)[i]} },
+		"MACD": func(i int) []float64 {
+			return []float64{macdBatch[i].MACD, macdBatch[i].Signal, macdBatch[i].Histogram}
+		},
+		"Bollinger": func(i int) []float64 {
+			return []float64{bollBatch[i].Upper, bollBatch[i].Middle, bollBatch[i].Lower}
+		},
+		"ATR":        func(i int) []float64 { return []float64{ATRSeries(candles, 14)[i]} },
+		"Stochastic": func(i int) []float64 { return []float64{stochBatch[i].K, stochBatch[i].D} },
+		"ADX": func(i int) []float64 {
+			return []float64{adxBatch[i].ADX, adxBatch[i].PlusDI, adxBatch[i].MinusDI}
+		},
+		"VWAP": func(i int) []float64 { return []float64{VWAPSeries(candles, true)[i]} },
+		"OBV":  func(i int) []float64 { return []float64{OBVSeries(candles)[i]} },
+		"Ichimoku": func(i int) []float64 {
+			v := ichimokuBatch[i]
+			return []float64{v.Tenkan, v.Kijun, v.SenkouA, v.SenkouB, v.CloudA, v.CloudB}
+		},
+	}
+
+	for i, c := range candles.Candles {
+		// unfinished candle updated twice before final one
+		first := *c
+		first.Close, first.High, first.Low, first.Volume = c.Open+5, c.High+7, c.Low-3, c.Volume/3
+		second := *c
+		second.Close, second.Volume = c.Open-2, c.Volume/2
+
+		for name, tc := range cases {
+			tc.ind.Update(&first)
+			tc.ind.Update(&second)
+			tc.ind.Update(c)
+			if i > 0 {
+				// older candle ignored
+				tc.ind.Update(candles.Candles[i-1])
+			}
+
+			want := batch[name](i)
+			if !tc.ind.Ready() {
+				if !math.IsNaN(want[0]) {
+					t.Fatalf("%s[%d]: not ready", name, i)
+				}
+				continue
+			}
+			for j, v := range tc.value() {
+				checkValue(t, name, i, v, want[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package indicators
+
+import (
+	"DaruBot/internal/models"
+)
+
+// SMA simple moving average of close
+type SMA struct {
+	tracker
+	w *window
+}
+
+func NewSMA(period int) *SMA {
+	return &SMA{w: newWindow(period)}
+}
+
+func (s *SMA) Update(c *models.Candle) {
+	switch s.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		s.w.undo()
+	}
+	s.w.push(c.Close)
+}
+
+func (s *SMA) Ready() bool {
+	return s.w.full()
+}
+
+func (s *SMA) Value() float64 {
+	return s.w.mean()
+}
+
+func SMASeries(candles *models.Candles, period int) []float64 {
+	ind := NewSMA(period)
+	return floatSeries(candles, ind, ind.Value)
+}
+
+// EMA exponential moving average of close, first value is SMA of period
+type EMA struct {
+	tracker
+	e *ema
+}
+
+func NewEMA(period int) *EMA {
+	return &EMA{e: newEMA(period)}
+}
+
+func (e *EMA) Update(c *models.Candle) {
+	switch e.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		e.e.undo()
+	}
+	e.e.push(c.Close)
+}
+
+func (e *EMA) Ready() bool {
+	return e.e.ready()
+}
+
+func (e *EMA) Value() float64 {
+	return e.e.value
+}
+
+func EMASeries(candles *models.Candles, period int) []float64 {
+	ind := NewEMA(period)
+	return floatSeries(candles, ind, ind.Value)
+}
+
+type wmaState struct {
+	num float64 // weighted sum
+}
+
+// WMA linear weighted moving average of close, the newest candle has weight period
+type WMA struct {
+	tracker
+	w *window
+	wmaState
+	undoState wmaState
+}
+
+func NewWMA(period int) *WMA {
+	return &WMA{w: newWindow(period)}
+}
+
+func (m *WMA) Update(c *models.Candle) {
+	switch m.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		m.w.undo()
+		m.wmaState = m.undoState
+	}
+	m.undoState = m.wmaState
+
+	// weights of values in full window decrease by one, the oldest drops out
+	if m.w.full() {
+		m.num = m.num - m.w.sum + float64(m.w.count)*c.Close
+	} else {
+		m.num = m.num + float64(m.w.count+1)*c.Close
+	}
+	m.w.push(c.Close)
+}
+
+func (m *WMA) Ready() bool {
+	return m.w.full()
+}
+
+func (m *WMA) Value() float64 {
+	n := float64(m.w.count)
+	if n == 0 {
+		return 0
+	}
+	return m.num / (n * (n + 1) / 2)
+}
+
+func WMASeries(candles *models.Candles, period int) []float64 {
+	ind := NewWMA(period)
+	return floatSeries(candles, ind, ind.Value)
+}
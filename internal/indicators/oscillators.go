@@ -0,0 +1,181 @@
+package indicators
+
+import (
+	"DaruBot/internal/models"
+	"math"
+)
+
+// RSI relative strength index with Wilder's smoothing of gains and losses
+type RSI struct {
+	tracker
+	gain *ema
+	loss *ema
+}
+
+func NewRSI(period int) *RSI {
+	return &RSI{gain: newWilder(period), loss: newWilder(period)}
+}
+
+func (r *RSI) Update(c *models.Candle) {
+	mode := r.track(c)
+	if mode == updateSkip || r.prev == nil {
+		return
+	}
+	if mode == updateReplace {
+		r.gain.undo()
+		r.loss.undo()
+	}
+
+	change := c.Close - r.prev.Close
+	r.gain.push(math.Max(change, 0))
+	r.loss.push(math.Max(-change, 0))
+}
+
+func (r *RSI) Ready() bool {
+	return r.gain.ready()
+}
+
+func (r *RSI) Value() float64 {
+	if r.loss.value == 0 {
+		if r.gain.value == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+r.gain.value/r.loss.value)
+}
+
+func RSISeries(candles *models.Candles, period int) []float64 {
+	ind := NewRSI(period)
+	return floatSeries(candles, ind, ind.Value)
+}
+
+type MACDValue struct {
+	MACD      float64
+	Signal    float64
+	Histogram float64
+}
+
+// MACD difference of fast and slow EMA of close and its signal EMA
+type MACD struct {
+	tracker
+	fast   *ema
+	slow   *ema
+	signal *ema
+}
+
+func NewMACD(fast, slow, signal int) *MACD {
+	return &MACD{fast: newEMA(fast), slow: newEMA(slow), signal: newEMA(signal)}
+}
+
+func (m *MACD) Update(c *models.Candle) {
+	switch m.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		// signal got value only if both averages were ready
+		if m.fast.ready() && m.slow.ready() {
+			m.signal.undo()
+		}
+		m.fast.undo()
+		m.slow.undo()
+	}
+
+	m.fast.push(c.Close)
+	m.slow.push(c.Close)
+	if m.fast.ready() && m.slow.ready() {
+		m.signal.push(m.fast.value - m.slow.value)
+	}
+}
+
+func (m *MACD) Ready() bool {
+	return m.signal.ready()
+}
+
+func (m *MACD) Value() MACDValue {
+	macd := m.fast.value - m.slow.value
+	return MACDValue{
+		MACD:      macd,
+		Signal:    m.signal.value,
+		Histogram: macd - m.signal.value,
+	}
+}
+
+func MACDSeries(candles *models.Candles, fast, slow, signal int) []MACDValue {
+	ind := NewMACD(fast, slow, signal)
+	rs := make([]MACDValue, len(candles.Candles))
+	for i, c := range candles.Candles {
+		ind.Update(c)
+		rs[i] = MACDValue{MACD: math.NaN(), Signal: math.NaN(), Histogram: math.NaN()}
+		if ind.Ready() {
+			rs[i] = ind.Value()
+		}
+	}
+	return rs
+}
+
+type StochasticValue struct {
+	K float64
+	D float64
+}
+
+// Stochastic oscillator, %K position of close in high-low range of kPeriod candles, %D SMA of %K
+type Stochastic struct {
+	tracker
+	high *extremum
+	low  *extremum
+	d    *window
+}
+
+func NewStochastic(kPeriod, dPeriod int) *Stochastic {
+	return &Stochastic{high: newMax(kPeriod), low: newMin(kPeriod), d: newWindow(dPeriod)}
+}
+
+func (s *Stochastic) Update(c *models.Candle) {
+	switch s.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		if s.high.ready() {
+			s.d.undo()
+		}
+		s.high.undo()
+		s.low.undo()
+	}
+
+	s.high.push(c.High)
+	s.low.push(c.Low)
+	if s.high.ready() {
+		s.d.push(s.k())
+	}
+}
+
+// k is 50 if range is empty
+func (s *Stochastic) k() float64 {
+	hh, ll := s.high.value(), s.low.value()
+	if hh == ll {
+		return 50
+	}
+	return 100 * (s.last.Close - ll) / (hh - ll)
+}
+
+func (s *Stochastic) Ready() bool {
+	return s.d.full()
+}
+
+func (s *Stochastic) Value() StochasticValue {
+	return StochasticValue{K: s.k(), D: s.d.mean()}
+}
+
+func StochasticSeries(candles *models.Candles, kPeriod, dPeriod int) []StochasticValue {
+	ind := NewStochastic(kPeriod, dPeriod)
+	rs := make([]StochasticValue, len(candles.Candles))
+	for i, c := range candles.Candles {
+		ind.Update(c)
+		rs[i] = StochasticValue{K: math.NaN(), D: math.NaN()}
+		if ind.Ready() {
+			rs[i] = ind.Value()
+		}
+	}
+	return rs
+}
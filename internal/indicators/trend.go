@@ -0,0 +1,206 @@
+package indicators
+
+import (
+	"DaruBot/internal/models"
+	"math"
+)
+
+type ADXValue struct {
+	ADX     float64
+	PlusDI  float64
+	MinusDI float64
+}
+
+// ADX average directional index with Wilder's smoothing, ready after 2*period candles
+type ADX struct {
+	tracker
+	tr      *ema
+	plusDM  *ema
+	minusDM *ema
+	adx     *ema
+}
+
+func NewADX(period int) *ADX {
+	return &ADX{tr: newWilder(period), plusDM: newWilder(period), minusDM: newWilder(period), adx: newWilder(period)}
+}
+
+func (a *ADX) Update(c *models.Candle) {
+	mode := a.track(c)
+	if mode == updateSkip || a.prev == nil {
+		return
+	}
+	if mode == updateReplace {
+		if a.tr.ready() {
+			a.adx.undo()
+		}
+		a.tr.undo()
+		a.plusDM.undo()
+		a.minusDM.undo()
+	}
+
+	up, down := c.High-a.prev.High, a.prev.Low-c.Low
+	plus, minus := 0.0, 0.0
+	if up > down && up > 0 {
+		plus = up
+	}
+	if down > up && down > 0 {
+		minus = down
+	}
+
+	a.tr.push(trueRange(c, a.prev))
+	a.plusDM.push(plus)
+	a.minusDM.push(minus)
+
+	if a.tr.ready() {
+		a.adx.push(a.dx())
+	}
+}
+
+func (a *ADX) di() (float64, float64) {
+	if a.tr.value == 0 {
+		return 0, 0
+	}
+	return 100 * a.plusDM.value / a.tr.value, 100 * a.minusDM.value / a.tr.value
+}
+
+func (a *ADX) dx() float64 {
+	plus, minus := a.di()
+	if plus+minus == 0 {
+		return 0
+	}
+	return 100 * math.Abs(plus-minus) / (plus + minus)
+}
+
+func (a *ADX) Ready() bool {
+	return a.adx.ready()
+}
+
+func (a *ADX) Value() ADXValue {
+	plus, minus := a.di()
+	return ADXValue{ADX: a.adx.value, PlusDI: plus, MinusDI: minus}
+}
+
+func ADXSeries(candles *models.Candles, period int) []ADXValue {
+	ind := NewADX(period)
+	rs := make([]ADXValue, len(candles.Candles))
+	for i, c := range candles.Candles {
+		ind.Update(c)
+		rs[i] = ADXValue{ADX: math.NaN(), PlusDI: math.NaN(), MinusDI: math.NaN()}
+		if ind.Ready() {
+			rs[i] = ind.Value()
+		}
+	}
+	return rs
+}
+
+// IchimokuValue lines of Ichimoku cloud. SenkouA and SenkouB calculated on current candle
+// are plotted Displacement candles ahead, CloudA and CloudB are spans plotted on current candle.
+// Chikou span is close plotted Displacement candles back
+type IchimokuValue struct {
+	Tenkan  float64
+	Kijun   float64
+	SenkouA float64
+	SenkouB float64
+	CloudA  float64 // NaN if not enough candles
+	CloudB  float64 // NaN if not enough candles
+}
+
+// Ichimoku cloud, usually with periods 9, 26, 52 and displacement 26
+type Ichimoku struct {
+	tracker
+	tenkanHigh, tenkanLow   *extremum
+	kijunHigh, kijunLow     *extremum
+	senkouHigh, senkouLow   *extremum
+	spanA, spanB            *window
+	senkouPeriod, displaced int
+}
+
+func NewIchimoku(tenkan, kijun, senkouB, displacement int) *Ichimoku {
+	displacement = checkPeriod(displacement)
+	return &Ichimoku{
+		tenkanHigh:   newMax(tenkan),
+		tenkanLow:    newMin(tenkan),
+		kijunHigh:    newMax(kijun),
+		kijunLow:     newMin(kijun),
+		senkouHigh:   newMax(senkouB),
+		senkouLow:    newMin(senkouB),
+		spanA:        newWindow(displacement + 1),
+		spanB:        newWindow(displacement + 1),
+		senkouPeriod: checkPeriod(senkouB),
+		displaced:    displacement,
+	}
+}
+
+func (ic *Ichimoku) extremes() []*extremum {
+	return []*extremum{ic.tenkanHigh, ic.kijunHigh, ic.senkouHigh, ic.tenkanLow, ic.kijunLow, ic.senkouLow}
+}
+
+func (ic *Ichimoku) Update(c *models.Candle) {
+	switch ic.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		for _, e := range ic.extremes() {
+			e.undo()
+		}
+		ic.spanA.undo()
+		ic.spanB.undo()
+	}
+
+	for i, e := range ic.extremes() {
+		if i < 3 {
+			e.push(c.High)
+		} else {
+			e.push(c.Low)
+		}
+	}
+
+	// spans of not ready lines are pushed to keep displacement, they are not used
+	v := ic.lines()
+	ic.spanA.push(v.SenkouA)
+	ic.spanB.push(v.SenkouB)
+}
+
+func (ic *Ichimoku) lines() IchimokuValue {
+	tenkan := (ic.tenkanHigh.value() + ic.tenkanLow.value()) / 2
+	kijun := (ic.kijunHigh.value() + ic.kijunLow.value()) / 2
+
+	return IchimokuValue{
+		Tenkan:  tenkan,
+		Kijun:   kijun,
+		SenkouA: (tenkan + kijun) / 2,
+		SenkouB: (ic.senkouHigh.value() + ic.senkouLow.value()) / 2,
+		CloudA:  math.NaN(),
+		CloudB:  math.NaN(),
+	}
+}
+
+// Ready if all lines are ready, cloud of current candle needs Displacement candles more
+func (ic *Ichimoku) Ready() bool {
+	return ic.tenkanHigh.ready() && ic.kijunHigh.ready() && ic.senkouHigh.ready()
+}
+
+func (ic *Ichimoku) Value() IchimokuValue {
+	v := ic.lines()
+	if ic.count >= ic.senkouPeriod+ic.displaced {
+		v.CloudA = ic.spanA.oldest()
+		v.CloudB = ic.spanB.oldest()
+	}
+	return v
+}
+
+func IchimokuSeries(candles *models.Candles, tenkan, kijun, senkouB, displacement int) []IchimokuValue {
+	ind := NewIchimoku(tenkan, kijun, senkouB, displacement)
+	rs := make([]IchimokuValue, len(candles.Candles))
+	for i, c := range candles.Candles {
+		ind.Update(c)
+		rs[i] = IchimokuValue{
+			Tenkan: math.NaN(), Kijun: math.NaN(), SenkouA: math.NaN(), SenkouB: math.NaN(),
+			CloudA: math.NaN(), CloudB: math.NaN(),
+		}
+		if ind.Ready() {
+			rs[i] = ind.Value()
+		}
+	}
+	return rs
+}
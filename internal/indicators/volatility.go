@@ -0,0 +1,89 @@
+package indicators
+
+import (
+	"DaruBot/internal/models"
+	"math"
+)
+
+type BollingerValue struct {
+	Upper  float64
+	Middle float64
+	Lower  float64
+}
+
+// Bollinger bands, SMA of close and k population standard deviations above and below
+type Bollinger struct {
+	tracker
+	w *window
+	k float64
+}
+
+func NewBollinger(period int, k float64) *Bollinger {
+	return &Bollinger{w: newWindow(period), k: k}
+}
+
+func (b *Bollinger) Update(c *models.Candle) {
+	switch b.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		b.w.undo()
+	}
+	b.w.push(c.Close)
+}
+
+func (b *Bollinger) Ready() bool {
+	return b.w.full()
+}
+
+func (b *Bollinger) Value() BollingerValue {
+	m, d := b.w.mean(), b.k*b.w.std()
+	return BollingerValue{Upper: m + d, Middle: m, Lower: m - d}
+}
+
+func BollingerSeries(candles *models.Candles, period int, k float64) []BollingerValue {
+	ind := NewBollinger(period, k)
+	rs := make([]BollingerValue, len(candles.Candles))
+	for i, c := range candles.Candles {
+		ind.Update(c)
+		rs[i] = BollingerValue{Upper: math.NaN(), Middle: math.NaN(), Lower: math.NaN()}
+		if ind.Ready() {
+			rs[i] = ind.Value()
+		}
+	}
+	return rs
+}
+
+// ATR average true range with Wilder's smoothing, true range calculated from second candle
+type ATR struct {
+	tracker
+	tr *ema
+}
+
+func NewATR(period int) *ATR {
+	return &ATR{tr: newWilder(period)}
+}
+
+func (a *ATR) Update(c *models.Candle) {
+	mode := a.track(c)
+	if mode == updateSkip || a.prev == nil {
+		return
+	}
+	if mode == updateReplace {
+		a.tr.undo()
+	}
+	a.tr.push(trueRange(c, a.prev))
+}
+
+func (a *ATR) Ready() bool {
+	return a.tr.ready()
+}
+
+func (a *ATR) Value() float64 {
+	return a.tr.value
+}
+
+func ATRSeries(candles *models.Candles, period int) []float64 {
+	ind := NewATR(period)
+	return floatSeries(candles, ind, ind.Value)
+}
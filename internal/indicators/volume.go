@@ -0,0 +1,103 @@
+package indicators
+
+import (
+	"DaruBot/internal/models"
+	"time"
+)
+
+type vwapState struct {
+	pv     float64 // sum of typical price * volume
+	volume float64
+	day    time.Time
+}
+
+// VWAP volume weighted average typical price, accumulated from the first candle or from start of day of candle date
+type VWAP struct {
+	tracker
+	daily bool
+	vwapState
+	undoState vwapState
+}
+
+func NewVWAP(daily bool) *VWAP {
+	return &VWAP{daily: daily}
+}
+
+func (v *VWAP) Update(c *models.Candle) {
+	switch v.track(c) {
+	case updateSkip:
+		return
+	case updateReplace:
+		v.vwapState = v.undoState
+	}
+	v.undoState = v.vwapState
+
+	if v.daily {
+		y, m, d := c.Date.Date()
+		day := time.Date(y, m, d, 0, 0, 0, 0, c.Date.Location())
+		if !day.Equal(v.day) {
+			v.vwapState = vwapState{day: day}
+		}
+	}
+
+	v.pv = v.pv + typicalPrice(c)*c.Volume
+	v.volume = v.volume + c.Volume
+}
+
+func (v *VWAP) Ready() bool {
+	return v.volume > 0
+}
+
+func (v *VWAP) Value() float64 {
+	if v.volume == 0 {
+		return 0
+	}
+	return v.pv / v.volume
+}
+
+func VWAPSeries(candles *models.Candles, daily bool) []float64 {
+	ind := NewVWAP(daily)
+	return floatSeries(candles, ind, ind.Value)
+}
+
+// OBV on-balance volume, starts from 0
+type OBV struct {
+	tracker
+	value     float64
+	undoValue float64
+}
+
+func NewOBV() *OBV {
+	return &OBV{}
+}
+
+func (o *OBV) Update(c *models.Candle) {
+	mode := o.track(c)
+	if mode == updateSkip || o.prev == nil {
+		return
+	}
+	if mode == updateReplace {
+		o.value = o.undoValue
+	}
+	o.undoValue = o.value
+
+	switch {
+	case c.Close > o.prev.Close:
+		o.value = o.value + c.Volume
+	case c.Close < o.prev.Close:
+		o.value = o.value - c.Volume
+	}
+}
+
+func (o *OBV) Ready() bool {
+	return o.count > 0
+}
+
+func (o *OBV) Value() float64 {
+	return o.value
+}
+
+func OBVSeries(candles *models.Candles) []float64 {
+	ind := NewOBV()
+	return floatSeries(candles, ind, ind.Value)
+}
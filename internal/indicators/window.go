@@ -0,0 +1,204 @@
+package indicators
+
+import "math"
+
+/*
+	Building blocks of indicators. Every block can undo the last push in O(1),
+	so update of the last candle is undo and push of new value.
+*/
+
+type windowState struct {
+	pos     int
+	count   int
+	sum     float64
+	sumSq   float64
+	evicted float64
+}
+
+// window of last n values with sum and sum of squares
+type window struct {
+	values []float64
+	windowState
+	undoState windowState
+}
+
+func newWindow(n int) *window {
+	return &window{values: make([]float64, checkPeriod(n))}
+}
+
+func (w *window) push(v float64) {
+	w.undoState = w.windowState
+	w.evicted = w.values[w.pos]
+
+	if w.full() {
+		w.sum = w.sum - w.evicted
+		w.sumSq = w.sumSq - w.evicted*w.evicted
+	} else {
+		w.count++
+	}
+
+	w.values[w.pos] = v
+	w.sum = w.sum + v
+	w.sumSq = w.sumSq + v*v
+	w.pos = (w.pos + 1) % len(w.values)
+}
+
+func (w *window) undo() {
+	w.values[w.undoState.pos] = w.evicted
+	w.windowState = w.undoState
+}
+
+func (w *window) full() bool {
+	return w.count == len(w.values)
+}
+
+func (w *window) mean() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	return w.sum / float64(w.count)
+}
+
+// std population standard deviation of values
+func (w *window) std() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	m := w.mean()
+	v := w.sumSq/float64(w.count) - m*m
+	if v < 0 {
+		return 0
+	}
+	return math.Sqrt(v)
+}
+
+// oldest value of window
+func (w *window) oldest() float64 {
+	if !w.full() {
+		return w.values[0]
+	}
+	return w.values[w.pos]
+}
+
+type emaState struct {
+	count int
+	sum   float64
+	value float64
+}
+
+// ema exponential moving average seeded by simple average of first n values
+type ema struct {
+	n     int
+	alpha float64
+	emaState
+	undoState emaState
+}
+
+func newEMA(n int) *ema {
+	n = checkPeriod(n)
+	return &ema{n: n, alpha: 2 / float64(n+1)}
+}
+
+// newWilder Wilder's smoothing, ema with alpha 1/n
+func newWilder(n int) *ema {
+	n = checkPeriod(n)
+	return &ema{n: n, alpha: 1 / float64(n)}
+}
+
+func (e *ema) push(v float64) {
+	e.undoState = e.emaState
+	e.count++
+
+	if e.count <= e.n {
+		e.sum = e.sum + v
+		e.value = e.sum / float64(e.count)
+		return
+	}
+
+	e.value = e.alpha*v + (1-e.alpha)*e.value
+}
+
+func (e *ema) undo() {
+	e.emaState = e.undoState
+}
+
+func (e *ema) ready() bool {
+	return e.count >= e.n
+}
+
+type extremumItem struct {
+	idx int
+	v   float64
+}
+
+// extremum max or min of last n values by monotonic queue, amortized O(1)
+type extremum struct {
+	n     int
+	max   bool
+	items []extremumItem
+	head  int
+	idx   int
+
+	undoBack  []extremumItem // items removed from back by last push
+	undoFront int            // items removed from front by last push
+}
+
+func newMax(n int) *extremum {
+	return &extremum{n: checkPeriod(n), max: true}
+}
+
+func newMin(n int) *extremum {
+	return &extremum{n: checkPeriod(n)}
+}
+
+func (e *extremum) dominates(v, than float64) bool {
+	if e.max {
+		return v >= than
+	}
+	return v <= than
+}
+
+func (e *extremum) push(v float64) {
+	// removed items of front are kept till next push for undo
+	if e.head > 64 && e.head*2 > len(e.items) {
+		e.items = append(e.items[:0], e.items[e.head:]...)
+		e.head = 0
+	}
+
+	e.undoBack = e.undoBack[:0]
+	for len(e.items) > e.head && e.dominates(v, e.items[len(e.items)-1].v) {
+		e.undoBack = append(e.undoBack, e.items[len(e.items)-1])
+		e.items = e.items[:len(e.items)-1]
+	}
+	e.items = append(e.items, extremumItem{idx: e.idx, v: v})
+
+	e.undoFront = 0
+	for e.items[e.head].idx <= e.idx-e.n {
+		e.head++
+		e.undoFront++
+	}
+
+	e.idx++
+}
+
+func (e *extremum) undo() {
+	e.idx--
+	e.head = e.head - e.undoFront
+	e.items = e.items[:len(e.items)-1]
+	for i := len(e.undoBack) - 1; i >= 0; i-- {
+		e.items = append(e.items, e.undoBack[i])
+	}
+	e.undoBack = e.undoBack[:0]
+	e.undoFront = 0
+}
+
+func (e *extremum) value() float64 {
+	if len(e.items) == e.head {
+		return 0
+	}
+	return e.items[e.head].v
+}
+
+func (e *extremum) ready() bool {
+	return e.idx >= e.n
+}
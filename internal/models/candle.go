@@ -71,7 +71,9 @@ func candleResolutionFromDuration(d time.Duration) (CandleResolution, error) {
 		return OneDay, nil
 	case time.Hour * 24 * 7:
 		return OneWeek, nil
-	case time.Hour * 24 * 7 * 4:
+	}
+	// calendar months have from 28 to 31 days
+	if d >= time.Hour*24*28 && d <= time.Hour*24*31 {
 		return OneMonth, nil
 	}
 	return OneMinute, fmt.Errorf("could not convert duration to resolution: %s", d)
@@ -81,6 +83,8 @@ func (r CandleResolution) String() string {
 	return string(r)
 }
 
+// ToDuration returns length of candle period, months differ in length and it's average month,
+// use PeriodStart and NextPeriod for calendar bounds
 func (r CandleResolution) ToDuration() time.Duration {
 	switch r {
 	case OneMinute:
@@ -104,7 +108,7 @@ func (r CandleResolution) ToDuration() time.Duration {
 	case OneWeek:
 		return time.Hour * 24 * 7
 	case OneMonth:
-		return time.Hour * 24 * 7 * 4
+		return time.Hour * 24 * 30
 	default:
 		panic("duration (⊙_⊙)？")
 	}
@@ -128,3 +132,67 @@ type Candle struct {
 	Low    float64
 	Volume float64
 }
+
+// PeriodStart returns start of candle period containing t in location of t. Days, weeks (from Monday)
+// and months are calendar periods in UTC as candles of exchanges, smaller resolutions are aligned to UTC
+func (r CandleResolution) PeriodStart(t time.Time) time.Time {
+	utc := t.UTC()
+
+	switch r {
+	case OneDay:
+		y, m, d := utc.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).In(t.Location())
+	case OneWeek:
+		day := OneDay.PeriodStart(utc)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7)).In(t.Location())
+	case OneMonth:
+		y, m, _ := utc.Date()
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).In(t.Location())
+	default:
+		return t.Truncate(r.ToDuration())
+	}
+}
+
+// NextPeriod returns start of candle period following period containing t
+func (r CandleResolution) NextPeriod(t time.Time) time.Time {
+	start := r.PeriodStart(t).UTC()
+
+	switch r {
+	case OneDay:
+		start = start.AddDate(0, 0, 1)
+	case OneWeek:
+		start = start.AddDate(0, 0, 7)
+	case OneMonth:
+		start = start.AddDate(0, 1, 0)
+	default:
+		start = start.Add(r.ToDuration())
+	}
+
+	return start.In(t.Location())
+}
+
+// PeriodEnd returns the last second of candle period containing t
+func (r CandleResolution) PeriodEnd(t time.Time) time.Time {
+	return r.NextPeriod(t).Add(-time.Second)
+}
+
+// IsPeriodStart returns true if t is start of candle period
+func (r CandleResolution) IsPeriodStart(t time.Time) bool {
+	return r.PeriodStart(t).Equal(t)
+}
+
+// CanAggregate returns true if candles of resolution r can be merged to candles of bigger resolution to
+func (r CandleResolution) CanAggregate(to CandleResolution) bool {
+	d, toD := r.ToDuration(), to.ToDuration()
+	if d >= toD {
+		return false
+	}
+
+	switch to {
+	case OneWeek, OneMonth:
+		// weeks are not aligned to months
+		return d <= OneDay.ToDuration() && OneDay.ToDuration()%d == 0
+	default:
+		return toD%d == 0
+	}
+}
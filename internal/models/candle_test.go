@@ -0,0 +1,55 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalendarPeriods(t *testing.T) {
+	date := func(y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		res   CandleResolution
+		t     time.Time
+		start time.Time
+		next  time.Time
+	}{
+		{OneMonth, date(2020, time.December, 15, 10), date(2020, time.December, 1, 0), date(2021, time.January, 1, 0)},
+		{OneMonth, date(2021, time.February, 28, 23), date(2021, time.February, 1, 0), date(2021, time.March, 1, 0)},
+		{OneMonth, date(2024, time.February, 29, 0), date(2024, time.February, 1, 0), date(2024, time.March, 1, 0)},
+		{OneWeek, date(2021, time.January, 1, 12), date(2020, time.December, 28, 0), date(2021, time.January, 4, 0)},
+		{OneWeek, date(2021, time.January, 4, 0), date(2021, time.January, 4, 0), date(2021, time.January, 11, 0)},
+		{OneDay, date(2021, time.March, 31, 23), date(2021, time.March, 31, 0), date(2021, time.April, 1, 0)},
+		{ThreeHours, date(2021, time.March, 31, 23), date(2021, time.March, 31, 21), date(2021, time.April, 1, 0)},
+	}
+
+	for _, tt := range tests {
+		if got := tt.res.PeriodStart(tt.t); !got.Equal(tt.start) {
+			t.Errorf("%s start of %s: expected %s, got %s", tt.res, tt.t, tt.start, got)
+		}
+		if got := tt.res.NextPeriod(tt.t); !got.Equal(tt.next) {
+			t.Errorf("%s next of %s: expected %s, got %s", tt.res, tt.t, tt.next, got)
+		}
+		if got := tt.res.PeriodEnd(tt.t); !got.Equal(tt.next.Add(-time.Second)) {
+			t.Errorf("%s end of %s: got %s", tt.res, tt.t, got)
+		}
+	}
+
+	// calendar periods are in UTC for any location
+	loc := time.FixedZone("UTC+3", 3*3600)
+	start := OneMonth.PeriodStart(time.Date(2021, time.March, 1, 1, 0, 0, 0, loc))
+	if !start.Equal(date(2021, time.February, 1, 0)) || start.Location() != loc {
+		t.Errorf("wrong start in location: %s", start)
+	}
+}
+
+func TestCandleResolutionFromDuration(t *testing.T) {
+	for days, want := range map[int]CandleResolution{1: OneDay, 7: OneWeek, 28: OneMonth, 30: OneMonth, 31: OneMonth} {
+		got, err := candleResolutionFromDuration(time.Duration(days) * 24 * time.Hour)
+		if err != nil || got != want {
+			t.Errorf("%d days: expected %s, got %s (%v)", days, want, got, err)
+		}
+	}
+}
@@ -4,4 +4,5 @@ type Stats struct {
 	TotalLoss   float64
 	TotalProfit float64
 	TotalTrades int
+	TotalFees   float64
 }
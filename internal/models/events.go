@@ -2,6 +2,7 @@ package models
 
 import (
 	"DaruBot/pkg/watcher"
+	"time"
 )
 
 const (
@@ -12,6 +13,9 @@ var (
 	EventTickerState = watcher.NewEventType(EventsModuleExchange, "EventTickerState", Ticker{})
 	EventCandleState = watcher.NewEventType(EventsModuleExchange, "EventCandleState", Candle{})
 
+	EventOrderBookUpdate = watcher.NewEventType(EventsModuleExchange, "EventOrderBookUpdate", OrderBook{})
+	EventTrade           = watcher.NewEventType(EventsModuleExchange, "EventTrade", Trade{})
+
 	EventWalletUpdate = watcher.NewEventType(EventsModuleExchange, "EventWalletUpdate", WalletCurrency{})
 
 	EventOrderPartiallyFilled = watcher.NewEventType(EventsModuleExchange, "EventOrderPartiallyFilled", Order{})
@@ -20,13 +24,34 @@ var (
 	EventOrderUpdate          = watcher.NewEventType(EventsModuleExchange, "EventOrderUpdate", Order{})
 	EventOrderCancel          = watcher.NewEventType(EventsModuleExchange, "EventOrderCancel", Order{})
 
+	EventOwnTradeExecuted = watcher.NewEventType(EventsModuleExchange, "EventOwnTradeExecuted", Execution{})
+
 	EventPositionNew    = watcher.NewEventType(EventsModuleExchange, "EventPositionNew", Position{})
 	EventPositionUpdate = watcher.NewEventType(EventsModuleExchange, "EventPositionUpdate", Position{})
 	EventPositionClosed = watcher.NewEventType(EventsModuleExchange, "EventPositionClosed", Position{})
 
 	EventError = watcher.NewEventType(EventsModuleExchange, "EventError", (*error)(nil))
+
+	EventTickDone = watcher.NewEventType(EventsModuleExchange, "EventTickDone", TickDone{})
 )
 
+// TickDone emitted by simulated exchange after all events of tick,
+// simulated time moves on only after listener call Ack
+type TickDone struct {
+	Time time.Time
+	ack  func()
+}
+
+func NewTickDone(t time.Time, ack func()) TickDone {
+	return TickDone{Time: t, ack: ack}
+}
+
+func (t TickDone) Ack() {
+	if t.ack != nil {
+		t.ack()
+	}
+}
+
 type RequestResult struct {
 	ReqID string
 	Msg   string
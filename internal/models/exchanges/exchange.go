@@ -5,6 +5,8 @@ type ExchangeType string
 const (
 	ExchangeTypeMock     ExchangeType = "CryptoMock"
 	ExchangeTypeBitfinex ExchangeType = "Bitfinex"
+	ExchangeTypePaper    ExchangeType = "Paper"
+	ExchangeTypeReplay   ExchangeType = "Replay"
 )
 
 func (e ExchangeType) String() string {
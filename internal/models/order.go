@@ -9,22 +9,31 @@ import (
 type OrderType string
 
 const (
-	OrderTypeLimit     OrderType = "LIMIT"
-	OrderTypeMarket    OrderType = "MARKET"
-	OrderTypeStop      OrderType = "STOP"
-	OrderTypeStopLimit OrderType = "STOP LIMIT"
-	OrderTypeUnknown   OrderType = "UNKNOWN"
+	OrderTypeLimit        OrderType = "LIMIT"
+	OrderTypeMarket       OrderType = "MARKET"
+	OrderTypeStop         OrderType = "STOP"
+	OrderTypeStopLimit    OrderType = "STOP LIMIT"
+	OrderTypeTrailingStop OrderType = "TRAILING STOP" // stop price follows the best price by TrailingDistance
+	OrderTypeFOK          OrderType = "FOK"           // limit order filled at once entirely or canceled
+	OrderTypeIOC          OrderType = "IOC"           // limit order filled at once as much as possible, rest canceled
+	OrderTypeOCO          OrderType = "OCO"           // limit order by Price and stop order by StopPrice, execution of one cancel other
+	OrderTypeUnknown      OrderType = "UNKNOWN"
 )
 
 type PutOrder struct {
-	InternalID string
-	Symbol     string
-	Type       OrderType
-	Amount     float64 // Positive for buy, Negative for sell
-	Price      float64 // ignoring if OrderTypeMarket
-	StopPrice  float64
+	InternalID       string
+	Symbol           string
+	Type             OrderType
+	Amount           float64 // Positive for buy, Negative for sell
+	Price            float64 // ignoring if OrderTypeMarket, OrderTypeStop or OrderTypeTrailingStop
+	StopPrice        float64
+	TrailingDistance float64 // distance of stop price from the best price for OrderTypeTrailingStop
 
 	Margin bool
+
+	PostOnly   bool // limit order canceled if it would be executed at once as taker
+	Hidden     bool // order not visible in order book
+	ReduceOnly bool // margin order only reduce position, canceled if it would open or increase position
 }
 
 type Order struct {
@@ -38,6 +47,8 @@ type Order struct {
 	AmountOriginal float64
 	Date           time.Time
 	Updated        time.Time
+	Fee            float64
+	FeeCurrency    string
 	Meta           map[string]interface{}
 }
 
@@ -94,7 +105,12 @@ func (o *Order) IsFilled() bool {
 }
 
 func (o *Order) IsSellOrder() bool {
-	if math.Signbit(o.AmountCurrent) {
+	amount := o.AmountCurrent
+	if amount == 0 { // filled order
+		amount = o.AmountOriginal
+	}
+
+	if math.Signbit(amount) {
 		return true
 	}
 
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+)
+
+// BookPrecision level of price aggregation, P0 is the most precise
+type BookPrecision string
+
+const (
+	BookPrecisionP0 BookPrecision = "P0"
+	BookPrecisionP1 BookPrecision = "P1"
+	BookPrecisionP2 BookPrecision = "P2"
+	BookPrecisionP3 BookPrecision = "P3"
+	BookPrecisionP4 BookPrecision = "P4"
+)
+
+// BookLevel orders aggregated by price
+type BookLevel struct {
+	Price  float64
+	Amount float64 // always positive
+	Count  int64
+}
+
+// OrderBook snapshot of book, levels sorted from the best price
+type OrderBook struct {
+	Symbol    string
+	Precision BookPrecision
+	Bids      []BookLevel // descending price
+	Asks      []BookLevel // ascending price
+	Date      time.Time
+}
+
+// BestBid returns the highest bid, false if there are no bids
+func (b *OrderBook) BestBid() (BookLevel, bool) {
+	if len(b.Bids) == 0 {
+		return BookLevel{}, false
+	}
+	return b.Bids[0], true
+}
+
+// BestAsk returns the lowest ask, false if there are no asks
+func (b *OrderBook) BestAsk() (BookLevel, bool) {
+	if len(b.Asks) == 0 {
+		return BookLevel{}, false
+	}
+	return b.Asks[0], true
+}
+
+// Spread returns difference of the best ask and bid, 0 if one of sides is empty
+func (b *OrderBook) Spread() float64 {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return 0
+	}
+	return ask.Price - bid.Price
+}
+
+// Mid returns price between the best ask and bid, 0 if one of sides is empty
+func (b *OrderBook) Mid() float64 {
+	bid, okBid := b.BestBid()
+	ask, okAsk := b.BestAsk()
+	if !okBid || !okAsk {
+		return 0
+	}
+	return (ask.Price + bid.Price) / 2
+}
+
+// Liquidity returns amount available to buy (positive amount) by asks not higher than price
+// or to sell (negative amount) by bids not lower than price
+func (b *OrderBook) Liquidity(amount float64, price float64) float64 {
+	rs := 0.0
+	if amount > 0 {
+		for _, l := range b.Asks {
+			if l.Price > price {
+				break
+			}
+			rs = rs + l.Amount
+		}
+		return rs
+	}
+
+	for _, l := range b.Bids {
+		if l.Price < price {
+			break
+		}
+		rs = rs + l.Amount
+	}
+	return rs
+}
+
+// Copy returns deep copy of book
+func (b *OrderBook) Copy() *OrderBook {
+	rs := *b
+	rs.Bids = append(make([]BookLevel, 0, len(b.Bids)), b.Bids...)
+	rs.Asks = append(make([]BookLevel, 0, len(b.Asks)), b.Asks...)
+	return &rs
+}
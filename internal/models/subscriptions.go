@@ -7,6 +7,8 @@ type SubType uint8
 const (
 	SubTypeTicker SubType = iota
 	SubTypeCandle
+	SubTypeOrderBook
+	SubTypeTrades
 )
 
 type Subscription struct {
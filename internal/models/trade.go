@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Trade public trade of market
+type Trade struct {
+	ID     string
+	Symbol string
+	Date   time.Time
+	Price  float64
+	Amount float64 // Positive for buy, Negative for sell
+}
+
+// IsSell returns true if taker sold
+func (t *Trade) IsSell() bool {
+	return t.Amount < 0
+}
+
+// Execution fill of own order, order can be filled by several executions
+type Execution struct {
+	ID          string
+	OrderID     string
+	Symbol      string
+	Date        time.Time
+	Price       float64
+	Amount      float64 // Positive for buy, Negative for sell
+	Fee         float64 // paid fee, negative is rebate
+	FeeCurrency string
+	Maker       bool
+}
+
+// Cost returns executed cost without fee, always positive
+func (e *Execution) Cost() float64 {
+	if e.Amount < 0 {
+		return -e.Amount * e.Price
+	}
+	return e.Amount * e.Price
+}
@@ -0,0 +1,52 @@
+package strategy
+
+import (
+	"reflect"
+	"strings"
+)
+
+type Param struct {
+	Name    string
+	Type    string
+	Default interface{}
+}
+
+// Describe returns parameters schema of strategy with default values
+func Describe(s Strategy) []Param {
+	rs := make([]Param, 0)
+
+	params := s.Params()
+	if params == nil {
+		return rs
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(params))
+	if v.Kind() != reflect.Struct {
+		return rs
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		rs = append(rs, Param{
+			Name:    strings.ToLower(field.Name),
+			Type:    field.Type.String(),
+			Default: v.Field(i).Interface(),
+		})
+	}
+
+	return rs
+}
+
+// HasParam report is strategy have parameter with name (case insensitive)
+func HasParam(s Strategy, name string) bool {
+	for _, p := range Describe(s) {
+		if strings.EqualFold(p.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package strategy
+
+import (
+	"DaruBot/internal/cache/candles"
+	"DaruBot/internal/exchanges"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHistoryWrongSize = errors.New("HISTORY SIZE MUST BE POSITIVE")
+)
+
+// HistoryUser strategy which needs candles history on start, SetHistory called before Init
+type HistoryUser interface {
+	SetHistory(h *History)
+}
+
+// History load the last closed candles for warm-up of indicators, so their state is correct
+// the moment strategy goes live
+type History struct {
+	ex    exchanges.CryptoExchange
+	cache *candles.MarketCandlesCache
+	now   func() time.Time
+}
+
+// NewHistory returns history of exchange. Candles are loaded through cache if it set,
+// market is name of candles in cache. now is time of exchange, candle is closed when its period passed,
+// if now is nil candle is closed only by the next one
+func NewHistory(ex exchanges.CryptoExchange, cache *candles.Cache, market string, now func() time.Time) *History {
+	h := &History{
+		ex:  ex,
+		now: now,
+	}
+
+	if cache != nil {
+		h.cache = cache.GetMarket(market, func(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+			return ex.GetCandles(symbol, resolution, from, to)
+		})
+	}
+
+	return h
+}
+
+// Warmup returns feed with the last n closed candles of symbol, less if exchange has no more
+func (h *History) Warmup(symbol string, resolution models.CandleResolution, n int) (*HistoryFeed, error) {
+	if n <= 0 {
+		return nil, ErrHistoryWrongSize
+	}
+
+	forming, err := h.ex.GetLastCandle(symbol, resolution)
+	if err != nil && h.now == nil {
+		return nil, err
+	}
+
+	// start of forming candle period
+	var end time.Time
+	if h.now != nil {
+		end = resolution.PeriodStart(h.now())
+	} else {
+		end = resolution.PeriodStart(forming.Date)
+	}
+
+	start := end
+	for i := 0; i < n; i++ {
+		start = resolution.PeriodStart(start.Add(-time.Second))
+	}
+
+	loaded, err := h.load(start, end.Add(-time.Second), symbol, resolution)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &HistoryFeed{
+		Symbol:     symbol,
+		Resolution: resolution,
+		size:       n,
+		candles:    make([]*models.Candle, 0, n),
+		now:        h.now,
+	}
+
+	for _, c := range loaded.Candles {
+		if c.Date.Before(start) || !c.Date.Before(end) {
+			continue
+		}
+		if len(feed.candles) > 0 && !c.Date.After(feed.candles[len(feed.candles)-1].Date) {
+			continue
+		}
+		feed.closeCandle(c)
+	}
+
+	if forming != nil && !forming.Date.Before(end) {
+		cc := *forming
+		feed.forming = &cc
+	}
+
+	return feed, nil
+}
+
+func (h *History) load(from, to time.Time, symbol string, resolution models.CandleResolution) (*models.Candles, error) {
+	if h.cache != nil {
+		return h.cache.Get(from, to, symbol, resolution)
+	}
+	return h.ex.GetCandles(symbol, resolution, from, to)
+}
+
+// HistoryFeed the last closed candles of symbol and resolution, kept up to date by live candles
+type HistoryFeed struct {
+	Symbol     string
+	Resolution models.CandleResolution
+
+	mu      sync.Mutex
+	size    int
+	candles []*models.Candle // closed candles sorted by date
+	forming *models.Candle
+	pending []models.Candle // closed by time, not yet returned by Update
+	now     func() time.Time
+}
+
+// Candles returns the last closed candles
+func (f *HistoryFeed) Candles() *models.Candles {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closeByTime()
+
+	rs := &models.Candles{
+		Symbol:     f.Symbol,
+		Resolution: f.Resolution,
+		Candles:    make([]*models.Candle, 0, len(f.candles)),
+	}
+	for _, c := range f.candles {
+		cc := *c
+		rs.Candles = append(rs.Candles, &cc)
+	}
+
+	return rs
+}
+
+// Forming returns the last update of not closed candle, nil if unknown
+func (f *HistoryFeed) Forming() *models.Candle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closeByTime()
+
+	if f.forming == nil {
+		return nil
+	}
+	cc := *f.forming
+	return &cc
+}
+
+// Update apply live candle (payload of EventCandleState), returns candles closed since previous update
+// sorted by date, including closed by time. Update of forming candle replaces it,
+// candles of other symbol or resolution and already closed are ignored
+func (f *HistoryFeed) Update(c models.Candle) []models.Candle {
+	if c.Symbol != f.Symbol || c.Resolution != f.Resolution {
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closeByTime()
+	rs := f.pending
+	f.pending = nil
+
+	if n := len(f.candles); n > 0 && !c.Date.After(f.candles[n-1].Date) {
+		return rs
+	}
+
+	if f.forming != nil && c.Date.After(f.forming.Date) {
+		rs = append(rs, *f.forming)
+		f.closeCandle(f.forming)
+		f.forming = nil
+	}
+
+	cc := c
+	if f.isClosed(&cc) {
+		rs = append(rs, cc)
+		f.closeCandle(&cc)
+		return rs
+	}
+	f.forming = &cc
+
+	return rs
+}
+
+// closeByTime close forming candle if its period passed
+func (f *HistoryFeed) closeByTime() {
+	if f.forming == nil || !f.isClosed(f.forming) {
+		return
+	}
+
+	f.pending = append(f.pending, *f.forming)
+	f.closeCandle(f.forming)
+	f.forming = nil
+}
+
+func (f *HistoryFeed) isClosed(c *models.Candle) bool {
+	return f.now != nil && !f.Resolution.NextPeriod(c.Date).After(f.now())
+}
+
+func (f *HistoryFeed) closeCandle(c *models.Candle) {
+	f.candles = append(f.candles, c)
+	if len(f.candles) > f.size {
+		f.candles = f.candles[len(f.candles)-f.size:]
+	}
+}
@@ -0,0 +1,128 @@
+package strategy
+
+import (
+	"DaruBot/internal/exchanges"
+	"DaruBot/internal/models"
+	"testing"
+	"time"
+)
+
+// historyExchange returns hourly candles until now, price is hour of day
+type historyExchange struct {
+	exchanges.CryptoExchange
+	now time.Time
+}
+
+func (e *historyExchange) candle(date time.Time) *models.Candle {
+	return &models.Candle{
+		Symbol:     "tBTCUSD",
+		Resolution: models.OneHour,
+		Date:       date,
+		Open:       float64(date.Hour()),
+		Close:      float64(date.Hour()),
+		Volume:     1,
+	}
+}
+
+func (e *historyExchange) GetLastCandle(symbol string, resolution models.CandleResolution) (*models.Candle, error) {
+	return e.candle(resolution.PeriodStart(e.now)), nil
+}
+
+func (e *historyExchange) GetCandles(symbol string, resolution models.CandleResolution, from time.Time, to time.Time) (*models.Candles, error) {
+	rs := &models.Candles{Symbol: symbol, Resolution: resolution}
+	for d := resolution.PeriodStart(from); !d.After(to) && !d.After(e.now); d = resolution.NextPeriod(d) {
+		rs.Candles = append(rs.Candles, e.candle(d))
+	}
+	return rs, nil
+}
+
+func TestHistoryWarmup(t *testing.T) {
+	ex := &historyExchange{now: time.Date(2021, 3, 1, 10, 30, 0, 0, time.UTC)}
+
+	h := NewHistory(ex, nil, "", nil)
+	if _, err := h.Warmup("tBTCUSD", models.OneHour, 0); err != ErrHistoryWrongSize {
+		t.Fatalf("expected %v, got %v", ErrHistoryWrongSize, err)
+	}
+
+	feed, err := h.Warmup("tBTCUSD", models.OneHour, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs := feed.Candles()
+	if len(rs.Candles) != 5 {
+		t.Fatalf("expected 5 candles, got %d", len(rs.Candles))
+	}
+	if first, last := rs.Candles[0], rs.Candles[4]; first.Open != 5 || last.Open != 9 {
+		t.Fatalf("wrong candles %+v - %+v", first, last)
+	}
+	if f := feed.Forming(); f == nil || f.Open != 10 {
+		t.Fatalf("wrong forming candle %+v", f)
+	}
+
+	// update of forming candle replaces it
+	upd := *ex.candle(time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC))
+	upd.Close = 100
+	if closed := feed.Update(upd); len(closed) != 0 {
+		t.Fatalf("forming candle closed %+v", closed)
+	}
+
+	// already closed candle ignored, other symbol ignored
+	if closed := feed.Update(*rs.Candles[4]); len(closed) != 0 {
+		t.Fatalf("closed candle updated %+v", closed)
+	}
+	other := upd
+	other.Symbol = "tETHUSD"
+	other.Date = other.Date.Add(time.Hour)
+	if closed := feed.Update(other); len(closed) != 0 {
+		t.Fatalf("other symbol closed %+v", closed)
+	}
+
+	// the next candle close forming one
+	closed := feed.Update(*ex.candle(time.Date(2021, 3, 1, 11, 0, 0, 0, time.UTC)))
+	if len(closed) != 1 || closed[0].Close != 100 {
+		t.Fatalf("wrong closed candles %+v", closed)
+	}
+
+	rs = feed.Candles()
+	if len(rs.Candles) != 5 || rs.Candles[0].Open != 6 || rs.Candles[4].Close != 100 {
+		t.Fatalf("wrong candles after update %+v - %+v", rs.Candles[0], rs.Candles[4])
+	}
+}
+
+func TestHistoryClock(t *testing.T) {
+	ex := &historyExchange{now: time.Date(2021, 3, 1, 10, 30, 0, 0, time.UTC)}
+
+	h := NewHistory(ex, nil, "", func() time.Time { return ex.now })
+	feed, err := h.Warmup("tBTCUSD", models.OneHour, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// forming candle closed when its period passed
+	ex.now = time.Date(2021, 3, 1, 11, 0, 0, 0, time.UTC)
+	if f := feed.Forming(); f != nil {
+		t.Fatalf("forming candle not closed %+v", f)
+	}
+
+	// candle closed by time returned by the next update once
+	closed := feed.Update(*ex.candle(time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)))
+	if len(closed) != 1 || closed[0].Open != 10 {
+		t.Fatalf("wrong closed candles %+v", closed)
+	}
+	if closed := feed.Update(*ex.candle(time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC))); len(closed) != 0 {
+		t.Fatalf("closed candle returned again %+v", closed)
+	}
+
+	// candle emitted after close (e.g. by mock exchange) is closed at once
+	ex.now = time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	closed = feed.Update(*ex.candle(time.Date(2021, 3, 1, 11, 0, 0, 0, time.UTC)))
+	if len(closed) != 1 || closed[0].Open != 11 {
+		t.Fatalf("wrong closed candles %+v", closed)
+	}
+
+	rs := feed.Candles()
+	if len(rs.Candles) != 3 || rs.Candles[0].Open != 9 || rs.Candles[2].Open != 11 {
+		t.Fatalf("wrong candles %+v", rs.Candles)
+	}
+}
@@ -0,0 +1,146 @@
+package strategy
+
+import (
+	"DaruBot/internal/config"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrStrategyNotSet      = errors.New("STRATEGY NOT SET")
+	ErrStrategyNotFound    = errors.New("STRATEGY NOT FOUND")
+	ErrStrategyWrongParams = errors.New("STRATEGY PARAMETERS INCORRECT")
+
+	mu       = &sync.RWMutex{}
+	registry = make(map[string]Factory)
+)
+
+// Settings of strategy in `strategies` config section
+// strategies:
+//
+//	custom_name:
+//	  strategy: registered_name # optional, custom_name used if empty
+//	  params:
+//	    foo: bar
+type Settings struct {
+	Strategy string
+	Params   map[string]interface{}
+}
+
+// Register make strategy available by name, usually called from init() of strategy package.
+// Panics if name is empty or already registered
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if name == "" {
+		panic("strategy name empty")
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("strategy %s factory is nil", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("strategy %s already registered", name))
+	}
+
+	registry[name] = factory
+}
+
+// List returns sorted names of registered strategies
+func List() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	rs := make([]string, 0, len(registry))
+	for name := range registry {
+		rs = append(rs, name)
+	}
+	sort.Strings(rs)
+
+	return rs
+}
+
+// New returns registered strategy with default parameters
+func New(name string, lg logger.Logger) (Strategy, error) {
+	mu.RLock()
+	factory, ok := registry[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, errors.WrapMessage(ErrStrategyNotFound, name)
+	}
+
+	return factory(lg.WithPrefix("strategy", name)), nil
+}
+
+// FromConfig resolve strategy selected in exchanges.bitfinex.strategy
+// and decode its parameters from strategies section
+func FromConfig(cfg config.Configurations, lg logger.Logger) (Strategy, error) {
+	name := cfg.Exchanges.Bitfinex.Strategy
+	if name == "" {
+		return nil, ErrStrategyNotSet
+	}
+
+	settings, err := settingsFromConfig(cfg, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := New(settings.Strategy, lg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := DecodeParams(settings.Params, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func settingsFromConfig(cfg config.Configurations, name string) (*Settings, error) {
+	settings := &Settings{}
+
+	if raw, ok := cfg.Strategies[name]; ok && raw != nil {
+		if err := mapstructure.WeakDecode(raw, settings); err != nil {
+			return nil, errors.WrapMessage(ErrStrategyWrongParams, err)
+		}
+	}
+
+	if settings.Strategy == "" {
+		settings.Strategy = name
+	}
+
+	return settings, nil
+}
+
+// DecodeParams decode params over default parameters of strategy
+func DecodeParams(params map[string]interface{}, s Strategy) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	if s.Params() == nil {
+		return errors.WrapMessage(ErrStrategyWrongParams, fmt.Sprintf("strategy %s have no parameters", s.Name()))
+	}
+
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		ErrorUnused:      true,
+		WeaklyTypedInput: true,
+		Result:           s.Params(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := decoder.Decode(params); err != nil {
+		return errors.WrapMessage(ErrStrategyWrongParams, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package strategy
+
+import (
+	"DaruBot/internal/config"
+	"DaruBot/internal/exchanges"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/errors"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"DaruBot/storage"
+	"os"
+	"testing"
+	"time"
+)
+
+type testParams struct {
+	Symbol  string
+	Amount  float64
+	Timeout time.Duration
+}
+
+type testStrategy struct {
+	params *testParams
+}
+
+func newTestStrategy(lg logger.Logger) Strategy {
+	return &testStrategy{
+		params: &testParams{
+			Symbol:  "tBTCUSD",
+			Amount:  1,
+			Timeout: time.Second,
+		},
+	}
+}
+
+func (t *testStrategy) Name() string        { return "test" }
+func (t *testStrategy) Params() interface{} { return t.params }
+func (t *testStrategy) Init(ex exchanges.CryptoExchange, wManager *watcher.Manager, st storage.CustomStorage) error {
+	return nil
+}
+func (t *testStrategy) OnTicker(ticker models.Ticker)                                {}
+func (t *testStrategy) OnCandle(candle models.Candle)                                {}
+func (t *testStrategy) OnOrder(event watcher.EventHead, order models.Order)          {}
+func (t *testStrategy) OnPosition(event watcher.EventHead, position models.Position) {}
+func (t *testStrategy) Shutdown() error                                              { return nil }
+
+func init() {
+	Register("test", newTestStrategy)
+}
+
+func TestFromConfig(t *testing.T) {
+	lg := logger.New(os.Stdout, logger.DebugLevel)
+
+	cfg := config.GetDefaultConfig()
+
+	if _, err := FromConfig(cfg, lg); err != ErrStrategyNotSet {
+		t.Fatalf("expected %v, got %v", ErrStrategyNotSet, err)
+	}
+
+	cfg.Exchanges.Bitfinex.Strategy = "test"
+
+	s, err := FromConfig(cfg, lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Params().(*testParams).Amount != 1 {
+		t.Fatal("default params changed")
+	}
+
+	cfg.Exchanges.Bitfinex.Strategy = "custom"
+	cfg.Strategies = map[string]interface{}{
+		"custom": map[string]interface{}{
+			"strategy": "test",
+			"params": map[string]interface{}{
+				"symbol":  "tETHUSD",
+				"amount":  "0.5",
+				"timeout": "1m",
+			},
+		},
+	}
+
+	s, err = FromConfig(cfg, lg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := s.Params().(*testParams)
+	if params.Symbol != "tETHUSD" || params.Amount != 0.5 || params.Timeout != time.Minute {
+		t.Fatalf("params not decoded %#v", params)
+	}
+
+	cfg.Strategies["custom"].(map[string]interface{})["params"] = map[string]interface{}{"unknown": 1}
+
+	if _, err = FromConfig(cfg, lg); errors.Cause(err) != ErrStrategyWrongParams {
+		t.Fatalf("expected %v, got %v", ErrStrategyWrongParams, err)
+	}
+
+	cfg.Exchanges.Bitfinex.Strategy = "not_exist"
+
+	if _, err = FromConfig(cfg, lg); errors.Cause(err) != ErrStrategyNotFound {
+		t.Fatalf("expected %v, got %v", ErrStrategyNotFound, err)
+	}
+}
@@ -0,0 +1,111 @@
+package strategy
+
+import (
+	"DaruBot/internal/exchanges"
+	"DaruBot/internal/models"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/tools"
+	"DaruBot/pkg/watcher"
+	"DaruBot/storage"
+	"context"
+	"fmt"
+)
+
+type Strategy interface {
+	Name() string
+	// Params returns pointer to parameters struct filled with default values,
+	// parameters from config are decoded into it before Init
+	Params() interface{}
+
+	Init(ex exchanges.CryptoExchange, wManager *watcher.Manager, st storage.CustomStorage) error
+
+	OnTicker(ticker models.Ticker)
+	OnCandle(candle models.Candle)
+	OnOrder(event watcher.EventHead, order models.Order)
+	OnPosition(event watcher.EventHead, position models.Position)
+
+	Shutdown() error
+}
+
+// OrderBookHandler strategy which receives EventOrderBookUpdate of subscribed books
+type OrderBookHandler interface {
+	OnOrderBook(book models.OrderBook)
+}
+
+// TradeHandler strategy which receives EventTrade of subscribed trades
+type TradeHandler interface {
+	OnTrade(trade models.Trade)
+}
+
+// ExecutionHandler strategy which receives EventOwnTradeExecuted, fills of own orders with fees
+type ExecutionHandler interface {
+	OnExecution(execution models.Execution)
+}
+
+// Factory make new instance of strategy with default parameters
+type Factory func(lg logger.Logger) Strategy
+
+// Start listen exchange events and pass them to strategy until context done,
+// returned channel closed when listening stopped
+func Start(ctx context.Context, s Strategy, wManager *watcher.Manager, exchangeName string, lg logger.Logger) (<-chan struct{}, error) {
+	watcherName := fmt.Sprintf("strategy_%s", s.Name())
+
+	wh, err := wManager.New(watcherName, models.EventsModuleExchange, exchangeName)
+	if err != nil {
+		return nil, err
+	}
+
+	events := wh.Listen()
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		defer func() {
+			// drain events, emitter may be blocked on full pipe while watcher removing
+			go wManager.Remove(watcherName)
+			for range events {
+			}
+		}()
+
+		for {
+			select {
+			case evt := <-events:
+				dispatch(s, evt.EventHead, evt.Payload, lg)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return done, nil
+}
+
+func dispatch(s Strategy, head watcher.EventHead, payload interface{}, lg logger.Logger) {
+	defer tools.Recover(lg)
+
+	switch data := payload.(type) {
+	case models.Ticker:
+		s.OnTicker(data)
+	case models.Candle:
+		s.OnCandle(data)
+	case models.OrderBook:
+		if h, ok := s.(OrderBookHandler); ok {
+			h.OnOrderBook(data)
+		}
+	case models.Trade:
+		if h, ok := s.(TradeHandler); ok {
+			h.OnTrade(data)
+		}
+	case models.Execution:
+		if h, ok := s.(ExecutionHandler); ok {
+			h.OnExecution(data)
+		}
+	case models.Order:
+		s.OnOrder(head, data)
+	case models.Position:
+		s.OnPosition(head, data)
+	case models.TickDone:
+		// all events of tick are processed, let simulated time move on
+		data.Ack()
+	}
+}
@@ -209,6 +209,10 @@ func (w *Manager) Emmit(evt *event) error {
 func (w *Manager) checkType(evt *event) error {
 	regT := evt.EventHead.(*eventHead).payloadType
 	plT := reflect.TypeOf(evt.Payload)
+	// payload of interface type (e.g. error) may be any implementation
+	if regT != nil && regT.Kind() == reflect.Interface && plT != nil && plT.Implements(regT) {
+		return nil
+	}
 	if regT != nil && regT != plT {
 		return fmt.Errorf("event contain wrong payload type: got (%s), expected (%s)\n", plT, regT)
 	}
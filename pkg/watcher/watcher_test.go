@@ -79,3 +79,15 @@ func TestHandler(t *testing.T) {
 		}
 	}
 }
+
+func TestInterfacePayload(t *testing.T) {
+	m := NewWatcherManager()
+	head := NewEventType("module", "error", (*error)(nil))
+
+	if err := m.Emmit(BuildEvent(head, "tester", fmt.Errorf("test"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Emmit(BuildEvent(head, "tester", "test")); err == nil {
+		t.Fatal("expected wrong payload type error")
+	}
+}
@@ -1,7 +1,13 @@
 package storage
 
-import "DaruBot/pkg/errors"
+import (
+	"DaruBot/pkg/errors"
+	"github.com/asdine/storm/v3"
+)
 
 var (
 	ErrBadStoragePath = errors.New("File to storage path incorrect")
+
+	// ErrNotFound returned by Load if key not found
+	ErrNotFound = storm.ErrNotFound
 )
@@ -0,0 +1,166 @@
+/*
+Dumb strategy, like your 3-year-old sister saw the price chart for the first time.
+Buy after several growing candles in a row, sell after several falling.
+*/
+package dumb
+
+import (
+	"DaruBot/internal/exchanges"
+	"DaruBot/internal/models"
+	"DaruBot/internal/strategy"
+	"DaruBot/pkg/logger"
+	"DaruBot/pkg/watcher"
+	"DaruBot/storage"
+)
+
+const (
+	Name = "dumb"
+
+	stateKey = "state"
+)
+
+func init() {
+	strategy.Register(Name, New)
+}
+
+type Params struct {
+	Symbol     string
+	Resolution string
+	Amount     float64
+	Streak     int
+	Margin     bool
+}
+
+type state struct {
+	Holding bool
+	Growth  int
+	Fall    int
+}
+
+type Dumb struct {
+	log    logger.Logger
+	params *Params
+	state  state
+
+	ex    exchanges.CryptoExchange
+	st    storage.CustomStorage
+	subID string
+}
+
+func New(lg logger.Logger) strategy.Strategy {
+	return &Dumb{
+		log: lg,
+		params: &Params{
+			Symbol:     "tBTCUSD",
+			Resolution: models.OneMinute.String(),
+			Amount:     0.001,
+			Streak:     3,
+			Margin:     false,
+		},
+	}
+}
+
+func (d *Dumb) Name() string {
+	return Name
+}
+
+func (d *Dumb) Params() interface{} {
+	return d.params
+}
+
+func (d *Dumb) Init(ex exchanges.CryptoExchange, wManager *watcher.Manager, st storage.CustomStorage) error {
+	res, err := models.CandleResolutionFromString(d.params.Resolution)
+	if err != nil {
+		return err
+	}
+
+	if err := ex.CheckSymbol(d.params.Symbol, d.params.Margin); err != nil {
+		return err
+	}
+
+	d.ex = ex
+	d.st = st
+
+	if st != nil {
+		if err := st.Load(stateKey, &d.state); err != nil {
+			d.log.Debugf("state not loaded: %v", err)
+		}
+	}
+
+	d.subID, err = ex.SubscribeCandles(d.params.Symbol, res)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Dumb) OnTicker(ticker models.Ticker) {}
+
+func (d *Dumb) OnCandle(candle models.Candle) {
+	if candle.Symbol != d.params.Symbol || candle.Resolution.String() != d.params.Resolution {
+		return
+	}
+
+	switch {
+	case candle.Close > candle.Open:
+		d.state.Growth++
+		d.state.Fall = 0
+	case candle.Close < candle.Open:
+		d.state.Fall++
+		d.state.Growth = 0
+	default:
+		return
+	}
+
+	switch {
+	case !d.state.Holding && d.state.Growth >= d.params.Streak:
+		d.putOrder(d.params.Amount)
+	case d.state.Holding && d.state.Fall >= d.params.Streak:
+		d.putOrder(-d.params.Amount)
+	}
+}
+
+func (d *Dumb) putOrder(amount float64) {
+	o, err := d.ex.PutOrder(&models.PutOrder{
+		Symbol: d.params.Symbol,
+		Type:   models.OrderTypeMarket,
+		Amount: amount,
+		Margin: d.params.Margin,
+	})
+	if err != nil {
+		d.log.Warnf("order not placed: %v", err)
+		return
+	}
+
+	d.log.Infof("order placed %s amount: %v", o.ID, amount)
+
+	d.state.Holding = amount > 0
+	d.state.Growth = 0
+	d.state.Fall = 0
+
+	d.saveState()
+}
+
+func (d *Dumb) OnOrder(event watcher.EventHead, order models.Order) {}
+
+func (d *Dumb) OnPosition(event watcher.EventHead, position models.Position) {}
+
+func (d *Dumb) saveState() {
+	if d.st == nil {
+		return
+	}
+	if err := d.st.Save(stateKey, d.state); err != nil {
+		d.log.Error(err)
+	}
+}
+
+func (d *Dumb) Shutdown() error {
+	d.saveState()
+
+	if d.subID != "" {
+		return d.ex.Unsubscribe(d.subID)
+	}
+
+	return nil
+}
@@ -0,0 +1,8 @@
+/*
+Bundled strategies, import strategy package here to register it
+*/
+package strategies
+
+import (
+	_ "DaruBot/strategies/dumb"
+)